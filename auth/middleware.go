@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// User - пользователь, от имени которого выполняется запрос.
+type User struct {
+	ID       int
+	Username string
+}
+
+type contextKey struct{}
+
+// WithUser возвращает контекст с пользователем user.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFrom возвращает пользователя запроса, если он вошел в систему.
+func UserFrom(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(contextKey{}).(User)
+	return user, ok
+}
+
+// Authenticate кладет в контекст запроса пользователя из заголовка
+// Authorization: Bearer <access-токен>. Запросы без заголовка проходят анонимно,
+// с недействительным токеном - получают 401, чтобы клиент обновил токен.
+func (m *Manager) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			unauthorized(w, "Authorization header must use the Bearer scheme")
+			return
+		}
+		user, err := m.Parse(strings.TrimSpace(token), AccessToken)
+		if err != nil {
+			slog.Warn("Отклонен запрос с недействительным токеном",
+				"ошибка", err,
+				"путь", r.URL.Path,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			unauthorized(w, "Invalid or expired access token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// RequireUser пропускает только запросы вошедших пользователей.
+// Работает после Authenticate.
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserFrom(r.Context()); !ok {
+			unauthorized(w, "Authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="video"`)
+	http.Error(w, message, http.StatusUnauthorized)
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"video/database"
+)
+
+func TestAuthenticate(t *testing.T) {
+	m := NewManager(testSecret, time.Hour, 24*time.Hour)
+	pair, err := m.Issue(&database.User{ID: 7, Username: "anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Обработчик отвечает ID пользователя из контекста в заголовке X-User
+	handler := m.Authenticate(RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := UserFrom(r.Context())
+		w.Header().Set("X-User", user.Username)
+	})))
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+		user   string
+	}{
+		{"access-токен", "Bearer " + pair.AccessToken, http.StatusOK, "anna"},
+		{"без заголовка", "", http.StatusUnauthorized, ""},
+		{"не Bearer", "Basic YW5uYTpzZWNyZXQ=", http.StatusUnauthorized, ""},
+		{"токен без схемы", pair.AccessToken, http.StatusUnauthorized, ""},
+		{"пустой Bearer", "Bearer ", http.StatusUnauthorized, ""},
+		{"refresh-токен", "Bearer " + pair.RefreshToken, http.StatusUnauthorized, ""},
+		{"поддельный", "Bearer " + pair.AccessToken + "x", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status || w.Header().Get("X-User") != tt.user {
+			t.Errorf("%s: ответ %d, пользователь %q; ожидалось %d, %q", tt.name, w.Code, w.Header().Get("X-User"), tt.status, tt.user)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: 401 без заголовка WWW-Authenticate", tt.name)
+		}
+	}
+}
+
+func TestAuthenticateAnonymous(t *testing.T) {
+	m := NewManager(testSecret, time.Hour, 24*time.Hour)
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserFrom(r.Context()); ok {
+			t.Error("у анонимного запроса есть пользователь")
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("анонимный запрос без RequireUser: ответ %d, ожидался 200", w.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ограничения на пароль. bcrypt учитывает только первые 72 байта,
+// поэтому более длинные пароли отклоняются, а не обрезаются молча.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// dummyHash сравнивается с паролем, когда пользователь не найден,
+// чтобы по времени ответа нельзя было узнать, существует ли имя.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// HashPassword хэширует пароль для хранения в базе.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("не удалось захэшировать пароль: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сравнивает пароль с хэшем. Пустой hash означает
+// отсутствующего пользователя: сравнение все равно выполняется.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
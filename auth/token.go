@@ -0,0 +1,107 @@
+// Package auth выдает и проверяет JWT пользователей и хэширует пароли.
+//
+// Пользователь получает пару токенов: короткоживущий access-токен для запросов
+// (заголовок Authorization: Bearer ...) и долгоживущий refresh-токен,
+// которым без пароля получается новая пара.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"video/database"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Типы токенов. Refresh-токен нельзя использовать вместо access-токена и наоборот.
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+// ErrInvalidToken возвращается для поддельных, просроченных и чужого типа токенов.
+var ErrInvalidToken = errors.New("некорректный токен")
+
+// Claims - содержимое JWT. ID пользователя хранится в стандартном поле sub.
+type Claims struct {
+	jwt.RegisteredClaims
+	Username string `json:"username"`
+	Type     string `json:"typ"`
+}
+
+// TokenPair - ответ на вход, регистрацию и обновление токенов.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"` // Всегда "Bearer"
+	ExpiresIn    int    `json:"expires_in"` // Сколько секунд живет access-токен
+}
+
+// Manager подписывает токены ключом secret алгоритмом HS256.
+type Manager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewManager создает Manager со сроками жизни access- и refresh-токенов.
+func NewManager(secret []byte, accessTTL, refreshTTL time.Duration) *Manager {
+	return &Manager{secret: secret, accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
+
+// Issue выдает пользователю новую пару токенов.
+func (m *Manager) Issue(user *database.User) (*TokenPair, error) {
+	access, err := m.sign(user, AccessToken, m.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := m.sign(user, RefreshToken, m.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(m.accessTTL.Seconds()),
+	}, nil
+}
+
+// Parse проверяет подпись, срок действия и тип токена и возвращает пользователя из него.
+func (m *Manager) Parse(token, tokenType string) (User, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return User{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Type != tokenType {
+		return User{}, fmt.Errorf("%w: ожидается токен типа %s", ErrInvalidToken, tokenType)
+	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil || id <= 0 {
+		return User{}, fmt.Errorf("%w: некорректный ID пользователя %q", ErrInvalidToken, claims.Subject)
+	}
+	return User{ID: id, Username: claims.Username}, nil
+}
+
+func (m *Manager) sign(user *database.User, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(user.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Username: user.Username,
+		Type:     tokenType,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", fmt.Errorf("не удалось подписать токен: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"video/database"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("secret")
+
+// signClaims подписывает произвольные claims методом method, чтобы собрать токены,
+// которые Manager сам никогда не выдает.
+func signClaims(t *testing.T, method jwt.SigningMethod, key any, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// claimsFor возвращает claims действующего токена типа tokenType пользователя с ID sub.
+func claimsFor(sub, tokenType string) Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sub,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Username: "anna",
+		Type:     tokenType,
+	}
+}
+
+func TestParse(t *testing.T) {
+	m := NewManager(testSecret, time.Hour, 24*time.Hour)
+	pair, err := m.Issue(&database.User{ID: 7, Username: "anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := NewManager(testSecret, -time.Minute, -time.Minute).Issue(&database.User{ID: 7, Username: "anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noExpiry := claimsFor("7", AccessToken)
+	noExpiry.ExpiresAt = nil
+
+	tests := []struct {
+		name      string
+		token     string
+		tokenType string
+		ok        bool
+	}{
+		{"access-токен", pair.AccessToken, AccessToken, true},
+		{"refresh-токен", pair.RefreshToken, RefreshToken, true},
+		{"refresh вместо access", pair.RefreshToken, AccessToken, false},
+		{"access вместо refresh", pair.AccessToken, RefreshToken, false},
+		{"просроченный", expired.AccessToken, AccessToken, false},
+		{"без срока действия", signClaims(t, jwt.SigningMethodHS256, testSecret, noExpiry), AccessToken, false},
+		{"чужой ключ", signClaims(t, jwt.SigningMethodHS256, []byte("other"), claimsFor("7", AccessToken)), AccessToken, false},
+		{"HS512", signClaims(t, jwt.SigningMethodHS512, testSecret, claimsFor("7", AccessToken)), AccessToken, false},
+		{"alg none", signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claimsFor("7", AccessToken)), AccessToken, false},
+		{"sub не число", signClaims(t, jwt.SigningMethodHS256, testSecret, claimsFor("anna", AccessToken)), AccessToken, false},
+		{"sub ноль", signClaims(t, jwt.SigningMethodHS256, testSecret, claimsFor("0", AccessToken)), AccessToken, false},
+		{"sub отрицательный", signClaims(t, jwt.SigningMethodHS256, testSecret, claimsFor("-1", AccessToken)), AccessToken, false},
+		{"мусор", "not.a.token", AccessToken, false},
+	}
+	for _, tt := range tests {
+		user, err := m.Parse(tt.token, tt.tokenType)
+		if !tt.ok {
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("%s: Parse = %+v, %v; ожидалась ErrInvalidToken", tt.name, user, err)
+			}
+			continue
+		}
+		if err != nil || user != (User{ID: 7, Username: "anna"}) {
+			t.Errorf("%s: Parse = %+v, %v", tt.name, user, err)
+		}
+	}
+}
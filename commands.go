@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"video/database"
+)
+
+// Команды обслуживания базы. Запуск без команды стартует сервер:
+//
+//	main [-config путь] migrate       применить все миграции
+//	main [-config путь] rollback [N]  откатить N последних миграций, по умолчанию одну
+//	main [-config путь] version       показать текущую и последнюю версию схемы
+const commandsUsage = "доступные команды: migrate, rollback [N], version"
+
+// runCommand выполняет команду обслуживания name с аргументами args.
+func runCommand(ctx context.Context, db *database.DB, name string, args []string) error {
+	switch name {
+	case "migrate":
+		if err := db.Migrate(ctx); err != nil {
+			return err
+		}
+		return printVersion(ctx, db)
+	case "rollback":
+		steps := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("некорректное число миграций для отката %q", args[0])
+			}
+			steps = n
+		}
+		if err := db.Rollback(ctx, steps); err != nil {
+			return err
+		}
+		return printVersion(ctx, db)
+	case "version":
+		return printVersion(ctx, db)
+	}
+	return fmt.Errorf("неизвестная команда %q, %s", name, commandsUsage)
+}
+
+func printVersion(ctx context.Context, db *database.DB) error {
+	current, err := db.Version(ctx)
+	if err != nil {
+		return err
+	}
+	latest, err := db.LatestVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Версия схемы: %d, последняя миграция: %d.\n", current, latest)
+	return nil
+}
+
+// checkSchema применяет миграции при запуске или, если autoMigrate выключен,
+// проверяет, что схема базы не отстает от этой версии сервиса.
+func checkSchema(ctx context.Context, db *database.DB, autoMigrate bool) error {
+	if autoMigrate {
+		return db.Migrate(ctx)
+	}
+	current, err := db.Version(ctx)
+	if err != nil {
+		return err
+	}
+	latest, err := db.LatestVersion()
+	if err != nil {
+		return err
+	}
+	if current < latest {
+		return fmt.Errorf("схема базы устарела (версия %d, нужна %d): выполните команду migrate", current, latest)
+	}
+	return nil
+}
@@ -1,6 +1,281 @@
+// Package config загружает настройки сервиса из YAML-файла и переменных окружения.
 package config
 
-const (
-	UploadDir = "./uploads"
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"video/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Папки с файлами видео. Заполняются из конфигурации в Load,
+// поэтому их можно использовать в любом пакете без передачи Config.
+var (
+	UploadDir    = "./uploads"
 	TemporaryDir = "./temp"
 )
+
+// DefaultProfileName - имя профиля кодирования, который есть всегда.
+const DefaultProfileName = "default"
+
+// Типы хранилища для готовых видео.
+const (
+	StorageLocal = "local" // Папка upload_dir на диске сервера
+	StorageS3    = "s3"    // S3-совместимый бакет
+)
+
+// Config - настройки сервиса.
+type Config struct {
+	ListenAddr     string                      `yaml:"listen_addr"`       // Адрес HTTP-сервера, например ":3030"
+	UploadDir      string                      `yaml:"upload_dir"`        // Папка с готовыми HLS-файлами
+	TemporaryDir   string                      `yaml:"temporary_dir"`     // Папка для загружаемых исходников
+	DatabasePath   string                      `yaml:"database_path"`     // Путь к файлу SQLite
+	DatabaseURL    string                      `yaml:"database_url"`      // Адрес PostgreSQL, если задан - используется вместо SQLite
+	FFmpegPath     string                      `yaml:"ffmpeg_path"`       // Путь к ffmpeg
+	FFprobePath    string                      `yaml:"ffprobe_path"`      // Путь к ffprobe
+	Workers        int                         `yaml:"transcode_workers"` // Сколько видео транскодируется одновременно
+	MaxUploadSize  int64                       `yaml:"max_upload_size"`   // Максимальный размер загружаемого файла в байтах
+	UploadExpiry   time.Duration               `yaml:"upload_expiration"` // Через сколько удаляется брошенная возобновляемая загрузка
+	DefaultProfile string                      `yaml:"default_profile"`   // Профиль для загрузок без явного выбора
+	Profiles       map[string]utils.HLSProfile `yaml:"profiles"`          // Профили кодирования по имени
+	Storage        StorageConfig               `yaml:"storage"`           // Где хранятся готовые видео
+	PlaybackSecret string                      `yaml:"playback_secret"`   // Ключ подписи ссылок на просмотр
+	PlaybackTTL    time.Duration               `yaml:"playback_ttl"`      // Сколько действует ссылка на просмотр
+	JWTSecret      string                      `yaml:"jwt_secret"`        // Ключ подписи токенов пользователей
+	AccessTTL      time.Duration               `yaml:"access_token_ttl"`  // Сколько действует access-токен
+	RefreshTTL     time.Duration               `yaml:"refresh_token_ttl"` // Сколько действует refresh-токен
+	AutoMigrate    bool                        `yaml:"auto_migrate"`      // Применять миграции базы при запуске
+	RoomPolicy     map[string]string           `yaml:"room_policy"`       // Какие роли участников что могут в комнате, см. rooms.NewPolicy
+}
+
+// StorageConfig - настройки хранилища готовых видео.
+type StorageConfig struct {
+	Type string   `yaml:"type"` // local или s3
+	S3   S3Config `yaml:"s3"`   // Используется при type: s3
+}
+
+// S3Config - настройки S3-совместимого хранилища.
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`   // Адрес без схемы, например "localhost:9000"
+	Bucket    string `yaml:"bucket"`     // Бакет создается при запуске, если его нет
+	Prefix    string `yaml:"prefix"`     // Префикс ключей внутри бакета
+	Region    string `yaml:"region"`     // Регион, для MinIO можно не задавать
+	AccessKey string `yaml:"access_key"` // Ключ доступа
+	SecretKey string `yaml:"secret_key"` // Секретный ключ
+	UseSSL    bool   `yaml:"use_ssl"`    // Подключаться по HTTPS
+}
+
+// Default возвращает конфигурацию по умолчанию.
+func Default() *Config {
+	return &Config{
+		ListenAddr:   ":3030",
+		UploadDir:    "./uploads",
+		TemporaryDir: "./temp",
+		DatabasePath: "./sqlite.db",
+		FFmpegPath:   "ffmpeg",
+		FFprobePath:  "ffprobe",
+		// ffmpeg загружает все ядра, поэтому больше двух-трёх обычно не нужно
+		Workers:        2,
+		MaxUploadSize:  50 << 30, // 50 ГиБ
+		UploadExpiry:   24 * time.Hour,
+		DefaultProfile: DefaultProfileName,
+		Profiles: map[string]utils.HLSProfile{
+			DefaultProfileName: utils.DefaultProfile,
+		},
+		Storage:     StorageConfig{Type: StorageLocal},
+		PlaybackTTL: 6 * time.Hour, // Хватает на фильм с перерывами
+		AccessTTL:   15 * time.Minute,
+		RefreshTTL:  30 * 24 * time.Hour,
+		AutoMigrate: true,
+	}
+}
+
+// Load читает конфигурацию из YAML-файла path поверх значений по умолчанию
+// и применяет переопределения из переменных окружения VIDEO_*.
+// Отсутствующий файл не ошибка: тогда используются только умолчания и окружение.
+// Load также обновляет UploadDir, TemporaryDir и пути к ffmpeg в пакете utils.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		fmt.Printf("Файл конфигурации %q не найден, используются настройки по умолчанию.\n", path)
+	case err != nil:
+		return nil, fmt.Errorf("не удалось прочитать файл конфигурации %q: %w", path, err)
+	default:
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("не удалось разобрать файл конфигурации %q: %w", path, err)
+		}
+	}
+
+	// Незаданные в профиле поля берем из профиля по умолчанию
+	for name, profile := range cfg.Profiles {
+		if len(profile.Qualities) == 0 {
+			profile.Qualities = utils.DefaultProfile.Qualities
+		}
+		if profile.SegmentDuration == 0 {
+			profile.SegmentDuration = utils.DefaultProfile.SegmentDuration
+		}
+		if profile.Preset == "" {
+			profile.Preset = utils.DefaultProfile.Preset
+		}
+		if profile.ThumbnailStep == 0 {
+			profile.ThumbnailStep = utils.DefaultProfile.ThumbnailStep
+		}
+		cfg.Profiles[name] = profile
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("некорректная конфигурация: %w", err)
+	}
+
+	UploadDir = cfg.UploadDir
+	TemporaryDir = cfg.TemporaryDir
+	utils.FFmpegPath = cfg.FFmpegPath
+	utils.FFprobePath = cfg.FFprobePath
+	return cfg, nil
+}
+
+// DatabaseDSN возвращает строку подключения к базе: database_url, если он задан,
+// иначе путь к файлу SQLite.
+func (c *Config) DatabaseDSN() string {
+	if c.DatabaseURL != "" {
+		return c.DatabaseURL
+	}
+	return c.DatabasePath
+}
+
+// Profile возвращает профиль кодирования по имени; пустое имя - профиль по умолчанию.
+func (c *Config) Profile(name string) (utils.HLSProfile, bool) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	profile, ok := c.Profiles[name]
+	return profile, ok
+}
+
+// applyEnv переопределяет настройки из переменных окружения.
+func (c *Config) applyEnv() error {
+	stringVars := map[string]*string{
+		"VIDEO_LISTEN_ADDR":     &c.ListenAddr,
+		"VIDEO_UPLOAD_DIR":      &c.UploadDir,
+		"VIDEO_TEMPORARY_DIR":   &c.TemporaryDir,
+		"VIDEO_DATABASE_PATH":   &c.DatabasePath,
+		"VIDEO_DATABASE_URL":    &c.DatabaseURL,
+		"VIDEO_FFMPEG_PATH":     &c.FFmpegPath,
+		"VIDEO_FFPROBE_PATH":    &c.FFprobePath,
+		"VIDEO_DEFAULT_PROFILE": &c.DefaultProfile,
+		"VIDEO_STORAGE_TYPE":    &c.Storage.Type,
+		"VIDEO_S3_ENDPOINT":     &c.Storage.S3.Endpoint,
+		"VIDEO_S3_BUCKET":       &c.Storage.S3.Bucket,
+		"VIDEO_S3_PREFIX":       &c.Storage.S3.Prefix,
+		"VIDEO_S3_REGION":       &c.Storage.S3.Region,
+		"VIDEO_S3_ACCESS_KEY":   &c.Storage.S3.AccessKey,
+		"VIDEO_S3_SECRET_KEY":   &c.Storage.S3.SecretKey,
+		"VIDEO_PLAYBACK_SECRET": &c.PlaybackSecret,
+		"VIDEO_JWT_SECRET":      &c.JWTSecret,
+	}
+	for env, field := range stringVars {
+		if value, ok := os.LookupEnv(env); ok {
+			*field = value
+		}
+	}
+
+	if value, ok := os.LookupEnv("VIDEO_TRANSCODE_WORKERS"); ok {
+		workers, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("некорректное значение VIDEO_TRANSCODE_WORKERS %q: %w", value, err)
+		}
+		c.Workers = workers
+	}
+	if value, ok := os.LookupEnv("VIDEO_MAX_UPLOAD_SIZE"); ok {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("некорректное значение VIDEO_MAX_UPLOAD_SIZE %q: %w", value, err)
+		}
+		c.MaxUploadSize = size
+	}
+	if value, ok := os.LookupEnv("VIDEO_S3_USE_SSL"); ok {
+		useSSL, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("некорректное значение VIDEO_S3_USE_SSL %q: %w", value, err)
+		}
+		c.Storage.S3.UseSSL = useSSL
+	}
+	if value, ok := os.LookupEnv("VIDEO_AUTO_MIGRATE"); ok {
+		autoMigrate, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("некорректное значение VIDEO_AUTO_MIGRATE %q: %w", value, err)
+		}
+		c.AutoMigrate = autoMigrate
+	}
+	if value, ok := os.LookupEnv("VIDEO_UPLOAD_EXPIRATION"); ok {
+		expiry, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("некорректное значение VIDEO_UPLOAD_EXPIRATION %q: %w", value, err)
+		}
+		c.UploadExpiry = expiry
+	}
+	durationVars := map[string]*time.Duration{
+		"VIDEO_PLAYBACK_TTL":      &c.PlaybackTTL,
+		"VIDEO_ACCESS_TOKEN_TTL":  &c.AccessTTL,
+		"VIDEO_REFRESH_TOKEN_TTL": &c.RefreshTTL,
+	}
+	for env, field := range durationVars {
+		if value, ok := os.LookupEnv(env); ok {
+			ttl, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("некорректное значение %s %q: %w", env, value, err)
+			}
+			*field = ttl
+		}
+	}
+	return nil
+}
+
+// validate проверяет согласованность настроек.
+func (c *Config) validate() error {
+	if c.ListenAddr == "" || c.UploadDir == "" || c.TemporaryDir == "" || c.DatabasePath == "" {
+		return fmt.Errorf("listen_addr, upload_dir, temporary_dir и database_path обязательны")
+	}
+	if c.Workers < 1 {
+		return fmt.Errorf("transcode_workers должен быть положительным: %d", c.Workers)
+	}
+	if c.MaxUploadSize <= 0 || c.UploadExpiry <= 0 || c.PlaybackTTL <= 0 {
+		return fmt.Errorf("max_upload_size, upload_expiration и playback_ttl должны быть положительными")
+	}
+	if c.AccessTTL <= 0 || c.RefreshTTL < c.AccessTTL {
+		return fmt.Errorf("access_token_ttl должен быть положительным и не больше refresh_token_ttl")
+	}
+	if c.DatabaseURL != "" && !strings.HasPrefix(c.DatabaseURL, "postgres://") && !strings.HasPrefix(c.DatabaseURL, "postgresql://") {
+		return fmt.Errorf("database_url должен начинаться с postgres:// или postgresql://")
+	}
+	switch c.Storage.Type {
+	case StorageLocal:
+	case StorageS3:
+		if c.Storage.S3.Endpoint == "" || c.Storage.S3.Bucket == "" {
+			return fmt.Errorf("для хранилища s3 обязательны storage.s3.endpoint и storage.s3.bucket")
+		}
+	default:
+		return fmt.Errorf("неизвестный тип хранилища %q: ожидается %s или %s", c.Storage.Type, StorageLocal, StorageS3)
+	}
+	for name, profile := range c.Profiles {
+		if err := profile.Validate(); err != nil {
+			return fmt.Errorf("профиль %q: %w", name, err)
+		}
+	}
+	if _, ok := c.Profiles[c.DefaultProfile]; !ok {
+		return fmt.Errorf("профиль по умолчанию %q не задан", c.DefaultProfile)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"video/utils"
+)
+
+// load записывает yaml во временный файл и загружает из него конфигурацию
+// с переменными окружения env. VIDEO_* из окружения теста не учитываются,
+// а глобальные пути, которые меняет Load, восстанавливаются после теста.
+func load(t *testing.T, yaml string, env map[string]string) (*Config, error) {
+	t.Helper()
+	for _, kv := range os.Environ() {
+		if key, _, _ := strings.Cut(kv, "="); strings.HasPrefix(key, "VIDEO_") {
+			t.Setenv(key, "")
+			os.Unsetenv(key)
+		}
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	oldUpload, oldTemp, oldFFmpeg, oldFFprobe := UploadDir, TemporaryDir, utils.FFmpegPath, utils.FFprobePath
+	t.Cleanup(func() {
+		UploadDir, TemporaryDir, utils.FFmpegPath, utils.FFprobePath = oldUpload, oldTemp, oldFFmpeg, oldFFprobe
+	})
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if yaml != "" {
+		if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Load(path)
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(t, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Default()
+	if cfg.ListenAddr != want.ListenAddr || cfg.Workers != want.Workers || cfg.DefaultProfile != DefaultProfileName {
+		t.Errorf("без файла конфигурации: %+v", *cfg)
+	}
+	if profile, ok := cfg.Profile(""); !ok || profile.Preset != utils.DefaultProfile.Preset {
+		t.Errorf("профиль по умолчанию = %+v, %v", profile, ok)
+	}
+	if UploadDir != cfg.UploadDir || TemporaryDir != cfg.TemporaryDir {
+		t.Errorf("глобальные папки не обновлены: %q, %q", UploadDir, TemporaryDir)
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	cfg, err := load(t, `
+default_profile: fast
+profiles:
+  fast:
+    preset: veryfast
+    segment_duration: 4
+  hd:
+    crf: 20
+    qualities:
+      - {name: 720p, height: 720, video_bitrate: 2M, audio_bitrate: 128k}
+      - {name: 1080p, height: 1080, video_bitrate: 5M, audio_bitrate: 192k}
+room_policy:
+  chat: moderator
+`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Профиль по умолчанию остается, даже если в файле заданы только свои
+	if _, ok := cfg.Profiles[DefaultProfileName]; !ok || len(cfg.Profiles) != 3 {
+		t.Errorf("профили = %v, ожидались default, fast и hd", cfg.Profiles)
+	}
+	fast, ok := cfg.Profile("")
+	if !ok || fast.Preset != "veryfast" || fast.SegmentDuration != 4 {
+		t.Fatalf("профиль по умолчанию = %+v, %v; ожидался fast", fast, ok)
+	}
+	// Незаданные поля берутся из профиля по умолчанию
+	if len(fast.Qualities) != len(utils.DefaultLadder) || fast.ThumbnailStep != utils.DefaultProfile.ThumbnailStep {
+		t.Errorf("профиль fast не дополнен умолчаниями: %+v", fast)
+	}
+	hd, _ := cfg.Profile("hd")
+	if len(hd.Qualities) != 2 || hd.Qualities[1].BaseName != "1080p" || hd.CRF != 20 ||
+		hd.Preset != utils.DefaultProfile.Preset || hd.SegmentDuration != utils.DefaultProfile.SegmentDuration {
+		t.Errorf("профиль hd = %+v", hd)
+	}
+	if _, ok := cfg.Profile("missing"); ok {
+		t.Error("найден незаданный профиль")
+	}
+
+	// Политику комнат дополняет умолчаниями и проверяет rooms.NewPolicy
+	if len(cfg.RoomPolicy) != 1 || cfg.RoomPolicy["chat"] != "moderator" {
+		t.Errorf("room_policy = %v, ожидался только chat: moderator из файла", cfg.RoomPolicy)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	cfg, err := load(t, `
+listen_addr: ":8080"
+transcode_workers: 4
+temporary_dir: ./from-file
+profiles:
+  hd:
+    qualities:
+      - {name: 720p, height: 720, video_bitrate: 2M, audio_bitrate: 128k}
+`, map[string]string{
+		"VIDEO_LISTEN_ADDR":        ":9090",
+		"VIDEO_TRANSCODE_WORKERS":  "3",
+		"VIDEO_TEMPORARY_DIR":      "/tmp/video-env",
+		"VIDEO_FFMPEG_PATH":        "/opt/ffmpeg/bin/ffmpeg",
+		"VIDEO_DEFAULT_PROFILE":    "hd",
+		"VIDEO_MAX_UPLOAD_SIZE":    "1048576",
+		"VIDEO_UPLOAD_EXPIRATION":  "2h",
+		"VIDEO_PLAYBACK_TTL":       "90m",
+		"VIDEO_ACCESS_TOKEN_TTL":   "5m",
+		"VIDEO_REFRESH_TOKEN_TTL":  "24h",
+		"VIDEO_AUTO_MIGRATE":       "false",
+		"VIDEO_STORAGE_TYPE":       "s3",
+		"VIDEO_S3_ENDPOINT":        "localhost:9000",
+		"VIDEO_S3_BUCKET":          "videos",
+		"VIDEO_S3_USE_SSL":         "true",
+		"VIDEO_DATABASE_URL":       "postgres://video@localhost/video",
+		"VIDEO_PLAYBACK_SECRET":    "playback",
+		"VIDEO_JWT_SECRET":         "jwt",
+		"VIDEO_S3_SECRET_KEY":      "secret",
+		"VIDEO_UNKNOWN_SETTING_XX": "ignored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"listen_addr", cfg.ListenAddr, ":9090"},
+		{"transcode_workers", cfg.Workers, 3},
+		{"temporary_dir", cfg.TemporaryDir, "/tmp/video-env"},
+		{"ffmpeg_path", cfg.FFmpegPath, "/opt/ffmpeg/bin/ffmpeg"},
+		{"default_profile", cfg.DefaultProfile, "hd"},
+		{"max_upload_size", cfg.MaxUploadSize, int64(1048576)},
+		{"upload_expiration", cfg.UploadExpiry, 2 * time.Hour},
+		{"playback_ttl", cfg.PlaybackTTL, 90 * time.Minute},
+		{"access_token_ttl", cfg.AccessTTL, 5 * time.Minute},
+		{"refresh_token_ttl", cfg.RefreshTTL, 24 * time.Hour},
+		{"auto_migrate", cfg.AutoMigrate, false},
+		{"storage.type", cfg.Storage.Type, StorageS3},
+		{"storage.s3.endpoint", cfg.Storage.S3.Endpoint, "localhost:9000"},
+		{"storage.s3.bucket", cfg.Storage.S3.Bucket, "videos"},
+		{"storage.s3.use_ssl", cfg.Storage.S3.UseSSL, true},
+		{"storage.s3.secret_key", cfg.Storage.S3.SecretKey, "secret"},
+		{"database_url", cfg.DatabaseDSN(), "postgres://video@localhost/video"},
+		{"playback_secret", cfg.PlaybackSecret, "playback"},
+		{"jwt_secret", cfg.JWTSecret, "jwt"},
+		// Поля без переменной окружения сохраняют значения из файла и умолчания
+		{"upload_dir", cfg.UploadDir, Default().UploadDir},
+		{"ffprobe_path", cfg.FFprobePath, "ffprobe"},
+		// Глобальные пути берутся из итоговой конфигурации
+		{"TemporaryDir", TemporaryDir, "/tmp/video-env"},
+		{"utils.FFmpegPath", utils.FFmpegPath, "/opt/ffmpeg/bin/ffmpeg"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, ожидалось %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadEnvInvalid(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"VIDEO_TRANSCODE_WORKERS": "два"},
+		{"VIDEO_MAX_UPLOAD_SIZE": "1GB"},
+		{"VIDEO_S3_USE_SSL": "да"},
+		{"VIDEO_AUTO_MIGRATE": "yes please"},
+		{"VIDEO_UPLOAD_EXPIRATION": "24"},
+		{"VIDEO_PLAYBACK_TTL": "6 hours"},
+		{"VIDEO_TRANSCODE_WORKERS": "0"},
+		{"VIDEO_DEFAULT_PROFILE": "missing"},
+		{"VIDEO_REFRESH_TOKEN_TTL": "1m"}, // Короче access-токена
+	} {
+		if _, err := load(t, "", env); err == nil {
+			t.Errorf("окружение %v: ошибка не возвращена", env)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string // Часть текста ошибки
+	}{
+		{"не YAML", "profiles: [", "не удалось разобрать"},
+		{"неизвестный профиль по умолчанию", "default_profile: missing", `профиль по умолчанию "missing"`},
+		{"качество без битрейта", `
+profiles:
+  hd:
+    qualities:
+      - {name: 720p, height: 720}
+`, `профиль "hd"`},
+		{"повтор качества", `
+profiles:
+  hd:
+    qualities:
+      - {name: 720p, height: 720, video_bitrate: 2M, audio_bitrate: 128k}
+      - {name: 720p, height: 720, video_bitrate: 3M, audio_bitrate: 128k}
+`, "задано дважды"},
+		{"отрицательная высота", `
+profiles:
+  hd:
+    qualities:
+      - {name: 720p, height: -720, video_bitrate: 2M, audio_bitrate: 128k}
+`, `профиль "hd"`},
+		{"CRF вне диапазона", `
+profiles:
+  hd:
+    crf: 60
+`, "CRF"},
+		{"отрицательный сегмент", `
+profiles:
+  hd:
+    segment_duration: -1
+`, "длительность сегмента"},
+		{"неизвестное хранилище", "storage: {type: ftp}", "неизвестный тип хранилища"},
+		{"s3 без бакета", "storage: {type: s3, s3: {endpoint: localhost:9000}}", "storage.s3.bucket"},
+		{"database_url не postgres", "database_url: mysql://localhost/video", "database_url"},
+		{"нет воркеров", "transcode_workers: 0", "transcode_workers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.yaml, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ошибка = %v, ожидалась с %q", err, tt.want)
+			}
+		})
+	}
+}
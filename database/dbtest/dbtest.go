@@ -0,0 +1,24 @@
+// Package dbtest готовит базу данных для тестов других пакетов.
+package dbtest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"video/database"
+)
+
+// Open открывает чистую базу SQLite во временной папке теста и применяет к ней миграции.
+// База закрывается в конце теста.
+func Open(t testing.TB) *database.DB {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
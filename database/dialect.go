@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Поддерживаемые СУБД. Запросы пишутся с плейсхолдерами "?",
+// для PostgreSQL они заменяются на $1, $2, ... перед выполнением.
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+)
+
+// Dialect возвращает СУБД, к которой подключена база: SQLite или Postgres.
+func (db *DB) Dialect() string {
+	return db.dialect
+}
+
+// rebind переписывает плейсхолдеры "?" под диалект базы.
+// Вопросительные знаки внутри строковых литералов не трогаются.
+func (db *DB) rebind(query string) string {
+	if db.dialect != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inString = !inString
+		case c == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// exec, query и queryRow выполняют запрос с плейсхолдерами "?" в любом диалекте.
+func (db *DB) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.conn.ExecContext(ctx, db.rebind(query), args...)
+}
+
+func (db *DB) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.conn.QueryContext(ctx, db.rebind(query), args...)
+}
+
+func (db *DB) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.conn.QueryRowContext(ctx, db.rebind(query), args...)
+}
+
+// insertID выполняет INSERT и возвращает ID новой строки.
+// LastInsertId не поддерживается драйвером PostgreSQL, поэтому используется RETURNING.
+func (db *DB) insertID(ctx context.Context, query string, args ...interface{}) (int, error) {
+	var id int
+	if err := db.queryRow(ctx, query+` RETURNING id`, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// skipLocked возвращает блокировку строки, выбираемой из очереди.
+// В PostgreSQL параллельные воркеры пропускают уже взятые строки,
+// SQLite пишет в базу по одному и блокировок строк не знает.
+func (db *DB) skipLocked() string {
+	if db.dialect == Postgres {
+		return ` FOR UPDATE SKIP LOCKED`
+	}
+	return ``
+}
+
+// secondsFromNow возвращает выражение для момента через "?" секунд от текущего.
+// В SQLite результат записывается в том же виде, что CURRENT_TIMESTAMP,
+// поэтому его можно сравнивать с CURRENT_TIMESTAMP как строку.
+func (db *DB) secondsFromNow() string {
+	if db.dialect == Postgres {
+		return `CURRENT_TIMESTAMP + make_interval(secs => ?)`
+	}
+	return `datetime(CURRENT_TIMESTAMP, '+' || ? || ' seconds')`
+}
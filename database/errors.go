@@ -0,0 +1,42 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Ошибки хранилища, которые проверяются через errors.Is.
+// Все остальные ошибки означают сбой самой базы.
+var (
+	ErrNotFound = errors.New("запись не найдена")
+	ErrConflict = errors.New("запись уже существует")
+	// ErrStale - изменение опирается на устаревшее состояние: запись успели изменить
+	// параллельным запросом. Повторить можно, заново прочитав запись.
+	ErrStale = errors.New("запись успели изменить")
+)
+
+// pgUniqueViolation - код ошибки PostgreSQL при нарушении UNIQUE.
+const pgUniqueViolation = "23505"
+
+// wrapError приводит ошибку драйвера к ErrNotFound или ErrConflict, если это возможно.
+func wrapError(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case isUniqueViolation(err):
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	return err
+}
+
+// isUniqueViolation сообщает, нарушает ли запрос ограничение UNIQUE или PRIMARY KEY.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	return isSQLiteUniqueViolation(err)
+}
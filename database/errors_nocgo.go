@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package database
+
+// Без CGO драйвер SQLite не работает, а его ошибки недоступны.
+func isSQLiteUniqueViolation(err error) bool {
+	return false
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Статусы задачи транскодирования.
+const (
+	JobQueued    = "queued"    // Ожидает свободного воркера
+	JobRunning   = "running"   // Выполняется
+	JobSucceeded = "succeeded" // Завершилась успешно
+	JobFailed    = "failed"    // Завершилась ошибкой
+)
+
+// JobStorage определяет контракт для работы с очередью задач транскодирования.
+type JobStorage interface {
+	InsertJob(ctx context.Context, videoID int, fileName, profile string) (*Job, error)
+	GetJobByID(ctx context.Context, id int) (*Job, error)
+	GetLatestJobByVideoID(ctx context.Context, videoID int) (*Job, error)
+	ClaimNextJob(ctx context.Context) (*Job, error)
+	FinishJob(ctx context.Context, id int, status, errorMessage string) error
+	RetryJob(ctx context.Context, id int, errorMessage string, delay time.Duration) error
+	RequeueRunningJobs(ctx context.Context) (int, error)
+}
+
+// Job представляет задачу транскодирования загруженного файла в HLS.
+type Job struct {
+	ID           int        `json:"id"`                  // Уникальный идентификатор
+	VideoID      int        `json:"video_id"`            // Видео, для которого генерируется HLS
+	FileName     string     `json:"file_name"`           // Имя исходного файла во временной папке
+	Profile      string     `json:"profile"`             // Имя профиля кодирования, пустое - по умолчанию
+	Status       string     `json:"status"`              // queued, running, succeeded или failed
+	Attempts     int        `json:"attempts"`            // Сколько раз задача бралась в работу
+	ErrorMessage string     `json:"error_message"`       // Текст ошибки для статуса failed
+	RunAfter     *time.Time `json:"run_after,omitempty"` // Раньше этого времени повтор не начнется, nil - можно сразу
+	CreatedAt    time.Time  `json:"created_at"`          // Время постановки в очередь
+	UpdatedAt    time.Time  `json:"updated_at"`          // Время последнего изменения статуса
+}
+
+const jobColumns = `id, video_id, file_name, profile, status, attempts, error_message, run_after, created_at, updated_at`
+
+// InsertJob ставит в очередь задачу транскодирования файла fileName для видео videoID
+// с профилем кодирования profile.
+func (db *DB) InsertJob(ctx context.Context, videoID int, fileName, profile string) (*Job, error) {
+	insertSQL := `INSERT INTO jobs (video_id, file_name, profile, status) VALUES (?, ?, ?, ?)`
+	id, err := db.insertID(ctx, insertSQL, videoID, fileName, profile, JobQueued)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка постановки задачи в очередь (video_id: %d, file_name: '%s'): %w", videoID, fileName, wrapError(err))
+	}
+	return db.GetJobByID(ctx, id)
+}
+
+// GetJobByID получает задачу по ее ID.
+func (db *DB) GetJobByID(ctx context.Context, id int) (*Job, error) {
+	querySQL := `SELECT ` + jobColumns + ` FROM jobs WHERE id = ?`
+	job, err := scanJob(db.queryRow(ctx, querySQL, id))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения задачи по ID %d: %w", id, wrapError(err))
+	}
+	return job, nil
+}
+
+// GetLatestJobByVideoID получает последнюю задачу транскодирования видео.
+func (db *DB) GetLatestJobByVideoID(ctx context.Context, videoID int) (*Job, error) {
+	querySQL := `SELECT ` + jobColumns + ` FROM jobs WHERE video_id = ? ORDER BY id DESC LIMIT 1`
+	job, err := scanJob(db.queryRow(ctx, querySQL, videoID))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения задачи по video_id %d: %w", videoID, wrapError(err))
+	}
+	return job, nil
+}
+
+// ClaimNextJob атомарно переводит самую старую задачу из очереди в статус running.
+// Задачи, время повтора которых еще не пришло, пропускаются.
+// Если брать нечего, возвращает nil без ошибки.
+func (db *DB) ClaimNextJob(ctx context.Context) (*Job, error) {
+	claimSQL := `
+	UPDATE jobs SET status = ?, attempts = attempts + 1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = (
+		SELECT id FROM jobs WHERE status = ? AND (run_after IS NULL OR run_after <= CURRENT_TIMESTAMP)
+		ORDER BY id LIMIT 1` + db.skipLocked() + `
+	)
+	RETURNING ` + jobColumns
+	job, err := scanJob(db.queryRow(ctx, claimSQL, JobRunning, JobQueued))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения задачи из очереди: %w", err)
+	}
+	return job, nil
+}
+
+// FinishJob записывает статус задачи после попытки и текст ошибки, если она была.
+func (db *DB) FinishJob(ctx context.Context, id int, status, errorMessage string) error {
+	updateSQL := `UPDATE jobs SET status = ?, error_message = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	result, err := db.exec(ctx, updateSQL, status, errorMessage, id)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления задачи с ID %d: %w", id, wrapError(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("задача с ID %d не найдена для обновления: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// RetryJob возвращает задачу в очередь после неудачной попытки с текстом ошибки.
+// Снова взять ее можно будет не раньше чем через delay.
+func (db *DB) RetryJob(ctx context.Context, id int, errorMessage string, delay time.Duration) error {
+	updateSQL := `UPDATE jobs SET status = ?, error_message = ?, run_after = ` + db.secondsFromNow() + `,
+	updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	result, err := db.exec(ctx, updateSQL, JobQueued, errorMessage, delay.Seconds(), id)
+	if err != nil {
+		return fmt.Errorf("ошибка возврата задачи с ID %d в очередь: %w", id, wrapError(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("задача с ID %d не найдена для повтора: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// RequeueRunningJobs возвращает в очередь задачи, прерванные остановкой сервера.
+// Возвращает количество возвращенных задач.
+func (db *DB) RequeueRunningJobs(ctx context.Context) (int, error) {
+	updateSQL := `UPDATE jobs SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE status = ?`
+	result, err := db.exec(ctx, updateSQL, JobQueued, JobRunning)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка возврата прерванных задач в очередь: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// scanJob читает задачу из строки результата запроса.
+func scanJob(row *sql.Row) (*Job, error) {
+	var j Job
+	var runAfter sql.NullTime
+	err := row.Scan(&j.ID, &j.VideoID, &j.FileName, &j.Profile, &j.Status, &j.Attempts, &j.ErrorMessage, &runAfter, &j.CreatedAt, &j.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if runAfter.Valid {
+		j.RunAfter = &runAfter.Time
+	}
+	return &j, nil
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MessageStorage определяет контракт для работы с историей чата комнат.
+type MessageStorage interface {
+	InsertMessage(ctx context.Context, m Message) (*Message, error)
+	GetMessages(ctx context.Context, roomID, beforeID, limit int) (*MessagePage, error)
+}
+
+// Message представляет сообщение чата комнаты.
+type Message struct {
+	ID         int       `json:"id"`          // Уникальный идентификатор, растет со временем отправки
+	RoomID     int       `json:"room_id"`     // Комната
+	MemberID   int       `json:"member_id"`   // Автор
+	MemberName string    `json:"member_name"` // Имя автора на момент отправки
+	VideoID    int       `json:"video_id"`    // Видео, которое смотрели при отправке
+	Position   float64   `json:"position"`    // Позиция в этом видео при отправке, секунды
+	Text       string    `json:"text"`        // Текст, выводится клиентом как обычный текст
+	CreatedAt  time.Time `json:"created_at"`  // Время отправки
+}
+
+// MessagePage - страница истории чата, от старых сообщений к новым.
+type MessagePage struct {
+	Messages []Message `json:"messages"`
+	HasMore  bool      `json:"has_more"` // Есть ли сообщения старше первого на странице
+}
+
+const messageColumns = `id, room_id, member_id, member_name, video_id, position, text, created_at`
+
+// InsertMessage сохраняет сообщение m. ID и CreatedAt заполняет база.
+func (db *DB) InsertMessage(ctx context.Context, m Message) (*Message, error) {
+	insertSQL := `INSERT INTO room_messages (room_id, member_id, member_name, video_id, position, text) VALUES (?, ?, ?, ?, ?, ?)`
+	id, err := db.insertID(ctx, insertSQL, m.RoomID, m.MemberID, m.MemberName, m.VideoID, m.Position, m.Text)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения сообщения в комнате %d: %w", m.RoomID, wrapError(err))
+	}
+
+	querySQL := `SELECT ` + messageColumns + ` FROM room_messages WHERE id = ?`
+	var saved Message
+	err = db.queryRow(ctx, querySQL, id).Scan(&saved.ID, &saved.RoomID, &saved.MemberID, &saved.MemberName, &saved.VideoID, &saved.Position, &saved.Text, &saved.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения сообщения по ID %d: %w", id, wrapError(err))
+	}
+	return &saved, nil
+}
+
+// GetMessages возвращает не больше limit последних сообщений комнаты roomID
+// с ID меньше beforeID. beforeID = 0 - самые последние сообщения.
+// Чтобы прокрутить историю дальше, передайте ID первого сообщения страницы.
+func (db *DB) GetMessages(ctx context.Context, roomID, beforeID, limit int) (*MessagePage, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("размер страницы должен быть положительным: %d", limit)
+	}
+
+	querySQL := `SELECT ` + messageColumns + ` FROM room_messages WHERE room_id = ?`
+	args := []interface{}{roomID}
+	if beforeID > 0 {
+		querySQL += ` AND id < ?`
+		args = append(args, beforeID)
+	}
+	querySQL += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit+1) // Лишнее сообщение показывает, есть ли история дальше
+
+	rows, err := db.query(ctx, querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.RoomID, &m.MemberID, &m.MemberName, &m.VideoID, &m.Position, &m.Text, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %w", err)
+	}
+
+	page := &MessagePage{HasMore: len(messages) > limit}
+	if page.HasMore {
+		messages = messages[:limit]
+	}
+	slices.Reverse(messages)
+	page.Messages = messages
+	return page, nil
+}
@@ -0,0 +1,403 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Миграции схемы лежат в migrations/<диалект>/ парами файлов NNNN_name.up.sql и
+// NNNN_name.down.sql и встраиваются в бинарник. Номера версий у SQLite и PostgreSQL
+// совпадают. Примененные версии записываются в таблицу schema_migrations.
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration - одна версия схемы базы данных.
+type Migration struct {
+	Version int    // Номер версии, миграции применяются по возрастанию
+	Name    string // Краткое описание из имени файла
+	Up      string // SQL применения
+	Down    string // SQL отката
+}
+
+// baselineTables - таблицы, которые сервис создавал до появления миграций.
+// Порядок важен: таблицы переносятся так, чтобы ссылки шли на уже созданные.
+var baselineTables = []string{"users", "videos", "rooms", "room_members", "jobs", "uploads"}
+
+// Migrations возвращает встроенные миграции для диалекта базы, упорядоченные по версии.
+func (db *DB) Migrations() ([]Migration, error) {
+	dir := "migrations/" + db.dialect
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения миграций: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("некорректное имя файла миграции '%s'", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := migrationFiles.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения миграции '%s': %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("у миграции %d разные имена: '%s' и '%s'", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s нет файла up или down", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// LatestVersion возвращает версию последней встроенной миграции.
+func (db *DB) LatestVersion() (int, error) {
+	migrations, err := db.Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Version возвращает текущую версию схемы, 0 - миграции не применялись.
+func (db *DB) Version(ctx context.Context) (int, error) {
+	exists, err := db.tableExists(ctx, "schema_migrations")
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version sql.NullInt64
+	if err := db.queryRow(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("ошибка чтения версии схемы: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// Migrate применяет все еще не примененные миграции и готовит индекс поиска видео.
+// База, созданная версией сервиса без миграций, сначала переводится на первую миграцию
+// с сохранением данных.
+func (db *DB) Migrate(ctx context.Context) error {
+	migrations, err := db.Migrations()
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	exists, err := db.tableExists(ctx, "schema_migrations")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := db.adoptLegacySchema(ctx, migrations[0]); err != nil {
+			return err
+		}
+	}
+
+	current, err := db.Version(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		err := db.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, db.rebind(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`), m.Version, m.Name)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка применения миграции %04d_%s: %w", m.Version, m.Name, err)
+		}
+		fmt.Printf("Применена миграция %04d_%s.\n", m.Version, m.Name)
+	}
+	return db.syncSearchIndex(ctx)
+}
+
+// Rollback откатывает steps последних примененных миграций.
+func (db *DB) Rollback(ctx context.Context, steps int) error {
+	migrations, err := db.Migrations()
+	if err != nil {
+		return err
+	}
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+	fts5 := hasFTS5(ctx, db.conn)
+
+	for i := 0; i < steps; i++ {
+		current, err := db.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if current == 0 {
+			fmt.Println("Откатывать нечего: миграции не применялись.")
+			return nil
+		}
+		m, ok := byVersion[current]
+		if !ok {
+			return fmt.Errorf("миграция %d применена, но ее нет в этой версии сервиса", current)
+		}
+		err = db.inTx(ctx, func(tx *sql.Tx) error {
+			if db.dialect == SQLite && m.Version == searchMigration {
+				if err := db.dropSearchIndex(ctx, tx, fts5); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, db.rebind(`DELETE FROM schema_migrations WHERE version = ?`), m.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка отката миграции %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if m.Version == searchMigration {
+			db.fts = false
+		}
+		fmt.Printf("Откачена миграция %04d_%s.\n", m.Version, m.Name)
+	}
+	return nil
+}
+
+// adoptLegacySchema создает schema_migrations. Если в базе SQLite уже есть таблицы
+// от версии сервиса без миграций, они пересоздаются по первой миграции: SQLite не умеет менять
+// ограничения колонок, а в старой таблице videos, например, есть size NOT NULL
+// без значения по умолчанию. Данные общих колонок переносятся, лишние колонки теряются.
+func (db *DB) adoptLegacySchema(ctx context.Context, first Migration) error {
+	var legacy []string
+	if db.dialect == SQLite { // В PostgreSQL сервис до миграций не работал
+		for _, table := range baselineTables {
+			exists, err := db.tableExists(ctx, table)
+			if err != nil {
+				return err
+			}
+			if exists {
+				legacy = append(legacy, table)
+			}
+		}
+	}
+
+	createSQL := `
+	CREATE TABLE schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+	if len(legacy) == 0 {
+		if _, err := db.conn.ExecContext(ctx, createSQL); err != nil {
+			return fmt.Errorf("ошибка создания таблицы 'schema_migrations': %w", err)
+		}
+		return nil
+	}
+
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		oldColumns := make(map[string][]string, len(legacy))
+		for _, table := range legacy {
+			columns, err := tableColumns(ctx, tx, table)
+			if err != nil {
+				return err
+			}
+			oldColumns[table] = columns
+			// Индексы переименованной таблицы сохраняют имена и мешают создать новые
+			if err := dropIndexes(ctx, tx, table); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s RENAME TO %s_legacy`, table, table)); err != nil {
+				return fmt.Errorf("ошибка переименования таблицы '%s': %w", table, err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, first.Up); err != nil {
+			return fmt.Errorf("ошибка применения миграции %04d_%s: %w", first.Version, first.Name, err)
+		}
+
+		for _, table := range legacy {
+			newColumns, err := tableColumns(ctx, tx, table)
+			if err != nil {
+				return err
+			}
+			var common []string
+			for _, column := range newColumns {
+				if slices.Contains(oldColumns[table], column) {
+					common = append(common, column)
+				}
+			}
+			list := strings.Join(common, ", ")
+			copySQL := fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s_legacy`, table, list, list, table)
+			if _, err := tx.ExecContext(ctx, copySQL); err != nil {
+				return fmt.Errorf("ошибка переноса данных таблицы '%s': %w", table, err)
+			}
+		}
+
+		if columns, ok := oldColumns["videos"]; ok {
+			if err := adoptLegacyVideos(ctx, tx, columns); err != nil {
+				return err
+			}
+		}
+
+		for _, table := range legacy {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE %s_legacy`, table)); err != nil {
+				return fmt.Errorf("ошибка удаления старой таблицы '%s': %w", table, err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, createSQL); err != nil {
+			return fmt.Errorf("ошибка создания таблицы 'schema_migrations': %w", err)
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, first.Version, first.Name)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка перевода базы на миграции: %w", err)
+	}
+	fmt.Printf("Существующая база переведена на миграцию %04d_%s, таблицы: %s.\n",
+		first.Version, first.Name, strings.Join(legacy, ", "))
+	return nil
+}
+
+// adoptLegacyVideos заполняет новые колонки видео, перенесенных из старой таблицы.
+func adoptLegacyVideos(ctx context.Context, tx *sql.Tx, oldColumns []string) error {
+	// Видео, загруженные до появления статусов, уже сконвертированы — считаем их готовыми
+	if !slices.Contains(oldColumns, "status") {
+		if _, err := tx.ExecContext(ctx, `UPDATE videos SET status = ?`, VideoReady); err != nil {
+			return fmt.Errorf("ошибка заполнения статусов видео: %w", err)
+		}
+	}
+	if slices.Contains(oldColumns, "hls_error_message") {
+		updateSQL := `
+		UPDATE videos SET status = ?, error_message = (
+			SELECT hls_error_message FROM videos_legacy WHERE videos_legacy.id = videos.id
+		)
+		WHERE id IN (SELECT id FROM videos_legacy WHERE COALESCE(hls_error_message, '') <> '')`
+		if _, err := tx.ExecContext(ctx, updateSQL, VideoFailed); err != nil {
+			return fmt.Errorf("ошибка переноса ошибок конвертации видео: %w", err)
+		}
+	}
+	backfillSQL := `
+	UPDATE videos SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+	UPDATE videos SET updated_at = created_at WHERE updated_at IS NULL;`
+	if _, err := tx.ExecContext(ctx, backfillSQL); err != nil {
+		return fmt.Errorf("ошибка заполнения времени создания видео: %w", err)
+	}
+	return nil
+}
+
+// inTx выполняет fn в транзакции и откатывает ее при ошибке.
+func (db *DB) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// tableExists сообщает, есть ли в базе таблица table.
+func (db *DB) tableExists(ctx context.Context, table string) (bool, error) {
+	querySQL := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if db.dialect == Postgres {
+		querySQL = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	}
+	var count int
+	err := db.queryRow(ctx, querySQL, table).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("ошибка проверки таблицы '%s': %w", table, err)
+	}
+	return count > 0, nil
+}
+
+// tableColumns возвращает имена колонок таблицы table.
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения схемы таблицы '%s': %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return nil, fmt.Errorf("ошибка чтения схемы таблицы '%s': %w", table, err)
+		}
+		columns = append(columns, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения схемы таблицы '%s': %w", table, err)
+	}
+	return columns, nil
+}
+
+// dropIndexes удаляет созданные вручную индексы таблицы table.
+// Индексы UNIQUE и PRIMARY KEY (sql IS NULL) удаляются вместе с таблицей.
+func dropIndexes(ctx context.Context, tx *sql.Tx, table string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL`, table)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения индексов таблицы '%s': %w", table, err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("ошибка чтения индексов таблицы '%s': %w", table, err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения индексов таблицы '%s': %w", table, err)
+	}
+
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP INDEX %s`, name)); err != nil {
+			return fmt.Errorf("ошибка удаления индекса '%s': %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS uploads;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS room_members;
+DROP TABLE IF EXISTS rooms;
+DROP TABLE IF EXISTS videos;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,65 @@
+-- Начальная схема: пользователи, видео, комнаты, очередь транскодирования и tus-загрузки.
+
+CREATE TABLE users (
+	id SERIAL PRIMARY KEY,
+	username TEXT NOT NULL,
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Имена уникальны без учета регистра, как COLLATE NOCASE в SQLite
+CREATE UNIQUE INDEX idx_users_username ON users(lower(username));
+
+CREATE TABLE videos (
+	id SERIAL PRIMARY KEY,
+	video_name TEXT NOT NULL,
+	file_name TEXT UNIQUE,
+	status TEXT NOT NULL DEFAULT 'uploaded',
+	error_message TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ,
+	owner_id INTEGER REFERENCES users(id)
+);
+CREATE INDEX idx_videos_status ON videos(status);
+CREATE INDEX idx_videos_owner_id ON videos(owner_id);
+
+CREATE TABLE rooms (
+	id SERIAL PRIMARY KEY,
+	code TEXT NOT NULL UNIQUE,
+	video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE room_members (
+	id SERIAL PRIMARY KEY,
+	room_id INTEGER NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	token TEXT NOT NULL UNIQUE,
+	joined_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_room_members_room_id ON room_members(room_id);
+
+CREATE TABLE jobs (
+	id SERIAL PRIMARY KEY,
+	video_id INTEGER NOT NULL,
+	file_name TEXT NOT NULL,
+	profile TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'queued',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	error_message TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_jobs_status ON jobs(status, id);
+CREATE INDEX idx_jobs_video_id ON jobs(video_id);
+
+CREATE TABLE uploads (
+	id TEXT PRIMARY KEY,
+	length BIGINT NOT NULL,
+	metadata TEXT NOT NULL DEFAULT '',
+	file_name TEXT NOT NULL,
+	profile TEXT NOT NULL DEFAULT '',
+	video_id INTEGER NOT NULL DEFAULT 0,
+	owner_id INTEGER NOT NULL DEFAULT 0,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_uploads_expires_at ON uploads(expires_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_videos_search;
+DROP INDEX IF EXISTS idx_videos_duration;
+DROP INDEX IF EXISTS idx_videos_name;
+
+ALTER TABLE videos DROP COLUMN search;
+ALTER TABLE videos DROP COLUMN duration;
+ALTER TABLE videos DROP COLUMN description;
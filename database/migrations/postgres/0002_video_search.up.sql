@@ -0,0 +1,12 @@
+-- Описание и длительность видео, индексы для сортировки и полнотекстовый поиск.
+
+ALTER TABLE videos ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE videos ADD COLUMN duration DOUBLE PRECISION NOT NULL DEFAULT 0; -- В секундах, 0 - неизвестна
+
+CREATE INDEX idx_videos_name ON videos(video_name, id);
+CREATE INDEX idx_videos_duration ON videos(duration, id);
+
+-- Конфигурация simple не знает языка: названия бывают и на русском, и на английском
+ALTER TABLE videos ADD COLUMN search tsvector
+	GENERATED ALWAYS AS (to_tsvector('simple', video_name || ' ' || description)) STORED;
+CREATE INDEX idx_videos_search ON videos USING GIN (search);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS video_tags;
+DROP INDEX IF EXISTS idx_videos_visibility;
+
+ALTER TABLE videos DROP COLUMN visibility;
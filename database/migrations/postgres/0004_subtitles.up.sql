@@ -0,0 +1,12 @@
+-- Дорожки субтитров: загруженные пользователем и извлеченные из исходника.
+
+CREATE TABLE subtitles (
+	id SERIAL PRIMARY KEY,
+	video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+	language TEXT NOT NULL DEFAULT '',
+	name TEXT NOT NULL,
+	source TEXT NOT NULL,
+	is_default BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_subtitles_video_id ON subtitles(video_id);
@@ -0,0 +1,11 @@
+-- Очередь видео комнаты. Текущее видео хранится в rooms.video_id,
+-- в очереди - то, что будет показано после него, по возрастанию position.
+
+CREATE TABLE room_queue (
+	id SERIAL PRIMARY KEY,
+	room_id INTEGER NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+	video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	added_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_room_queue_room_id ON room_queue(room_id, position);
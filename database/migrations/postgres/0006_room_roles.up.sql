@@ -0,0 +1,8 @@
+-- Роли участников комнаты: хозяин, модератор и зритель.
+
+ALTER TABLE room_members ADD COLUMN role TEXT NOT NULL DEFAULT 'viewer'
+	CHECK (role IN ('host', 'moderator', 'viewer'));
+
+-- В уже открытых комнатах хозяином становится тот, кто вошел первым
+UPDATE room_members SET role = 'host'
+	WHERE id IN (SELECT MIN(id) FROM room_members GROUP BY room_id);
@@ -0,0 +1,13 @@
+-- Реакции зрителей в комнатах. Хранятся по видео и переживают комнату,
+-- чтобы при повторном просмотре было видно, где смеялись.
+
+CREATE TABLE reactions (
+	id SERIAL PRIMARY KEY,
+	video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+	room_id INTEGER NOT NULL,
+	member_id INTEGER NOT NULL,
+	emoji TEXT NOT NULL,
+	position DOUBLE PRECISION NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_reactions_video_id ON reactions(video_id, position);
@@ -0,0 +1,4 @@
+-- В PostgreSQL комнаты и участники удаляются по ON DELETE CASCADE из 0001.
+-- Миграция есть только затем, чтобы номера версий совпадали с SQLite.
+
+SELECT 1;
@@ -0,0 +1,5 @@
+-- Номер переключения видео комнаты. Растет при каждом переходе к следующему видео
+-- из очереди, чтобы отличать повторное окончание того же видео, если оно стоит
+-- в очереди несколько раз подряд.
+
+ALTER TABLE rooms ADD COLUMN video_seq INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_video_id_fkey;
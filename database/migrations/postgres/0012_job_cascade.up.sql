@@ -0,0 +1,8 @@
+-- Задачи транскодирования удаляются вместе со своим видео.
+-- Иначе задача удаленного видео осталась бы в очереди и закодировала бы его исходник.
+
+-- Задачи видео, которые уже удалены
+DELETE FROM jobs WHERE video_id NOT IN (SELECT id FROM videos);
+
+ALTER TABLE jobs ADD CONSTRAINT jobs_video_id_fkey
+	FOREIGN KEY (video_id) REFERENCES videos(id) ON DELETE CASCADE;
@@ -0,0 +1,63 @@
+-- Начальная схема: пользователи, видео, комнаты, очередь транскодирования и tus-загрузки.
+
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+	password_hash TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE videos (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	video_name TEXT NOT NULL,
+	file_name TEXT UNIQUE,
+	status TEXT NOT NULL DEFAULT 'uploaded',
+	error_message TEXT NOT NULL DEFAULT '',
+	created_at DATETIME,
+	updated_at DATETIME,
+	owner_id INTEGER REFERENCES users(id)
+);
+CREATE INDEX idx_videos_status ON videos(status);
+CREATE INDEX idx_videos_owner_id ON videos(owner_id);
+
+CREATE TABLE rooms (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	code TEXT NOT NULL UNIQUE,
+	video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE room_members (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	token TEXT NOT NULL UNIQUE,
+	joined_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_room_members_room_id ON room_members(room_id);
+
+CREATE TABLE jobs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	video_id INTEGER NOT NULL,
+	file_name TEXT NOT NULL,
+	profile TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'queued',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	error_message TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_jobs_status ON jobs(status, id);
+CREATE INDEX idx_jobs_video_id ON jobs(video_id);
+
+CREATE TABLE uploads (
+	id TEXT PRIMARY KEY,
+	length INTEGER NOT NULL,
+	metadata TEXT NOT NULL DEFAULT '',
+	file_name TEXT NOT NULL,
+	profile TEXT NOT NULL DEFAULT '',
+	video_id INTEGER NOT NULL DEFAULT 0,
+	owner_id INTEGER NOT NULL DEFAULT 0,
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_uploads_expires_at ON uploads(expires_at);
@@ -0,0 +1,8 @@
+-- Индекс videos_fts удаляет DB.Rollback: без FTS5 его не удалить, и откат упал бы
+-- с "no such module: fts5" (см. database/search.go).
+
+DROP INDEX IF EXISTS idx_videos_duration;
+DROP INDEX IF EXISTS idx_videos_name;
+
+ALTER TABLE videos DROP COLUMN duration;
+ALTER TABLE videos DROP COLUMN description;
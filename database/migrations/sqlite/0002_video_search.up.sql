@@ -0,0 +1,9 @@
+-- Описание и длительность видео, индексы для сортировки и поиска.
+-- Полнотекстовый индекс videos_fts создает DB.Migrate: FTS5 есть только в сборке
+-- с тегом sqlite_fts5, без него поиск идет через LIKE (см. database/search.go).
+
+ALTER TABLE videos ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE videos ADD COLUMN duration REAL NOT NULL DEFAULT 0; -- В секундах, 0 - неизвестна
+
+CREATE INDEX idx_videos_name ON videos(video_name, id);
+CREATE INDEX idx_videos_duration ON videos(duration, id);
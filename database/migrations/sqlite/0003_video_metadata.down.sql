@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS video_tags_delete;
+DROP TABLE IF EXISTS video_tags;
+DROP INDEX IF EXISTS idx_videos_visibility;
+
+ALTER TABLE videos DROP COLUMN visibility;
@@ -0,0 +1,17 @@
+-- Видимость и теги видео.
+
+ALTER TABLE videos ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+	CHECK (visibility IN ('public', 'unlisted', 'private'));
+CREATE INDEX idx_videos_visibility ON videos(visibility);
+
+CREATE TABLE video_tags (
+	video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (video_id, tag)
+);
+CREATE INDEX idx_video_tags_tag ON video_tags(tag);
+
+-- Внешние ключи в SQLite не включены, поэтому теги удаленного видео чистит триггер
+CREATE TRIGGER video_tags_delete AFTER DELETE ON videos BEGIN
+	DELETE FROM video_tags WHERE video_id = old.id;
+END;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS subtitles_delete;
+DROP TABLE IF EXISTS subtitles;
@@ -0,0 +1,16 @@
+-- Дорожки субтитров: загруженные пользователем и извлеченные из исходника.
+
+CREATE TABLE subtitles (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+	language TEXT NOT NULL DEFAULT '',
+	name TEXT NOT NULL,
+	source TEXT NOT NULL,
+	is_default BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_subtitles_video_id ON subtitles(video_id);
+
+CREATE TRIGGER subtitles_delete AFTER DELETE ON videos BEGIN
+	DELETE FROM subtitles WHERE video_id = old.id;
+END;
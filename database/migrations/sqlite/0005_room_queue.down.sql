@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS room_queue_delete_room;
+DROP TRIGGER IF EXISTS room_queue_delete_video;
+DROP TABLE IF EXISTS room_queue;
@@ -0,0 +1,18 @@
+-- Очередь видео комнаты. Текущее видео хранится в rooms.video_id,
+-- в очереди - то, что будет показано после него, по возрастанию position.
+
+CREATE TABLE room_queue (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+	video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	added_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_room_queue_room_id ON room_queue(room_id, position);
+
+CREATE TRIGGER room_queue_delete_video AFTER DELETE ON videos BEGIN
+	DELETE FROM room_queue WHERE video_id = old.id;
+END;
+CREATE TRIGGER room_queue_delete_room AFTER DELETE ON rooms BEGIN
+	DELETE FROM room_queue WHERE room_id = old.id;
+END;
@@ -0,0 +1 @@
+ALTER TABLE room_members DROP COLUMN role;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS room_messages_delete;
+DROP TABLE IF EXISTS room_messages;
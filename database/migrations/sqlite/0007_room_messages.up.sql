@@ -0,0 +1,18 @@
+-- Сообщения чата комнаты. Имя автора копируется: участник может уйти,
+-- а история остается. video_id и position - что и где смотрели в момент отправки.
+
+CREATE TABLE room_messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+	member_id INTEGER NOT NULL,
+	member_name TEXT NOT NULL,
+	video_id INTEGER NOT NULL,
+	position REAL NOT NULL DEFAULT 0,
+	text TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_room_messages_room_id ON room_messages(room_id, id);
+
+CREATE TRIGGER room_messages_delete AFTER DELETE ON rooms BEGIN
+	DELETE FROM room_messages WHERE room_id = old.id;
+END;
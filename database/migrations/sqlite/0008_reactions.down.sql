@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS reactions_delete;
+DROP TABLE IF EXISTS reactions;
@@ -0,0 +1,17 @@
+-- Реакции зрителей в комнатах. Хранятся по видео и переживают комнату,
+-- чтобы при повторном просмотре было видно, где смеялись.
+
+CREATE TABLE reactions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+	room_id INTEGER NOT NULL,
+	member_id INTEGER NOT NULL,
+	emoji TEXT NOT NULL,
+	position REAL NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_reactions_video_id ON reactions(video_id, position);
+
+CREATE TRIGGER reactions_delete AFTER DELETE ON videos BEGIN
+	DELETE FROM reactions WHERE video_id = old.id;
+END;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS room_members_delete_room;
+DROP TRIGGER IF EXISTS rooms_delete_video;
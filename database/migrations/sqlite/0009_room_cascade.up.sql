@@ -0,0 +1,14 @@
+-- Внешние ключи в SQLite выключены, поэтому ON DELETE CASCADE для комнат и участников
+-- повторяется триггерами, как в PostgreSQL: комната удаляется вместе со своим видео,
+-- участники - вместе с комнатой. Очередь и чат комнаты чистят триггеры из 0005 и 0007.
+
+CREATE TRIGGER rooms_delete_video AFTER DELETE ON videos BEGIN
+	DELETE FROM rooms WHERE video_id = old.id;
+END;
+CREATE TRIGGER room_members_delete_room AFTER DELETE ON rooms BEGIN
+	DELETE FROM room_members WHERE room_id = old.id;
+END;
+
+-- Комнаты, видео которых уже удалено
+DELETE FROM rooms WHERE video_id NOT IN (SELECT id FROM videos);
+DELETE FROM room_members WHERE room_id NOT IN (SELECT id FROM rooms);
@@ -0,0 +1,8 @@
+-- В комнате не больше одного хозяина. Индекс не дает двум участникам,
+-- одновременно вошедшим в пустую комнату, обоим стать хозяевами.
+
+-- Если так уже случилось, хозяином остается вошедший первым
+UPDATE room_members SET role = 'moderator'
+	WHERE role = 'host' AND id NOT IN (SELECT MIN(id) FROM room_members WHERE role = 'host' GROUP BY room_id);
+
+CREATE UNIQUE INDEX idx_room_members_host ON room_members(room_id) WHERE role = 'host';
@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS jobs_delete_video;
@@ -0,0 +1,9 @@
+-- Задачи транскодирования удаляются вместе со своим видео, как комнаты в 0009.
+-- Иначе задача удаленного видео осталась бы в очереди и закодировала бы его исходник.
+
+CREATE TRIGGER jobs_delete_video AFTER DELETE ON videos BEGIN
+	DELETE FROM jobs WHERE video_id = old.id;
+END;
+
+-- Задачи видео, которые уже удалены
+DELETE FROM jobs WHERE video_id NOT IN (SELECT id FROM videos);
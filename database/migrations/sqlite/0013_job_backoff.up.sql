@@ -0,0 +1,5 @@
+-- Время, раньше которого задачу не берут из очереди. Неудачную попытку повторяют
+-- не сразу, а с растущей паузой, чтобы временный сбой успел пройти.
+-- NULL - задачу можно брать сразу.
+
+ALTER TABLE jobs ADD COLUMN run_after DATETIME;
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrQueueEmpty - в очереди комнаты не осталось видео.
+var ErrQueueEmpty = errors.New("очередь комнаты пуста")
+
+// QueueStorage определяет контракт для работы с очередью видео комнаты.
+type QueueStorage interface {
+	AddToQueue(ctx context.Context, roomID, videoID int) (*QueueItem, error)
+	GetQueue(ctx context.Context, roomID int) ([]QueueItem, error)
+	DeleteQueueItem(ctx context.Context, roomID, itemID int) error
+	ReorderQueue(ctx context.Context, roomID int, itemIDs []int) error
+	AdvanceQueue(ctx context.Context, roomID, fromSeq int) (*Room, error)
+}
+
+// QueueItem представляет видео в очереди комнаты.
+type QueueItem struct {
+	ID       int       `json:"id"`       // Уникальный идентификатор
+	RoomID   int       `json:"room_id"`  // Комната, в очереди которой стоит видео
+	VideoID  int       `json:"video_id"` // Видео
+	Position int       `json:"position"` // Место в очереди, меньше - раньше
+	AddedAt  time.Time `json:"added_at"` // Время добавления
+}
+
+const queueColumns = `id, room_id, video_id, position, added_at`
+
+// AddToQueue ставит видео videoID в конец очереди комнаты roomID.
+func (db *DB) AddToQueue(ctx context.Context, roomID, videoID int) (*QueueItem, error) {
+	insertSQL := `INSERT INTO room_queue (room_id, video_id, position)
+		SELECT ?, ?, COALESCE(MAX(position), -1) + 1 FROM room_queue WHERE room_id = ?`
+	id, err := db.insertID(ctx, insertSQL, roomID, videoID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка добавления видео %d в очередь комнаты %d: %w", videoID, roomID, wrapError(err))
+	}
+
+	querySQL := `SELECT ` + queueColumns + ` FROM room_queue WHERE id = ?`
+	var item QueueItem
+	err = db.queryRow(ctx, querySQL, id).Scan(&item.ID, &item.RoomID, &item.VideoID, &item.Position, &item.AddedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения элемента очереди по ID %d: %w", id, wrapError(err))
+	}
+	return &item, nil
+}
+
+// GetQueue возвращает очередь комнаты в порядке показа.
+func (db *DB) GetQueue(ctx context.Context, roomID int) ([]QueueItem, error) {
+	querySQL := `SELECT ` + queueColumns + ` FROM room_queue WHERE room_id = ? ORDER BY position, id`
+	rows, err := db.query(ctx, querySQL, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer rows.Close()
+
+	queue := []QueueItem{}
+	for rows.Next() {
+		var item QueueItem
+		if err := rows.Scan(&item.ID, &item.RoomID, &item.VideoID, &item.Position, &item.AddedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %w", err)
+		}
+		queue = append(queue, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %w", err)
+	}
+	return queue, nil
+}
+
+// DeleteQueueItem убирает элемент itemID из очереди комнаты roomID.
+func (db *DB) DeleteQueueItem(ctx context.Context, roomID, itemID int) error {
+	result, err := db.exec(ctx, `DELETE FROM room_queue WHERE room_id = ? AND id = ?`, roomID, itemID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления элемента очереди: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("элемент очереди %d не найден в комнате %d: %w", itemID, roomID, ErrNotFound)
+	}
+	return nil
+}
+
+// ReorderQueue расставляет очередь комнаты в порядке itemIDs.
+// itemIDs должен содержать каждый элемент очереди ровно один раз,
+// иначе возвращается ErrStale: очередь успели изменить.
+func (db *DB) ReorderQueue(ctx context.Context, roomID int, itemIDs []int) error {
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, db.rebind(`SELECT id FROM room_queue WHERE room_id = ?`), roomID)
+		if err != nil {
+			return err
+		}
+		var current []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			current = append(current, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		requested := slices.Clone(itemIDs)
+		slices.Sort(current)
+		slices.Sort(requested)
+		if !slices.Equal(current, requested) {
+			return fmt.Errorf("%w: новый порядок не совпадает с элементами очереди", ErrStale)
+		}
+
+		for position, id := range itemIDs {
+			updateSQL := `UPDATE room_queue SET position = ? WHERE room_id = ? AND id = ?`
+			if _, err := tx.ExecContext(ctx, db.rebind(updateSQL), position, roomID, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка изменения порядка очереди комнаты %d: %w", roomID, err)
+	}
+	return nil
+}
+
+// AdvanceQueue переключает комнату на первое видео из очереди и убирает его из очереди.
+// fromSeq - Room.VideoSeq видео, которое комната показывает сейчас: если ее уже переключили,
+// возвращается ErrStale, чтобы одно окончание видео не пропустило два элемента.
+// Сравнивается именно номер переключения, а не ID видео: одно видео может стоять в очереди
+// несколько раз подряд. Если очередь пуста, возвращается ErrQueueEmpty.
+func (db *DB) AdvanceQueue(ctx context.Context, roomID, fromSeq int) (*Room, error) {
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		var itemID, videoID int
+		querySQL := `SELECT id, video_id FROM room_queue WHERE room_id = ? ORDER BY position, id LIMIT 1`
+		err := tx.QueryRowContext(ctx, db.rebind(querySQL), roomID).Scan(&itemID, &videoID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrQueueEmpty
+		}
+		if err != nil {
+			return err
+		}
+
+		updateSQL := `UPDATE rooms SET video_id = ?, video_seq = video_seq + 1 WHERE id = ? AND video_seq = ?`
+		result, err := tx.ExecContext(ctx, db.rebind(updateSQL), videoID, roomID, fromSeq)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: комнату уже переключили, video_seq больше не %d", ErrStale, fromSeq)
+		}
+
+		_, err = tx.ExecContext(ctx, db.rebind(`DELETE FROM room_queue WHERE id = ?`), itemID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка переключения комнаты %d на следующее видео: %w", roomID, wrapError(err))
+	}
+	return db.GetRoomByID(ctx, roomID)
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReactionStorage определяет контракт для работы с реакциями зрителей.
+type ReactionStorage interface {
+	InsertReaction(ctx context.Context, r Reaction) error
+	GetReactionTimeline(ctx context.Context, videoID, bucket int) ([]ReactionBucket, error)
+}
+
+// Reaction представляет реакцию участника комнаты на момент видео.
+type Reaction struct {
+	VideoID  int     // Видео, на которое отреагировали
+	RoomID   int     // Комната, в которой смотрели
+	MemberID int     // Автор реакции
+	Emoji    string  // Эмодзи
+	Position float64 // Позиция в видео, секунды
+}
+
+// ReactionBucket - реакции на отрезок видео [Start, End).
+type ReactionBucket struct {
+	Start     int            `json:"start"`     // Начало отрезка, секунды
+	End       int            `json:"end"`       // Конец отрезка, секунды
+	Total     int            `json:"total"`     // Всего реакций на отрезке
+	Reactions map[string]int `json:"reactions"` // Сколько раз поставили каждый эмодзи
+}
+
+// InsertReaction сохраняет реакцию r.
+func (db *DB) InsertReaction(ctx context.Context, r Reaction) error {
+	insertSQL := `INSERT INTO reactions (video_id, room_id, member_id, emoji, position) VALUES (?, ?, ?, ?, ?)`
+	if _, err := db.exec(ctx, insertSQL, r.VideoID, r.RoomID, r.MemberID, r.Emoji, r.Position); err != nil {
+		return fmt.Errorf("ошибка сохранения реакции на видео %d: %w", r.VideoID, wrapError(err))
+	}
+	return nil
+}
+
+// GetReactionTimeline считает реакции на видео videoID по отрезкам в bucket секунд.
+// Отрезки без реакций не возвращаются.
+func (db *DB) GetReactionTimeline(ctx context.Context, videoID, bucket int) ([]ReactionBucket, error) {
+	if bucket < 1 {
+		return nil, fmt.Errorf("длина отрезка должна быть положительной: %d", bucket)
+	}
+
+	// Позиция не бывает отрицательной, поэтому в SQLite отбрасывание дробной части и есть округление вниз,
+	// а PostgreSQL при CAST округляет к ближайшему
+	bucketExpr := `CAST(position / ? AS INTEGER)`
+	if db.dialect == Postgres {
+		bucketExpr = `CAST(FLOOR(position / ?) AS INTEGER)`
+	}
+	querySQL := `SELECT ` + bucketExpr + ` AS bucket, emoji, COUNT(*) FROM reactions
+		WHERE video_id = ? GROUP BY bucket, emoji ORDER BY bucket, emoji`
+	rows, err := db.query(ctx, querySQL, float64(bucket), videoID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer rows.Close()
+
+	timeline := []ReactionBucket{}
+	for rows.Next() {
+		var index, count int
+		var emoji string
+		if err := rows.Scan(&index, &emoji, &count); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %w", err)
+		}
+		if n := len(timeline); n == 0 || timeline[n-1].Start != index*bucket {
+			timeline = append(timeline, ReactionBucket{
+				Start:     index * bucket,
+				End:       (index + 1) * bucket,
+				Reactions: make(map[string]int),
+			})
+		}
+		last := &timeline[len(timeline)-1]
+		last.Reactions[emoji] = count
+		last.Total += count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %w", err)
+	}
+	return timeline, nil
+}
@@ -0,0 +1,262 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RoomStorage определяет контракт для работы с хранилищем комнат и их участников.
+type RoomStorage interface {
+	InsertRoom(ctx context.Context, code string, videoID int) (*Room, error)
+	GetRoomByID(ctx context.Context, id int) (*Room, error)
+	GetRoomByCode(ctx context.Context, code string) (*Room, error)
+	DeleteRoomByID(ctx context.Context, id int) error
+	InsertMember(ctx context.Context, roomID int, name, token string) (*Member, error)
+	GetMembers(ctx context.Context, roomID int) ([]Member, error)
+	GetMemberByToken(ctx context.Context, token string) (*Member, error)
+	DeleteMember(ctx context.Context, roomID, memberID int) error
+	SetMemberRole(ctx context.Context, roomID, memberID int, role string) error
+	TransferHost(ctx context.Context, roomID, fromID, toID int) error
+}
+
+// Роли участников комнаты, от старшей к младшей.
+const (
+	RoleHost      = "host"      // Хозяин: может все, в комнате он один
+	RoleModerator = "moderator" // Помощник хозяина, права задает политика комнаты
+	RoleViewer    = "viewer"    // Обычный зритель
+)
+
+// RoleRank возвращает старшинство роли: чем больше, тем старше. Для неизвестной роли - 0.
+func RoleRank(role string) int {
+	switch role {
+	case RoleHost:
+		return 3
+	case RoleModerator:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Room представляет комнату совместного просмотра.
+type Room struct {
+	ID        int       `json:"id"`         // Уникальный идентификатор
+	Code      string    `json:"code"`       // Короткий код приглашения
+	VideoID   int       `json:"video_id"`   // Видео, которое смотрят в комнате
+	VideoSeq  int       `json:"video_seq"`  // Сколько раз комната переключалась на следующее видео
+	CreatedAt time.Time `json:"created_at"` // Время создания
+}
+
+// Member представляет участника комнаты.
+type Member struct {
+	ID       int       `json:"id"`        // Уникальный идентификатор
+	RoomID   int       `json:"room_id"`   // Комната, в которой находится участник
+	Name     string    `json:"name"`      // Отображаемое имя
+	Token    string    `json:"-"`         // Секрет участника, выдаётся только при входе
+	Role     string    `json:"role"`      // RoleHost, RoleModerator или RoleViewer
+	JoinedAt time.Time `json:"joined_at"` // Время входа в комнату
+}
+
+// InsertRoom создает комнату с кодом приглашения code для видео videoID.
+func (db *DB) InsertRoom(ctx context.Context, code string, videoID int) (*Room, error) {
+	insertSQL := `INSERT INTO rooms (code, video_id) VALUES (?, ?)`
+	id, err := db.insertID(ctx, insertSQL, code, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания комнаты (code: '%s', video_id: %d): %w", code, videoID, wrapError(err))
+	}
+	return db.GetRoomByID(ctx, id)
+}
+
+// GetRoomByID получает комнату по ее ID.
+func (db *DB) GetRoomByID(ctx context.Context, id int) (*Room, error) {
+	querySQL := `SELECT id, code, video_id, video_seq, created_at FROM rooms WHERE id = ?`
+	row := db.queryRow(ctx, querySQL, id)
+
+	var room Room
+	err := row.Scan(&room.ID, &room.Code, &room.VideoID, &room.VideoSeq, &room.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения комнаты по ID %d: %w", id, wrapError(err))
+	}
+	return &room, nil
+}
+
+// GetRoomByCode получает комнату по коду приглашения.
+func (db *DB) GetRoomByCode(ctx context.Context, code string) (*Room, error) {
+	querySQL := `SELECT id, code, video_id, video_seq, created_at FROM rooms WHERE code = ?`
+	row := db.queryRow(ctx, querySQL, code)
+
+	var room Room
+	err := row.Scan(&room.ID, &room.Code, &room.VideoID, &room.VideoSeq, &room.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения комнаты по коду '%s': %w", code, wrapError(err))
+	}
+	return &room, nil
+}
+
+// DeleteRoomByID удаляет комнату вместе со всеми ее участниками.
+func (db *DB) DeleteRoomByID(ctx context.Context, id int) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, db.rebind(`DELETE FROM room_members WHERE room_id = ?`), id); err != nil {
+		return fmt.Errorf("ошибка удаления участников комнаты %d: %w", id, err)
+	}
+	result, err := tx.ExecContext(ctx, db.rebind(`DELETE FROM rooms WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления комнаты %d: %w", id, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("комната с ID %d не найдена для удаления: %w", id, ErrNotFound)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	fmt.Printf("Комната с ID %d удалена.\n", id)
+	return nil
+}
+
+// InsertMember добавляет участника с именем name в комнату roomID.
+// Первый вошедший в комнату без хозяина становится хозяином, остальные - зрителями.
+func (db *DB) InsertMember(ctx context.Context, roomID int, name, token string) (*Member, error) {
+	insertSQL := `INSERT INTO room_members (room_id, name, token, role)
+		SELECT ?, ?, ?, CASE WHEN EXISTS (
+			SELECT 1 FROM room_members WHERE room_id = ? AND role = 'host'
+		) THEN 'viewer' ELSE 'host' END`
+	id, err := db.insertID(ctx, insertSQL, roomID, name, token, roomID)
+	if errors.Is(wrapError(err), ErrConflict) {
+		// Хозяином одновременно стал другой участник, и второго хозяина не пустил
+		// индекс idx_room_members_host. Повторная ошибка - это уже занятый токен
+		insertSQL := `INSERT INTO room_members (room_id, name, token, role) VALUES (?, ?, ?, 'viewer')`
+		id, err = db.insertID(ctx, insertSQL, roomID, name, token)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка добавления участника '%s' в комнату %d: %w", name, roomID, wrapError(err))
+	}
+
+	querySQL := `SELECT id, room_id, name, token, role, joined_at FROM room_members WHERE id = ?`
+	var m Member
+	err = db.queryRow(ctx, querySQL, id).Scan(&m.ID, &m.RoomID, &m.Name, &m.Token, &m.Role, &m.JoinedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения участника по ID %d: %w", id, wrapError(err))
+	}
+	return &m, nil
+}
+
+// GetMembers получает всех участников комнаты в порядке входа.
+func (db *DB) GetMembers(ctx context.Context, roomID int) ([]Member, error) {
+	querySQL := `SELECT id, room_id, name, token, role, joined_at FROM room_members WHERE room_id = ? ORDER BY id`
+	rows, err := db.query(ctx, querySQL, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ID, &m.RoomID, &m.Name, &m.Token, &m.Role, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %w", err)
+		}
+		members = append(members, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %w", err)
+	}
+	return members, nil
+}
+
+// GetMemberByToken получает участника по его секретному токену.
+func (db *DB) GetMemberByToken(ctx context.Context, token string) (*Member, error) {
+	querySQL := `SELECT id, room_id, name, token, role, joined_at FROM room_members WHERE token = ?`
+	var m Member
+	err := db.queryRow(ctx, querySQL, token).Scan(&m.ID, &m.RoomID, &m.Name, &m.Token, &m.Role, &m.JoinedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения участника по токену: %w", wrapError(err))
+	}
+	return &m, nil
+}
+
+// DeleteMember удаляет участника memberID из комнаты roomID.
+func (db *DB) DeleteMember(ctx context.Context, roomID, memberID int) error {
+	deleteSQL := `DELETE FROM room_members WHERE room_id = ? AND id = ?`
+	result, err := db.exec(ctx, deleteSQL, roomID, memberID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления участника: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("участник с ID %d не найден в комнате %d: %w", memberID, roomID, ErrNotFound)
+	}
+
+	fmt.Printf("Участник с ID %d покинул комнату %d.\n", memberID, roomID)
+	return nil
+}
+
+// SetMemberRole меняет роль участника memberID комнаты roomID.
+// Хозяина так не назначить и не разжаловать: для этого есть TransferHost.
+func (db *DB) SetMemberRole(ctx context.Context, roomID, memberID int, role string) error {
+	updateSQL := `UPDATE room_members SET role = ? WHERE room_id = ? AND id = ? AND role <> 'host'`
+	result, err := db.exec(ctx, updateSQL, role, roomID, memberID)
+	if err != nil {
+		return fmt.Errorf("ошибка изменения роли участника %d: %w", memberID, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("участник с ID %d не найден в комнате %d или он хозяин: %w", memberID, roomID, ErrNotFound)
+	}
+	return nil
+}
+
+// TransferHost передает роль хозяина комнаты roomID от участника fromID участнику toID.
+// Прежний хозяин становится модератором. Если fromID уже не хозяин,
+// возвращается ErrStale: роль успели передать.
+func (db *DB) TransferHost(ctx context.Context, roomID, fromID, toID int) error {
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		demoteSQL := `UPDATE room_members SET role = 'moderator' WHERE room_id = ? AND id = ? AND role = 'host'`
+		result, err := tx.ExecContext(ctx, db.rebind(demoteSQL), roomID, fromID)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if rowsAffected == 0 {
+			return fmt.Errorf("%w: участник %d уже не хозяин", ErrStale, fromID)
+		}
+
+		promoteSQL := `UPDATE room_members SET role = 'host' WHERE room_id = ? AND id = ?`
+		result, err = tx.ExecContext(ctx, db.rebind(promoteSQL), roomID, toID)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if rowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка передачи роли хозяина в комнате %d участнику %d: %w", roomID, toID, err)
+	}
+	return nil
+}
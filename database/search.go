@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Полнотекстовый поиск по видео в SQLite идет через FTS5, но FTS5 есть только в сборке
+// с тегом sqlite_fts5. Поэтому индекс videos_fts не входит в миграции: его создает
+// Migrate, если SQLite его поддерживает, и удаляет Rollback, а без FTS5 поиск работает через LIKE.
+
+// searchMigration - миграция, добавившая описание видео, по которому тоже идет поиск.
+const searchMigration = 2
+
+// ftsTriggers - триггеры, которые держат videos_fts в актуальном состоянии.
+var ftsTriggers = []string{"videos_fts_insert", "videos_fts_delete", "videos_fts_update"}
+
+// ftsSchemaSQL создает индекс videos_fts и его триггеры. Индекс хранит только токены,
+// сами тексты берутся из videos.
+const ftsSchemaSQL = `
+CREATE VIRTUAL TABLE IF NOT EXISTS videos_fts USING fts5(
+	video_name,
+	description,
+	content = 'videos',
+	content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS videos_fts_insert AFTER INSERT ON videos BEGIN
+	INSERT INTO videos_fts (rowid, video_name, description) VALUES (new.id, new.video_name, new.description);
+END;
+CREATE TRIGGER IF NOT EXISTS videos_fts_delete AFTER DELETE ON videos BEGIN
+	INSERT INTO videos_fts (videos_fts, rowid, video_name, description) VALUES ('delete', old.id, old.video_name, old.description);
+END;
+CREATE TRIGGER IF NOT EXISTS videos_fts_update AFTER UPDATE OF video_name, description ON videos BEGIN
+	INSERT INTO videos_fts (videos_fts, rowid, video_name, description) VALUES ('delete', old.id, old.video_name, old.description);
+	INSERT INTO videos_fts (rowid, video_name, description) VALUES (new.id, new.video_name, new.description);
+END;
+
+INSERT INTO videos_fts (videos_fts) VALUES ('rebuild');`
+
+// hasFTS5 сообщает, собрана ли SQLite с FTS5.
+func hasFTS5(ctx context.Context, conn *sql.DB) bool {
+	var used bool
+	err := conn.QueryRowContext(ctx, `SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&used)
+	return err == nil && used
+}
+
+// detectSearch определяет, можно ли искать видео по индексу videos_fts.
+func (db *DB) detectSearch(ctx context.Context) error {
+	db.fts = false
+	if db.dialect != SQLite || !hasFTS5(ctx, db.conn) {
+		return nil
+	}
+	exists, err := db.tableExists(ctx, "videos_fts")
+	if err != nil || !exists {
+		return err
+	}
+	triggers, err := db.countFTSTriggers(ctx)
+	if err != nil {
+		return err
+	}
+	db.fts = triggers == len(ftsTriggers)
+	return nil
+}
+
+// syncSearchIndex создает и перестраивает индекс videos_fts, если SQLite собрана с FTS5,
+// а индекса нет или его триггеры удалены.
+func (db *DB) syncSearchIndex(ctx context.Context) error {
+	if db.dialect != SQLite || !hasFTS5(ctx, db.conn) {
+		return nil
+	}
+	version, err := db.Version(ctx)
+	if err != nil || version < searchMigration {
+		return err
+	}
+	triggers, err := db.countFTSTriggers(ctx)
+	if err != nil {
+		return err
+	}
+
+	exists, err := db.tableExists(ctx, "videos_fts")
+	if err != nil {
+		return err
+	}
+	if !exists || triggers < len(ftsTriggers) {
+		err := db.inTx(ctx, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, ftsSchemaSQL)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка создания полнотекстового индекса: %w", err)
+		}
+		fmt.Println("Полнотекстовый индекс видео построен.")
+	}
+	db.fts = true
+	return nil
+}
+
+// dropStaleFTSTriggers удаляет триггеры индекса, созданного сборкой с FTS5, если текущая
+// сборка собрана без FTS5: иначе любая запись в videos падала бы с "no such module: fts5".
+// Вызывается при открытии базы, чтобы запись работала и в командах, которые не вызывают Migrate.
+// Индекс при этом устаревает и будет перестроен, когда сервис снова запустят со сборкой с FTS5.
+func (db *DB) dropStaleFTSTriggers(ctx context.Context) error {
+	if db.dialect != SQLite || hasFTS5(ctx, db.conn) {
+		return nil
+	}
+	triggers, err := db.countFTSTriggers(ctx)
+	if err != nil || triggers == 0 {
+		return err
+	}
+	for _, name := range ftsTriggers {
+		if _, err := db.conn.ExecContext(ctx, `DROP TRIGGER IF EXISTS `+name); err != nil {
+			return fmt.Errorf("ошибка удаления триггера '%s': %w", name, err)
+		}
+	}
+	fmt.Println("Триггеры полнотекстового индекса удалены: SQLite собрана без FTS5.")
+	return nil
+}
+
+// dropSearchIndex удаляет индекс videos_fts при откате миграции searchMigration.
+// Без FTS5 виртуальную таблицу удалить нельзя, поэтому она остается в базе без триггеров:
+// ее никто не читает, а сборка с FTS5 после повторной миграции перестроит ее.
+func (db *DB) dropSearchIndex(ctx context.Context, tx *sql.Tx, fts5 bool) error {
+	for _, name := range ftsTriggers {
+		if _, err := tx.ExecContext(ctx, `DROP TRIGGER IF EXISTS `+name); err != nil {
+			return fmt.Errorf("ошибка удаления триггера '%s': %w", name, err)
+		}
+	}
+	if !fts5 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS videos_fts`); err != nil {
+		return fmt.Errorf("ошибка удаления полнотекстового индекса: %w", err)
+	}
+	return nil
+}
+
+// countFTSTriggers возвращает, сколько триггеров индекса videos_fts есть в базе.
+func (db *DB) countFTSTriggers(ctx context.Context) (int, error) {
+	querySQL := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (?, ?, ?)`
+	var count int
+	err := db.queryRow(ctx, querySQL, ftsTriggers[0], ftsTriggers[1], ftsTriggers[2]).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка проверки триггеров полнотекстового индекса: %w", err)
+	}
+	return count, nil
+}
+
+// searchCondition строит условие поиска по названию и описанию.
+// Каждое слово запроса ищется как префикс, все слова должны встретиться.
+// Без FTS5 слово ищется как подстрока, а регистр не учитывается только у латиницы.
+func (db *DB) searchCondition(search string) (string, []interface{}, bool) {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "", nil, false
+	}
+
+	terms := make([]string, len(words))
+	switch {
+	case db.dialect == Postgres:
+		for i, w := range words {
+			terms[i] = strings.ToLower(w) + `:*`
+		}
+		return `search @@ to_tsquery('simple', ?)`, []interface{}{strings.Join(terms, " & ")}, true
+	case db.fts:
+		for i, w := range words {
+			terms[i] = `"` + w + `"*`
+		}
+		return `id IN (SELECT rowid FROM videos_fts WHERE videos_fts MATCH ?)`, []interface{}{strings.Join(terms, " ")}, true
+	}
+
+	// В словах только буквы и цифры, поэтому экранировать % и _ не нужно
+	args := make([]interface{}, 0, 2*len(words))
+	for i, w := range words {
+		terms[i] = `(video_name LIKE ? OR description LIKE ?)`
+		args = append(args, "%"+w+"%", "%"+w+"%")
+	}
+	return strings.Join(terms, ` AND `), args, true
+}
@@ -1,7 +1,8 @@
-// Package db предоставляет функции для работы с базой данных SQLite.
+// Package db предоставляет функции для работы с базой данных SQLite или PostgreSQL.
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -11,7 +12,9 @@ import (
 
 // DB - структура, инкапсулирующая соединение с базой данных.
 type DB struct {
-	conn *sql.DB
+	conn    *sql.DB
+	dialect string // SQLite или Postgres
+	fts     bool   // Поиск видео идет по индексу FTS5, иначе через LIKE (только SQLite)
 }
 
 // New создает новое подключение к базе данных SQLite.
@@ -32,13 +35,19 @@ func New(filepath string) (*DB, error) {
 	}
 
 	fmt.Println("Успешное подключение к SQLite!")
-	return &DB{conn: dbConn}, nil
-}
-func (db *DB) CreateTable() error {
-	if err := db.CreateVideosTable(); err != nil {
-		return fmt.Errorf("ошибка videos: %w", err)
+	db := &DB{conn: dbConn, dialect: SQLite}
+	if err := db.dropStaleFTSTriggers(context.Background()); err != nil {
+		dbConn.Close()
+		return nil, err
 	}
-	return nil
+	if err := db.detectSearch(context.Background()); err != nil {
+		dbConn.Close()
+		return nil, err
+	}
+	if !hasFTS5(context.Background(), dbConn) {
+		fmt.Println("SQLite собрана без FTS5: поиск видео работает через LIKE. Для полнотекстового поиска соберите сервис с -tags sqlite_fts5.")
+	}
+	return db, nil
 }
 
 // Close закрывает соединение с базой данных.
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Откуда взялась дорожка субтитров.
+const (
+	SubtitleUploaded = "upload"   // Загружена пользователем файлом SRT, ASS или WebVTT
+	SubtitleEmbedded = "embedded" // Извлечена из исходника при обработке
+)
+
+// SubtitleStorage определяет контракт для работы с хранилищем дорожек субтитров.
+type SubtitleStorage interface {
+	InsertSubtitle(ctx context.Context, videoID int, language, name, source string, isDefault bool) (*Subtitle, error)
+	GetSubtitleByID(ctx context.Context, id int) (*Subtitle, error)
+	GetSubtitles(ctx context.Context, videoID int) ([]Subtitle, error)
+	DeleteSubtitle(ctx context.Context, id int) error
+	DeleteSubtitlesBySource(ctx context.Context, videoID int, source string) error
+}
+
+// Subtitle представляет дорожку субтитров видео.
+// Сами субтитры лежат в папке HLS видео, имя плейлиста дает utils.SubtitleBaseName(ID).
+type Subtitle struct {
+	ID        int       `json:"id"`         // Уникальный идентификатор
+	VideoID   int       `json:"video_id"`   // Видео, к которому относятся субтитры
+	Language  string    `json:"language"`   // Код языка, например "en", пустой - не указан
+	Name      string    `json:"name"`       // Название в меню плеера
+	Source    string    `json:"source"`     // SubtitleUploaded или SubtitleEmbedded
+	IsDefault bool      `json:"is_default"` // Включаются по умолчанию
+	CreatedAt time.Time `json:"created_at"` // Время добавления
+}
+
+const subtitleColumns = `id, video_id, language, name, source, is_default, created_at`
+
+// InsertSubtitle добавляет дорожку субтитров к видео videoID.
+func (db *DB) InsertSubtitle(ctx context.Context, videoID int, language, name, source string, isDefault bool) (*Subtitle, error) {
+	insertSQL := `INSERT INTO subtitles (video_id, language, name, source, is_default) VALUES (?, ?, ?, ?, ?)`
+	id, err := db.insertID(ctx, insertSQL, videoID, language, name, source, isDefault)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка добавления субтитров к видео %d: %w", videoID, wrapError(err))
+	}
+	return db.GetSubtitleByID(ctx, id)
+}
+
+// GetSubtitleByID получает дорожку субтитров по ее ID.
+func (db *DB) GetSubtitleByID(ctx context.Context, id int) (*Subtitle, error) {
+	querySQL := `SELECT ` + subtitleColumns + ` FROM subtitles WHERE id = ?`
+	var s Subtitle
+	err := db.queryRow(ctx, querySQL, id).Scan(&s.ID, &s.VideoID, &s.Language, &s.Name, &s.Source, &s.IsDefault, &s.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения субтитров по ID %d: %w", id, wrapError(err))
+	}
+	return &s, nil
+}
+
+// GetSubtitles возвращает дорожки субтитров видео в порядке добавления.
+func (db *DB) GetSubtitles(ctx context.Context, videoID int) ([]Subtitle, error) {
+	querySQL := `SELECT ` + subtitleColumns + ` FROM subtitles WHERE video_id = ? ORDER BY id`
+	rows, err := db.query(ctx, querySQL, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer rows.Close()
+
+	subtitles := []Subtitle{}
+	for rows.Next() {
+		var s Subtitle
+		if err := rows.Scan(&s.ID, &s.VideoID, &s.Language, &s.Name, &s.Source, &s.IsDefault, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %w", err)
+		}
+		subtitles = append(subtitles, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %w", err)
+	}
+	return subtitles, nil
+}
+
+// DeleteSubtitle удаляет дорожку субтитров.
+func (db *DB) DeleteSubtitle(ctx context.Context, id int) error {
+	result, err := db.exec(ctx, `DELETE FROM subtitles WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления субтитров: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("субтитры с ID %d не найдены для удаления: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteSubtitlesBySource удаляет дорожки видео с источником source.
+// Перед повторной обработкой так убираются извлеченные в прошлый раз субтитры.
+func (db *DB) DeleteSubtitlesBySource(ctx context.Context, videoID int, source string) error {
+	_, err := db.exec(ctx, `DELETE FROM subtitles WHERE video_id = ? AND source = ?`, videoID, source)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления субтитров видео %d: %w", videoID, err)
+	}
+	return nil
+}
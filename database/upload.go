@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// UploadStorage определяет контракт для работы с возобновляемыми загрузками (tus).
+type UploadStorage interface {
+	InsertUpload(ctx context.Context, u *Upload) error
+	GetUploadByID(ctx context.Context, id string) (*Upload, error)
+	UpdateUploadExpiration(ctx context.Context, id string, expiresAt time.Time) error
+	CompleteUpload(ctx context.Context, id string, videoID int) error
+	GetExpiredUploads(ctx context.Context, now time.Time) ([]Upload, error)
+	DeleteUpload(ctx context.Context, id string) error
+}
+
+// Upload представляет возобновляемую загрузку файла по протоколу tus.
+// Текущее смещение не хранится: это размер частично загруженного файла на диске.
+type Upload struct {
+	ID        string    // Идентификатор загрузки, он же имя файла без расширения
+	Length    int64     // Полный размер файла в байтах
+	Metadata  string    // Заголовок Upload-Metadata как его прислал клиент
+	FileName  string    // Исходное имя файла из метаданных
+	Profile   string    // Профиль кодирования из метаданных
+	VideoID   int       // ID созданного видео, 0 - загрузка еще не завершена
+	OwnerID   int       // ID пользователя, начавшего загрузку
+	ExpiresAt time.Time // После этого момента незавершенная загрузка удаляется
+	CreatedAt time.Time // Время создания загрузки
+}
+
+const uploadColumns = `id, length, metadata, file_name, profile, video_id, owner_id, expires_at, created_at`
+
+// InsertUpload сохраняет новую загрузку.
+func (db *DB) InsertUpload(ctx context.Context, u *Upload) error {
+	insertSQL := `INSERT INTO uploads (id, length, metadata, file_name, profile, owner_id, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.exec(ctx, insertSQL, u.ID, u.Length, u.Metadata, u.FileName, u.Profile, u.OwnerID, u.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("ошибка создания загрузки '%s': %w", u.ID, wrapError(err))
+	}
+	return nil
+}
+
+// GetUploadByID получает загрузку по ее идентификатору.
+func (db *DB) GetUploadByID(ctx context.Context, id string) (*Upload, error) {
+	querySQL := `SELECT ` + uploadColumns + ` FROM uploads WHERE id = ?`
+	u, err := scanUpload(db.queryRow(ctx, querySQL, id))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения загрузки '%s': %w", id, wrapError(err))
+	}
+	return u, nil
+}
+
+// UpdateUploadExpiration продлевает срок жизни загрузки.
+func (db *DB) UpdateUploadExpiration(ctx context.Context, id string, expiresAt time.Time) error {
+	updateSQL := `UPDATE uploads SET expires_at = ? WHERE id = ?`
+	if _, err := db.exec(ctx, updateSQL, expiresAt.UTC(), id); err != nil {
+		return fmt.Errorf("ошибка продления загрузки '%s': %w", id, wrapError(err))
+	}
+	return nil
+}
+
+// CompleteUpload отмечает загрузку завершенной и связывает ее с созданным видео.
+func (db *DB) CompleteUpload(ctx context.Context, id string, videoID int) error {
+	updateSQL := `UPDATE uploads SET video_id = ? WHERE id = ?`
+	if _, err := db.exec(ctx, updateSQL, videoID, id); err != nil {
+		return fmt.Errorf("ошибка завершения загрузки '%s': %w", id, wrapError(err))
+	}
+	return nil
+}
+
+// GetExpiredUploads получает загрузки, срок жизни которых истек к моменту now.
+func (db *DB) GetExpiredUploads(ctx context.Context, now time.Time) ([]Upload, error) {
+	querySQL := `SELECT ` + uploadColumns + ` FROM uploads WHERE expires_at < ?`
+	rows, err := db.query(ctx, querySQL, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer rows.Close()
+
+	var uploads []Upload
+	for rows.Next() {
+		u, err := scanUpload(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %w", err)
+		}
+		uploads = append(uploads, *u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %w", err)
+	}
+	return uploads, nil
+}
+
+// DeleteUpload удаляет запись о загрузке.
+func (db *DB) DeleteUpload(ctx context.Context, id string) error {
+	deleteSQL := `DELETE FROM uploads WHERE id = ?`
+	result, err := db.exec(ctx, deleteSQL, id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления загрузки '%s': %w", id, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("загрузка '%s' не найдена для удаления: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// scanUpload читает загрузку из строки результата запроса.
+func scanUpload(row rowScanner) (*Upload, error) {
+	var u Upload
+	err := row.Scan(&u.ID, &u.Length, &u.Metadata, &u.FileName, &u.Profile, &u.VideoID, &u.OwnerID, &u.ExpiresAt, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
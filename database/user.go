@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// UserStorage определяет контракт для работы с учетными записями.
+type UserStorage interface {
+	InsertUser(ctx context.Context, username, passwordHash string) (*User, error)
+	GetUserByID(ctx context.Context, id int) (*User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+}
+
+// User представляет учетную запись пользователя.
+type User struct {
+	ID           int       `json:"id"`         // Уникальный идентификатор
+	Username     string    `json:"username"`   // Имя для входа, уникально без учета регистра
+	PasswordHash string    `json:"-"`          // Хэш bcrypt, наружу не отдается
+	CreatedAt    time.Time `json:"created_at"` // Время регистрации
+}
+
+const userColumns = `id, username, password_hash, created_at`
+
+// InsertUser создает пользователя с уже посчитанным хэшем пароля.
+func (db *DB) InsertUser(ctx context.Context, username, passwordHash string) (*User, error) {
+	insertSQL := `INSERT INTO users (username, password_hash) VALUES (?, ?)`
+	id, err := db.insertID(ctx, insertSQL, username, passwordHash)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания пользователя '%s': %w", username, wrapError(err))
+	}
+	return db.GetUserByID(ctx, id)
+}
+
+// GetUserByID получает пользователя по его ID.
+func (db *DB) GetUserByID(ctx context.Context, id int) (*User, error) {
+	querySQL := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+	u, err := scanUser(db.queryRow(ctx, querySQL, id))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователя по ID %d: %w", id, wrapError(err))
+	}
+	return u, nil
+}
+
+// GetUserByUsername получает пользователя по имени без учета регистра.
+func (db *DB) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	querySQL := `SELECT ` + userColumns + ` FROM users WHERE lower(username) = lower(?)`
+	u, err := scanUser(db.queryRow(ctx, querySQL, username))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователя '%s': %w", username, wrapError(err))
+	}
+	return u, nil
+}
+
+// scanUser читает пользователя из строки результата запроса.
+func scanUser(row rowScanner) (*User, error) {
+	var u User
+	if err := row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
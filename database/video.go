@@ -1,112 +1,323 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
+)
+
+// Статусы обработки видео.
+const (
+	VideoUploaded   = "uploaded"   // Файл загружен и ждет очереди на обработку
+	VideoProcessing = "processing" // Идет генерация HLS
+	VideoReady      = "ready"      // HLS готов, видео можно смотреть
+	VideoFailed     = "failed"     // Генерация HLS завершилась ошибкой
+)
+
+// Видимость видео.
+const (
+	VisibilityPublic   = "public"   // Видно в списке и доступно всем
+	VisibilityUnlisted = "unlisted" // Не видно в списке, но доступно по ID
+	VisibilityPrivate  = "private"  // Доступно только владельцу
 )
 
 // VideoStorage определяет контракт для работы с хранилищем видео.
+// Отсутствующее видео дает ErrNotFound, занятый file_name - ErrConflict.
 type VideoStorage interface {
-	InsertVideo(videoName, fileName string) error
-	GetAllVideos() ([]Video, error)
-	GetVideoByID(id int) (*Video, error)
-	GetVideoByFileName(fileName string) (*Video, error)
-	UpdateVideo(id int, newVideoName, newFileName string) error
-	DeleteVideoByID(id int) error
-	DeleteVideoByFileName(fileName string) error
+	InsertVideo(ctx context.Context, videoName, fileName string, ownerID int) (int, error)
+	ListVideos(ctx context.Context, q VideoQuery) (*VideoPage, error)
+	GetVideoByID(ctx context.Context, id int) (*Video, error)
+	GetVideoByFileName(ctx context.Context, fileName string) (*Video, error)
+	UpdateVideo(ctx context.Context, id int, newVideoName, newFileName string) error
+	UpdateVideoMetadata(ctx context.Context, id int, meta VideoMetadata) error
+	UpdateVideoStatus(ctx context.Context, id int, status, errorMessage string) error
+	UpdateVideoDuration(ctx context.Context, id int, duration float64) error
+	DeleteVideoByID(ctx context.Context, id int) error
+	DeleteVideoByFileName(ctx context.Context, fileName string) error
 }
 
 // Video представляет структуру данных видео.
 type Video struct {
-	ID        int    // Уникальный идентификатор
-	VideoName string // Имя видео, заданное пользователем
-	FileName  string // Имя файла в системе
+	ID           int       `json:"id"`            // Уникальный идентификатор
+	VideoName    string    `json:"video_name"`    // Имя видео, заданное пользователем
+	FileName     string    `json:"file_name"`     // Имя файла в системе, по нему видео удаляют и скачивают
+	Status       string    `json:"status"`        // uploaded, processing, ready или failed
+	ErrorMessage string    `json:"error_message"` // Текст ошибки для статуса failed
+	CreatedAt    time.Time `json:"created_at"`    // Время загрузки
+	UpdatedAt    time.Time `json:"updated_at"`    // Время последнего изменения
+	OwnerID      int       `json:"owner_id"`      // ID загрузившего пользователя, 0 - видео загружено до появления учетных записей
+	Description  string    `json:"description"`   // Описание, участвует в поиске вместе с названием
+	Duration     float64   `json:"duration"`      // Длительность в секундах, 0 - еще неизвестна
+	Visibility   string    `json:"visibility"`    // VisibilityPublic, VisibilityUnlisted или VisibilityPrivate
+	Tags         []string  `json:"tags"`          // Теги по алфавиту, заполняются ListVideos и GetVideoByID
+}
 
+// VideoMetadata - редактируемые пользователем данные видео.
+type VideoMetadata struct {
+	VideoName   string
+	Description string
+	Tags        []string
+	Visibility  string
 }
 
-// CreateVideosTable создает таблицу 'videos', если она еще не существует.
-func (db *DB) CreateVideosTable() error {
-	createTablesSQL := `
-	CREATE TABLE IF NOT EXISTS videos (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		video_name TEXT NOT NULL,
-		file_name TEXT UNIQUE
-	);`
-	_, err := db.conn.Exec(createTablesSQL)
-	if err != nil {
-		return fmt.Errorf("ошибка создания таблицы 'videos': %w", err)
+const videoColumns = `id, video_name, file_name, status, error_message, created_at, updated_at, owner_id, description, duration, visibility`
+
+// IsVisibility сообщает, является ли visibility известной видимостью видео.
+func IsVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPublic, VisibilityUnlisted, VisibilityPrivate:
+		return true
 	}
-	fmt.Println("Таблица 'videos' готова.")
-	return nil
+	return false
 }
 
-// InsertVideo добавляет новую запись видео в таблицу 'videos'.
-func (db *DB) InsertVideo(videoName, fileName string) error {
-	insertSQL := `INSERT INTO videos (video_name, file_name) VALUES (?, ?)`
-	_, err := db.conn.Exec(insertSQL, videoName, fileName)
+// CanView сообщает, может ли пользователь userID смотреть видео.
+// Приватное видео доступно только владельцу, анонимный пользователь имеет userID 0.
+func (v *Video) CanView(userID int) bool {
+	return v.Visibility != VisibilityPrivate || (userID != 0 && v.OwnerID == userID)
+}
+
+// IsVideoStatus сообщает, является ли status известным статусом обработки видео.
+func IsVideoStatus(status string) bool {
+	switch status {
+	case VideoUploaded, VideoProcessing, VideoReady, VideoFailed:
+		return true
+	}
+	return false
+}
+
+// InsertVideo добавляет новую запись видео пользователя ownerID в таблицу 'videos' и возвращает ее ID.
+func (db *DB) InsertVideo(ctx context.Context, videoName, fileName string, ownerID int) (int, error) {
+	insertSQL := `INSERT INTO videos (video_name, file_name, status, created_at, updated_at, owner_id)
+	VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, ?)`
+	id, err := db.insertID(ctx, insertSQL, videoName, fileName, VideoUploaded, nullID(ownerID))
 	if err != nil {
-		return fmt.Errorf("ошибка вставки видео (video_name: '%s', file_name: '%s'): %w", videoName, fileName, err)
+		return 0, fmt.Errorf("ошибка вставки видео (video_name: '%s', file_name: '%s'): %w", videoName, fileName, wrapError(err))
 	}
 	fmt.Printf("Видео добавлено: '%s' -> '%s'\n", videoName, fileName)
-	return nil
+	return id, nil
+}
+
+// Порядок сортировки списка видео. Время загрузки растет вместе с ID,
+// поэтому сортировка по нему идет по id без отдельного индекса.
+const (
+	VideoSortCreated  = "created"
+	VideoSortName     = "name"
+	VideoSortDuration = "duration"
+)
+
+// ErrInvalidCursor возвращается, если курсор страницы поврежден
+// или выдан для другой сортировки.
+var ErrInvalidCursor = errors.New("некорректный курсор страницы")
+
+// VideoQuery описывает выборку страницы видео.
+type VideoQuery struct {
+	Status string // Только видео с этим статусом, пустой - любые
+	Viewer int    // ID пользователя: кроме публичных видео, ему показываются его собственные
+	Search string // Поиск по названию и описанию, пустой - без поиска
+	Sort   string // VideoSortCreated, VideoSortName или VideoSortDuration
+	Desc   bool   // Сортировка по убыванию
+	Limit  int    // Размер страницы
+	Cursor string // NextCursor предыдущей страницы, пустой - первая страница
+}
+
+// VideoPage - страница списка видео.
+// NextCursor пустой, если страница последняя. Total - число видео по фильтрам без учета страниц.
+type VideoPage struct {
+	Videos     []Video `json:"videos"`
+	NextCursor string  `json:"next_cursor"`
+	Total      int     `json:"total"`
+}
+
+// videoCursor - позиция последнего видео страницы, кодируется в base64url JSON.
+type videoCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v,omitempty"`
+	ID    int         `json:"id"`
+}
+
+// IsVideoSort сообщает, является ли sort известным порядком сортировки.
+func IsVideoSort(sort string) bool {
+	switch sort {
+	case VideoSortCreated, VideoSortName, VideoSortDuration:
+		return true
+	}
+	return false
 }
 
-// GetAllVideos получает все записи из таблицы 'videos'.
-func (db *DB) GetAllVideos() ([]Video, error) {
-	querySQL := `SELECT id, video_name, file_name FROM videos`
-	rows, err := db.conn.Query(querySQL)
+// ListVideos возвращает страницу видео по фильтрам, сортировке и курсору.
+// Пагинация по ключу: следующая страница начинается строго после последнего
+// видео предыдущей, поэтому новые загрузки не сдвигают уже выданные страницы.
+func (db *DB) ListVideos(ctx context.Context, q VideoQuery) (*VideoPage, error) {
+	if q.Sort == "" {
+		q.Sort = VideoSortCreated
+	}
+	var sortColumn string
+	switch q.Sort {
+	case VideoSortCreated:
+	case VideoSortName:
+		sortColumn = "video_name"
+	case VideoSortDuration:
+		sortColumn = "duration"
+	default:
+		return nil, fmt.Errorf("неизвестная сортировка %q", q.Sort)
+	}
+	if q.Limit < 1 {
+		return nil, fmt.Errorf("некорректный размер страницы %d", q.Limit)
+	}
+
+	where := []string{`visibility = ?`}
+	args := []interface{}{VisibilityPublic}
+	if q.Viewer != 0 {
+		where[0] = `(visibility = ? OR owner_id = ?)`
+		args = append(args, q.Viewer)
+	}
+	if q.Status != "" {
+		where = append(where, `status = ?`)
+		args = append(args, q.Status)
+	}
+	if cond, condArgs, ok := db.searchCondition(q.Search); ok {
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	var total int
+	countSQL := `SELECT COUNT(*) FROM videos` + whereClause(where)
+	if err := db.queryRow(ctx, countSQL, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("ошибка подсчета видео: %w", err)
+	}
+
+	cmp, dir := ">", "ASC"
+	if q.Desc {
+		cmp, dir = "<", "DESC"
+	}
+	if q.Cursor != "" {
+		c, err := decodeVideoCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return nil, err
+		}
+		if sortColumn == "" {
+			where = append(where, `id `+cmp+` ?`)
+			args = append(args, c.ID)
+		} else {
+			where = append(where, `(`+sortColumn+`, id) `+cmp+` (?, ?)`)
+			args = append(args, c.Value, c.ID)
+		}
+	}
+
+	orderBy := `id ` + dir
+	if sortColumn != "" {
+		orderBy = sortColumn + ` ` + dir + `, ` + orderBy
+	}
+	// Лишняя строка показывает, есть ли следующая страница
+	querySQL := `SELECT ` + videoColumns + ` FROM videos` + whereClause(where) +
+		` ORDER BY ` + orderBy + ` LIMIT ?`
+	args = append(args, q.Limit+1)
+
+	rows, err := db.query(ctx, querySQL, args...)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
 	}
 	defer rows.Close()
 
-	var videos []Video
+	page := &VideoPage{Videos: []Video{}, Total: total}
 	for rows.Next() {
-		var v Video
-		err := rows.Scan(&v.ID, &v.VideoName, &v.FileName)
+		v, err := scanVideo(rows)
 		if err != nil {
 			return nil, fmt.Errorf("ошибка сканирования строки: %w", err)
 		}
-		videos = append(videos, v)
+		page.Videos = append(page.Videos, *v)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("ошибка итерации по строкам: %w", err)
 	}
 
-	return videos, nil
+	if len(page.Videos) > q.Limit {
+		page.Videos = page.Videos[:q.Limit]
+		page.NextCursor = encodeVideoCursor(q.Sort, page.Videos[q.Limit-1])
+	}
+	if err := db.loadTags(ctx, page.Videos); err != nil {
+		return nil, err
+	}
+	return page, nil
 }
 
-// GetVideoByID получает видео по его ID.
-func (db *DB) GetVideoByID(id int) (*Video, error) {
-	querySQL := `SELECT id, video_name, file_name FROM videos WHERE id = ?`
-	row := db.conn.QueryRow(querySQL, id)
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ``
+	}
+	return ` WHERE ` + strings.Join(conds, ` AND `)
+}
 
-	var v Video
-	err := row.Scan(&v.ID, &v.VideoName, &v.FileName)
+func encodeVideoCursor(sort string, last Video) string {
+	c := videoCursor{Sort: sort, ID: last.ID}
+	switch sort {
+	case VideoSortName:
+		c.Value = last.VideoName
+	case VideoSortDuration:
+		c.Value = last.Duration
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeVideoCursor(cursor, sort string) (*videoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка получения видео по ID %d: %w", id, err)
+		return nil, ErrInvalidCursor
+	}
+	var c videoCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort {
+		return nil, ErrInvalidCursor
 	}
 
-	return &v, nil
+	switch sort {
+	case VideoSortName:
+		if _, ok := c.Value.(string); !ok {
+			return nil, ErrInvalidCursor
+		}
+	case VideoSortDuration:
+		if _, ok := c.Value.(float64); !ok {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &c, nil
 }
 
-// GetVideoByFileName получает видео по его file_name.
-func (db *DB) GetVideoByFileName(fileName string) (*Video, error) {
-	querySQL := `SELECT id, video_name, file_name FROM videos WHERE file_name = ?`
-	row := db.conn.QueryRow(querySQL, fileName)
+// GetVideoByID получает видео по его ID.
+func (db *DB) GetVideoByID(ctx context.Context, id int) (*Video, error) {
+	querySQL := `SELECT ` + videoColumns + ` FROM videos WHERE id = ?`
+	v, err := scanVideo(db.queryRow(ctx, querySQL, id))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения видео по ID %d: %w", id, wrapError(err))
+	}
+	videos := []Video{*v}
+	if err := db.loadTags(ctx, videos); err != nil {
+		return nil, err
+	}
 
-	var v Video
-	err := row.Scan(&v.ID, &v.VideoName, &v.FileName)
+	return &videos[0], nil
+}
+
+// GetVideoByFileName получает видео по его file_name.
+func (db *DB) GetVideoByFileName(ctx context.Context, fileName string) (*Video, error) {
+	querySQL := `SELECT ` + videoColumns + ` FROM videos WHERE file_name = ?`
+	v, err := scanVideo(db.queryRow(ctx, querySQL, fileName))
 	if err != nil {
-		return nil, fmt.Errorf("ошибка получения видео по file_name '%s': %w", fileName, err)
+		return nil, fmt.Errorf("ошибка получения видео по file_name '%s': %w", fileName, wrapError(err))
 	}
 
-	return &v, nil
+	return v, nil
 }
 
 // UpdateVideo обновляет video_name и/или file_name видео по ID.
-func (db *DB) UpdateVideo(id int, newVideoName, newFileName string) error {
+func (db *DB) UpdateVideo(ctx context.Context, id int, newVideoName, newFileName string) error {
 	var updates []string
 	var args []interface{}
 
@@ -124,11 +335,12 @@ func (db *DB) UpdateVideo(id int, newVideoName, newFileName string) error {
 		return nil
 	}
 
+	updates = append(updates, "updated_at = CURRENT_TIMESTAMP")
 	args = append(args, id)
 	querySQL := fmt.Sprintf("UPDATE videos SET %s WHERE id = ?", strings.Join(updates, ", "))
-	result, err := db.conn.Exec(querySQL, args...)
+	result, err := db.exec(ctx, querySQL, args...)
 	if err != nil {
-		return fmt.Errorf("ошибка обновления видео с ID %d: %w", id, err)
+		return fmt.Errorf("ошибка обновления видео с ID %d: %w", id, wrapError(err))
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -136,17 +348,86 @@ func (db *DB) UpdateVideo(id int, newVideoName, newFileName string) error {
 		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("видео с ID %d не найдено для обновления", id)
+		return fmt.Errorf("видео с ID %d не найдено для обновления: %w", id, ErrNotFound)
 	}
 
 	fmt.Printf("Видео с ID %d обновлено.\n", id)
 	return nil
 }
 
+// UpdateVideoMetadata сохраняет название, описание, видимость и теги видео.
+// Теги заменяются целиком.
+func (db *DB) UpdateVideoMetadata(ctx context.Context, id int, meta VideoMetadata) error {
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		updateSQL := `UPDATE videos SET video_name = ?, description = ?, visibility = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+		result, err := tx.ExecContext(ctx, db.rebind(updateSQL), meta.VideoName, meta.Description, meta.Visibility, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		if _, err := tx.ExecContext(ctx, db.rebind(`DELETE FROM video_tags WHERE video_id = ?`), id); err != nil {
+			return err
+		}
+		for _, tag := range meta.Tags {
+			if _, err := tx.ExecContext(ctx, db.rebind(`INSERT INTO video_tags (video_id, tag) VALUES (?, ?)`), id, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка обновления данных видео с ID %d: %w", id, wrapError(err))
+	}
+	return nil
+}
+
+// UpdateVideoStatus меняет статус обработки видео и текст ошибки.
+func (db *DB) UpdateVideoStatus(ctx context.Context, id int, status, errorMessage string) error {
+	updateSQL := `UPDATE videos SET status = ?, error_message = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	result, err := db.exec(ctx, updateSQL, status, errorMessage, id)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления статуса видео с ID %d: %w", id, wrapError(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("видео с ID %d не найдено для обновления статуса: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateVideoDuration сохраняет длительность видео в секундах.
+func (db *DB) UpdateVideoDuration(ctx context.Context, id int, duration float64) error {
+	updateSQL := `UPDATE videos SET duration = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	result, err := db.exec(ctx, updateSQL, duration, id)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения длительности видео с ID %d: %w", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("видео с ID %d не найдено для сохранения длительности: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
 // DeleteVideoByID удаляет видео по его ID.
-func (db *DB) DeleteVideoByID(id int) error {
+func (db *DB) DeleteVideoByID(ctx context.Context, id int) error {
 	deleteSQL := `DELETE FROM videos WHERE id = ?`
-	result, err := db.conn.Exec(deleteSQL, id)
+	result, err := db.exec(ctx, deleteSQL, id)
 	if err != nil {
 		return fmt.Errorf("ошибка удаления видео: %w", err)
 	}
@@ -156,7 +437,7 @@ func (db *DB) DeleteVideoByID(id int) error {
 		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("видео с ID %d не найдено для удаления", id)
+		return fmt.Errorf("видео с ID %d не найдено для удаления: %w", id, ErrNotFound)
 	}
 
 	fmt.Printf("Видео с ID %d удалено.\n", id)
@@ -164,9 +445,9 @@ func (db *DB) DeleteVideoByID(id int) error {
 }
 
 // DeleteVideoByFileName удаляет видео по его file_name.
-func (db *DB) DeleteVideoByFileName(fileName string) error {
+func (db *DB) DeleteVideoByFileName(ctx context.Context, fileName string) error {
 	deleteSQL := `DELETE FROM videos WHERE file_name = ?`
-	result, err := db.conn.Exec(deleteSQL, fileName)
+	result, err := db.exec(ctx, deleteSQL, fileName)
 	if err != nil {
 		return fmt.Errorf("ошибка удаления видео по file_name: %w", err)
 	}
@@ -176,9 +457,73 @@ func (db *DB) DeleteVideoByFileName(fileName string) error {
 		return fmt.Errorf("ошибка получения количества затронутых строк: %w", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("видео с file_name '%s' не найдено для удаления", fileName)
+		return fmt.Errorf("видео с file_name '%s' не найдено для удаления: %w", fileName, ErrNotFound)
 	}
 
 	fmt.Printf("Видео с file_name '%s' удалено.\n", fileName)
 	return nil
 }
+
+// loadTags заполняет теги видео одним запросом.
+func (db *DB) loadTags(ctx context.Context, videos []Video) error {
+	if len(videos) == 0 {
+		return nil
+	}
+	byID := make(map[int]*Video, len(videos))
+	placeholders := make([]string, len(videos))
+	args := make([]interface{}, len(videos))
+	for i := range videos {
+		videos[i].Tags = []string{}
+		byID[videos[i].ID] = &videos[i]
+		placeholders[i] = "?"
+		args[i] = videos[i].ID
+	}
+
+	querySQL := `SELECT video_id, tag FROM video_tags WHERE video_id IN (` + strings.Join(placeholders, ", ") + `) ORDER BY tag`
+	rows, err := db.query(ctx, querySQL, args...)
+	if err != nil {
+		return fmt.Errorf("ошибка получения тегов видео: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var videoID int
+		var tag string
+		if err := rows.Scan(&videoID, &tag); err != nil {
+			return fmt.Errorf("ошибка сканирования строки: %w", err)
+		}
+		if v, ok := byID[videoID]; ok {
+			v.Tags = append(v.Tags, tag)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка итерации по строкам: %w", err)
+	}
+	return nil
+}
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanVideo читает видео из строки результата запроса.
+func scanVideo(row rowScanner) (*Video, error) {
+	var v Video
+	var fileName sql.NullString
+	var ownerID sql.NullInt64
+	err := row.Scan(&v.ID, &v.VideoName, &fileName, &v.Status, &v.ErrorMessage, &v.CreatedAt, &v.UpdatedAt, &ownerID,
+		&v.Description, &v.Duration, &v.Visibility)
+	if err != nil {
+		return nil, err
+	}
+	v.FileName = fileName.String
+	v.OwnerID = int(ownerID.Int64)
+	return &v, nil
+}
+
+// nullID превращает отсутствующий ID (0) в NULL для внешнего ключа.
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
@@ -0,0 +1,47 @@
+// Package handlers содержит общее для HTTP-обработчиков всех разделов API.
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"video/database"
+)
+
+// StatusClientClosedRequest - клиент закрыл соединение, не дождавшись ответа.
+// Нестандартный код, как в nginx: виден только в логах.
+const StatusClientClosedRequest = 499
+
+// StorageStatus возвращает HTTP-статус для ошибки хранилища.
+func StorageStatus(err error) int {
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, database.ErrConflict), errors.Is(err, database.ErrStale):
+		return http.StatusConflict
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// StorageError отвечает клиенту на ошибку хранилища. Для отсутствующей записи
+// отправляется текст notFound, для остальных ошибок - общий текст по статусу:
+// подробности сбоя базы клиенту не показываются.
+func StorageError(w http.ResponseWriter, err error, notFound string) {
+	status := StorageStatus(err)
+	switch status {
+	case http.StatusNotFound:
+		http.Error(w, notFound, status)
+	case http.StatusConflict:
+		if errors.Is(err, database.ErrStale) {
+			http.Error(w, "Changed by another request, try again", status)
+			return
+		}
+		http.Error(w, "Already exists", status)
+	default:
+		http.Error(w, http.StatusText(status), status)
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"video/database"
+)
+
+func TestStorageStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"нет записи", database.ErrNotFound, http.StatusNotFound},
+		{"обернутая ErrNotFound", fmt.Errorf("видео 7: %w", database.ErrNotFound), http.StatusNotFound},
+		{"запись уже есть", fmt.Errorf("%w: UNIQUE constraint failed", database.ErrConflict), http.StatusConflict},
+		{"запись успели изменить", fmt.Errorf("очередь: %w", database.ErrStale), http.StatusConflict},
+		{"клиент отключился", fmt.Errorf("запрос: %w", context.Canceled), StatusClientClosedRequest},
+		{"истек таймаут", fmt.Errorf("запрос: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{"сбой базы", errors.New("database is locked"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := StorageStatus(tt.err); got != tt.code {
+			t.Errorf("%s: StorageStatus = %d, ожидался %d", tt.name, got, tt.code)
+		}
+	}
+}
+
+func TestStorageError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+		body string
+	}{
+		{"нет записи", database.ErrNotFound, http.StatusNotFound, "Video not found"},
+		{"запись уже есть", database.ErrConflict, http.StatusConflict, "Already exists"},
+		{"запись успели изменить", database.ErrStale, http.StatusConflict, "Changed by another request, try again"},
+		{"истек таймаут", context.DeadlineExceeded, http.StatusGatewayTimeout, "Gateway Timeout"},
+		// Подробности сбоя базы клиенту не показываются
+		{"сбой базы", errors.New("database is locked"), http.StatusInternalServerError, "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		StorageError(w, tt.err, "Video not found")
+		if body := strings.TrimSpace(w.Body.String()); w.Code != tt.code || body != tt.body {
+			t.Errorf("%s: ответ %d %q, ожидался %d %q", tt.name, w.Code, body, tt.code, tt.body)
+		}
+	}
+}
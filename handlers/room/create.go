@@ -0,0 +1,90 @@
+package room
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"video/auth"
+	"video/database"
+	"video/handlers"
+	"video/rooms"
+)
+
+// maxCodeAttempts - сколько раз пытаемся подобрать свободный код приглашения.
+const maxCodeAttempts = 5
+
+type createRequest struct {
+	VideoID int `json:"video_id"`
+}
+
+// Create создает комнату для просмотра видео.
+// POST /room/create, тело: {"video_id": 1}
+func Create(roomStorage database.RoomStorage, videoStorage database.VideoStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req createRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.VideoID <= 0 {
+			slog.Warn("Некорректный запрос на создание комнаты",
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			http.Error(w, "Invalid request body: video_id is required", http.StatusBadRequest)
+			return
+		}
+
+		video, err := videoStorage.GetVideoByID(r.Context(), req.VideoID)
+		if err == nil {
+			// Приватное видео может показать в комнате только владелец
+			if user, _ := auth.UserFrom(r.Context()); !video.CanView(user.ID) {
+				err = database.ErrNotFound
+			}
+		}
+		if err != nil {
+			slog.Warn("Видео для комнаты не найдено",
+				"video_id", req.VideoID,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			handlers.StorageError(w, err, "Video not found")
+			return
+		}
+
+		var room *database.Room
+		for attempt := 0; attempt < maxCodeAttempts && room == nil; attempt++ {
+			created, err := roomStorage.InsertRoom(r.Context(), rooms.NewInviteCode(), req.VideoID)
+			if errors.Is(err, database.ErrConflict) {
+				continue // Код уже занят — пробуем другой
+			}
+			if err != nil {
+				slog.Error("Не удалось создать комнату",
+					"video_id", req.VideoID,
+					"ошибка", err,
+					"удалённый_адрес", r.RemoteAddr,
+				)
+				handlers.StorageError(w, err, "")
+				return
+			}
+			room = created
+		}
+		if room == nil {
+			slog.Error("Не удалось подобрать свободный код комнаты",
+				"попыток", maxCodeAttempts,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			http.Error(w, "Failed to create room", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("Комната создана",
+			"room_id", room.ID,
+			"code", room.Code,
+			"video_id", room.VideoID,
+		)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(room); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package room
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"video/database"
+	"video/handlers"
+	"video/rooms"
+)
+
+type joinRequest struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type joinResponse struct {
+	Room   *database.Room   `json:"room"`
+	Member *database.Member `json:"member"`
+	Token  string           `json:"token"` // Нужен для выхода из комнаты и подключения к ней
+}
+
+// Join добавляет участника в комнату по коду приглашения.
+// POST /room/join, тело: {"code": "ABC123", "name": "Вася"}
+func Join(roomStorage database.RoomStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req joinRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			slog.Warn("Некорректный запрос на вход в комнату",
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		code := rooms.NormalizeCode(req.Code)
+		name := strings.TrimSpace(req.Name)
+		if code == "" || name == "" {
+			http.Error(w, "Missing required fields: code, name", http.StatusBadRequest)
+			return
+		}
+		if len([]rune(name)) > maxNameLength {
+			http.Error(w, "Name is too long", http.StatusBadRequest)
+			return
+		}
+
+		room, err := roomStorage.GetRoomByCode(r.Context(), code)
+		if err != nil {
+			slog.Warn("Комната по коду не найдена",
+				"code", code,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			handlers.StorageError(w, err, "Room not found")
+			return
+		}
+
+		member, err := roomStorage.InsertMember(r.Context(), room.ID, name, rooms.NewMemberToken())
+		if err != nil {
+			slog.Error("Не удалось добавить участника в комнату",
+				"room_id", room.ID,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			http.Error(w, "Failed to join room", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("Участник вошёл в комнату",
+			"room_id", room.ID,
+			"member_id", member.ID,
+			"имя", member.Name,
+		)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(joinResponse{
+			Room:   room,
+			Member: member,
+			Token:  member.Token,
+		}); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package room
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"video/database"
+	"video/handlers"
+	"video/rooms"
+)
+
+type leaveRequest struct {
+	Token string `json:"token"`
+}
+
+// Leave удаляет участника из комнаты. Если уходит хозяин, его роль передается
+// другому участнику. Последний вышедший закрывает комнату.
+// POST /room/{id}/leave, тело: {"token": "..."}
+func Leave(roomStorage database.RoomStorage, hub *rooms.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+
+		var req leaveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			http.Error(w, "Missing required field: token", http.StatusBadRequest)
+			return
+		}
+
+		member, err := roomStorage.GetMemberByToken(r.Context(), req.Token)
+		if err == nil && member.RoomID != roomID {
+			err = database.ErrNotFound
+		}
+		if err != nil {
+			slog.Warn("Участник не найден в комнате",
+				"room_id", roomID,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			handlers.StorageError(w, err, "Member not found")
+			return
+		}
+
+		if member.Role == database.RoleHost {
+			hub.HandOverHost(r.Context(), roomID, member.ID)
+		}
+		if err := roomStorage.DeleteMember(r.Context(), roomID, member.ID); err != nil {
+			slog.Error("Не удалось удалить участника из комнаты",
+				"room_id", roomID,
+				"member_id", member.ID,
+				"ошибка", err,
+			)
+			http.Error(w, "Failed to leave room", http.StatusInternalServerError)
+			return
+		}
+
+		members, err := roomStorage.GetMembers(r.Context(), roomID)
+		if err == nil && len(members) == 0 {
+			if err := roomStorage.DeleteRoomByID(r.Context(), roomID); err != nil {
+				slog.Error("Не удалось удалить опустевшую комнату",
+					"room_id", roomID,
+					"ошибка", err,
+				)
+			} else {
+				hub.Close(roomID)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{
+			"message": "Left the room",
+		}); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
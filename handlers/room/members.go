@@ -0,0 +1,40 @@
+package room
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"video/database"
+)
+
+// Members возвращает список участников комнаты. Список видят только участники.
+// GET /room/{id}/members?token=<токен участника>
+func Members(roomStorage database.RoomStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := roomMember(w, r, roomStorage, roomID); !ok {
+			return
+		}
+
+		members, err := roomStorage.GetMembers(r.Context(), roomID)
+		if err != nil {
+			slog.Error("Не удалось получить участников комнаты",
+				"room_id", roomID,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			http.Error(w, "Failed to get members", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(members); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package room
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"video/database"
+	"video/handlers"
+)
+
+// Размер страницы истории чата.
+const (
+	defaultMessagesPage = 50
+	maxMessagesPage     = 100
+)
+
+// Messages возвращает историю чата комнаты страницами от новых сообщений к старым.
+// Сообщения на странице идут по порядку отправки. Чтобы прокрутить историю дальше,
+// передайте в before ID первого сообщения страницы, пока has_more = true.
+// GET /room/{id}/messages?token=<токен участника>&before=120&limit=50
+func Messages(roomStorage database.RoomStorage, messageStorage database.MessageStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+		if _, ok := roomMember(w, r, roomStorage, roomID); !ok {
+			return
+		}
+
+		query := r.URL.Query()
+		before := 0
+		if value := query.Get("before"); value != "" {
+			before, err = strconv.Atoi(value)
+			if err != nil || before <= 0 {
+				http.Error(w, "before must be a message id", http.StatusBadRequest)
+				return
+			}
+		}
+		limit := defaultMessagesPage
+		if value := query.Get("limit"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > maxMessagesPage {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxMessagesPage), http.StatusBadRequest)
+				return
+			}
+		}
+
+		page, err := messageStorage.GetMessages(r.Context(), roomID, before, limit)
+		if err != nil {
+			slog.Error("Не удалось получить историю чата",
+				"room_id", roomID,
+				"ошибка", err,
+			)
+			handlers.StorageError(w, err, "")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package room
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"video/database"
+	"video/handlers"
+	"video/playback"
+)
+
+// Playback выдает участнику комнаты подписанную ссылку на видео комнаты.
+// Ссылка перестает работать, когда комната закрывается.
+// GET /room/{id}/playback?token=<токен участника>
+func Playback(roomStorage database.RoomStorage, videoStorage database.VideoStorage, signer *playback.Signer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := roomMember(w, r, roomStorage, roomID); !ok {
+			return
+		}
+
+		room, err := roomStorage.GetRoomByID(r.Context(), roomID)
+		if err != nil {
+			handlers.StorageError(w, err, "Room not found")
+			return
+		}
+		video, err := videoStorage.GetVideoByID(r.Context(), room.VideoID)
+		if err != nil {
+			slog.Error("Видео комнаты не найдено",
+				"room_id", roomID,
+				"video_id", room.VideoID,
+				"ошибка", err,
+			)
+			handlers.StorageError(w, err, "Video not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(signer.Link(video.ID, video.FileName, roomID)); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"video/auth"
+	"video/database"
+	"video/handlers"
+	"video/rooms"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxQueueLength - сколько видео можно поставить в очередь комнаты.
+const maxQueueLength = 100
+
+type addToQueueRequest struct {
+	VideoID int `json:"video_id"`
+}
+
+type reorderQueueRequest struct {
+	Items []int `json:"items"` // ID элементов очереди в новом порядке
+}
+
+// Queue возвращает очередь видео комнаты. Текущее видео в нее не входит.
+// Очередь видна только участникам комнаты.
+// GET /room/{id}/queue?token=<токен участника>
+func Queue(roomStorage database.RoomStorage, queueStorage database.QueueStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+		if _, ok := roomMember(w, r, roomStorage, roomID); !ok {
+			return
+		}
+
+		queue, err := queueStorage.GetQueue(r.Context(), roomID)
+		if err != nil {
+			slog.Error("Не удалось получить очередь комнаты",
+				"room_id", roomID,
+				"ошибка", err,
+			)
+			http.Error(w, "Failed to get queue", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(queue); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// AddToQueue ставит видео в конец очереди комнаты.
+// Менять очередь могут участники, которым это разрешает политика комнат.
+// POST /room/{id}/queue?token=<токен участника>, тело: {"video_id": 1}
+func AddToQueue(roomStorage database.RoomStorage, queueStorage database.QueueStorage, videoStorage database.VideoStorage, hub *rooms.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+		member, ok := roomMember(w, r, roomStorage, roomID)
+		if !ok || !allowed(w, r, hub, member, rooms.ActionQueue) {
+			return
+		}
+
+		var req addToQueueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.VideoID <= 0 {
+			http.Error(w, "Invalid request body: video_id is required", http.StatusBadRequest)
+			return
+		}
+
+		video, err := videoStorage.GetVideoByID(r.Context(), req.VideoID)
+		if err == nil {
+			// Приватное видео может поставить в очередь только владелец
+			if user, _ := auth.UserFrom(r.Context()); !video.CanView(user.ID) {
+				err = database.ErrNotFound
+			}
+		}
+		if err != nil {
+			handlers.StorageError(w, err, "Video not found")
+			return
+		}
+		if video.Status != database.VideoReady {
+			http.Error(w, "Video is not processed yet", http.StatusConflict)
+			return
+		}
+
+		queue, err := queueStorage.GetQueue(r.Context(), roomID)
+		if err != nil {
+			handlers.StorageError(w, err, "")
+			return
+		}
+		if len(queue) >= maxQueueLength {
+			http.Error(w, "Queue is full", http.StatusConflict)
+			return
+		}
+
+		item, err := queueStorage.AddToQueue(r.Context(), roomID, video.ID)
+		if err != nil {
+			slog.Error("Не удалось добавить видео в очередь комнаты",
+				"room_id", roomID,
+				"video_id", video.ID,
+				"ошибка", err,
+			)
+			handlers.StorageError(w, err, "")
+			return
+		}
+		slog.Info("Видео добавлено в очередь комнаты",
+			"room_id", roomID,
+			"video_id", video.ID,
+			"member_id", member.ID,
+		)
+		broadcastQueue(r.Context(), queueStorage, hub, roomID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(item); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// RemoveFromQueue убирает видео из очереди комнаты.
+// DELETE /room/{id}/queue/{itemID}?token=<токен участника>
+func RemoveFromQueue(roomStorage database.RoomStorage, queueStorage database.QueueStorage, hub *rooms.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+		member, ok := roomMember(w, r, roomStorage, roomID)
+		if !ok || !allowed(w, r, hub, member, rooms.ActionQueue) {
+			return
+		}
+		itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+		if err != nil || itemID <= 0 {
+			http.Error(w, "Invalid queue item id", http.StatusBadRequest)
+			return
+		}
+
+		if err := queueStorage.DeleteQueueItem(r.Context(), roomID, itemID); err != nil {
+			handlers.StorageError(w, err, "Queue item not found")
+			return
+		}
+		broadcastQueue(r.Context(), queueStorage, hub, roomID)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ReorderQueue меняет порядок очереди комнаты. В теле перечисляются
+// все элементы очереди; если очередь успели изменить, отвечает 409.
+// PUT /room/{id}/queue?token=<токен участника>, тело: {"items": [3, 1, 2]}
+func ReorderQueue(roomStorage database.RoomStorage, queueStorage database.QueueStorage, hub *rooms.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+		member, ok := roomMember(w, r, roomStorage, roomID)
+		if !ok || !allowed(w, r, hub, member, rooms.ActionQueue) {
+			return
+		}
+
+		var req reorderQueueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body: items is required", http.StatusBadRequest)
+			return
+		}
+
+		err = queueStorage.ReorderQueue(r.Context(), roomID, req.Items)
+		if errors.Is(err, database.ErrStale) {
+			http.Error(w, "Items do not match the current queue", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			slog.Error("Не удалось изменить порядок очереди комнаты",
+				"room_id", roomID,
+				"ошибка", err,
+			)
+			handlers.StorageError(w, err, "")
+			return
+		}
+
+		queue := broadcastQueue(r.Context(), queueStorage, hub, roomID)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(queue); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// Skip переключает комнату на следующее видео из очереди, не дожидаясь конца текущего.
+// Участники получают по каналу синхронизации событие next со ссылкой на новое видео.
+// POST /room/{id}/queue/skip?token=<токен участника>
+func Skip(roomStorage database.RoomStorage, hub *rooms.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+		member, ok := roomMember(w, r, roomStorage, roomID)
+		if !ok || !allowed(w, r, hub, member, rooms.ActionQueue) {
+			return
+		}
+
+		room, err := hub.Skip(r.Context(), roomID)
+		if errors.Is(err, database.ErrQueueEmpty) {
+			http.Error(w, "Queue is empty", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, database.ErrStale) {
+			// Комнату переключил параллельный пропуск или окончание видео
+			http.Error(w, "Room has already switched to another video", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			slog.Error("Не удалось переключить комнату на следующее видео",
+				"room_id", roomID,
+				"member_id", member.ID,
+				"ошибка", err,
+			)
+			handlers.StorageError(w, err, "Room not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(room); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// broadcastQueue рассылает участникам комнаты ее очередь после изменения и возвращает ее.
+// Изменение уже сохранено, поэтому ошибка чтения очереди только логируется.
+func broadcastQueue(ctx context.Context, queueStorage database.QueueStorage, hub *rooms.Hub, roomID int) []database.QueueItem {
+	queue, err := queueStorage.GetQueue(ctx, roomID)
+	if err != nil {
+		slog.Error("Не удалось получить очередь комнаты",
+			"room_id", roomID,
+			"ошибка", err,
+		)
+		return []database.QueueItem{}
+	}
+	hub.QueueChanged(roomID, queue)
+	return queue
+}
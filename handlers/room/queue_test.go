@@ -0,0 +1,82 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"video/database"
+)
+
+func TestRoomReadsMembersOnly(t *testing.T) {
+	ctx := context.Background()
+	srv, db, room := syncServer(t)
+
+	next, err := db.InsertVideo(ctx, "Второе", "second.mp4", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddToQueue(ctx, room.ID, next); err != nil {
+		t.Fatal(err)
+	}
+	other, err := db.InsertRoom(ctx, "SYNC02", room.VideoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.InsertMember(ctx, other.ID, "Борис", "stranger-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string, roomID int, token string) *http.Response {
+		resp, err := http.Get(fmt.Sprintf("%s/room/%d/%s?token=%s", srv.URL, roomID, path, token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	tests := []struct {
+		name   string
+		roomID int
+		token  string
+		code   int
+	}{
+		{"без токена", room.ID, "", http.StatusUnauthorized},
+		{"неизвестный токен", room.ID, "unknown", http.StatusForbidden},
+		{"участник другой комнаты", room.ID, "stranger-token", http.StatusForbidden},
+		{"несуществующая комната", room.ID + 100, "viewer-token", http.StatusForbidden},
+	}
+	for _, path := range []string{"queue", "members"} {
+		for _, tt := range tests {
+			if resp := get(path, tt.roomID, tt.token); resp.StatusCode != tt.code {
+				t.Errorf("%s, %s: ответ %d, ожидался %d", path, tt.name, resp.StatusCode, tt.code)
+			}
+		}
+	}
+
+	resp := get("queue", room.ID, "viewer-token")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("участник: ответ %d", resp.StatusCode)
+	}
+	var queue []database.QueueItem
+	if err := json.NewDecoder(resp.Body).Decode(&queue); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].VideoID != next {
+		t.Errorf("очередь = %+v, ожидалось видео %d", queue, next)
+	}
+
+	resp = get("members", room.ID, "viewer-token")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("участники: ответ %d", resp.StatusCode)
+	}
+	var members []database.Member
+	if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].Name != "host-token" || members[1].Name != "viewer-token" {
+		t.Errorf("участники = %+v, ожидались только хозяин и зритель комнаты", members)
+	}
+}
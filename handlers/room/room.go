@@ -0,0 +1,72 @@
+// Package room содержит HTTP-обработчики комнат совместного просмотра.
+package room
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"video/database"
+	"video/handlers"
+	"video/rooms"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxNameLength - максимальная длина отображаемого имени участника.
+const maxNameLength = 64
+
+// roomIDParam извлекает ID комнаты из параметра маршрута {id}.
+func roomIDParam(r *http.Request) (int, error) {
+	idParam := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("некорректный ID комнаты: %q", idParam)
+	}
+	return id, nil
+}
+
+// roomMember находит участника комнаты roomID по токену из параметра token.
+// При отказе пишет ответ сам.
+func roomMember(w http.ResponseWriter, r *http.Request, roomStorage database.RoomStorage, roomID int) (*database.Member, bool) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Missing required parameter: token", http.StatusUnauthorized)
+		return nil, false
+	}
+	member, err := roomStorage.GetMemberByToken(r.Context(), token)
+	if err == nil && member.RoomID != roomID {
+		err = database.ErrNotFound
+	}
+	if errors.Is(err, database.ErrNotFound) {
+		slog.Warn("Участник не найден в комнате",
+			"room_id", roomID,
+			"удалённый_адрес", r.RemoteAddr,
+		)
+		http.Error(w, "Member not found", http.StatusForbidden)
+		return nil, false
+	}
+	if err != nil {
+		handlers.StorageError(w, err, "")
+		return nil, false
+	}
+	return member, true
+}
+
+// allowed проверяет, разрешено ли участнику member действие action.
+// При отказе пишет ответ сам.
+func allowed(w http.ResponseWriter, r *http.Request, hub *rooms.Hub, member *database.Member, action string) bool {
+	if hub.Allows(member, action) {
+		return true
+	}
+	slog.Warn("Действие в комнате запрещено ролью участника",
+		"room_id", member.RoomID,
+		"member_id", member.ID,
+		"role", member.Role,
+		"action", action,
+		"удалённый_адрес", r.RemoteAddr,
+	)
+	http.Error(w, "Your role is not allowed to "+action, http.StatusForbidden)
+	return false
+}
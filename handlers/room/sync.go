@@ -0,0 +1,46 @@
+package room
+
+import (
+	"log/slog"
+	"net/http"
+	"video/database"
+	"video/rooms"
+
+	"github.com/gorilla/websocket"
+)
+
+// upgrader разрешает подключения с любого Origin, как и CORSMiddleware.
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// Sync подключает участника к каналу синхронизации воспроизведения комнаты.
+// GET /room/{id}/ws?token=... (токен выдается при входе в комнату)
+func Sync(roomStorage database.RoomStorage, hub *rooms.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := roomIDParam(r)
+		if err != nil {
+			http.Error(w, "Invalid room id", http.StatusBadRequest)
+			return
+		}
+
+		member, ok := roomMember(w, r, roomStorage, roomID)
+		if !ok {
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade уже отправил клиенту ответ с ошибкой
+			slog.Error("Не удалось установить WebSocket-соединение",
+				"room_id", roomID,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			return
+		}
+		hub.Serve(conn, *member)
+	}
+}
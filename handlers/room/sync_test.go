@@ -0,0 +1,140 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"video/database"
+	"video/database/dbtest"
+	"video/playback"
+	"video/rooms"
+	"video/rooms/roomstest"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+)
+
+// syncServer поднимает канал синхронизации и чтение комнаты на httptest-сервере
+// и возвращает комнату с хозяином (токен "host-token") и зрителем ("viewer-token").
+func syncServer(t *testing.T) (*httptest.Server, *database.DB, *database.Room) {
+	t.Helper()
+	ctx := context.Background()
+
+	db := dbtest.Open(t)
+	videoID, err := db.InsertVideo(ctx, "Фильм", "film.mp4", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	room, err := db.InsertRoom(ctx, "SYNC01", videoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"host-token", "viewer-token"} {
+		if _, err := db.InsertMember(ctx, room.ID, token, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hub := rooms.NewHub(db, db, db, db, db, playback.NewSigner([]byte("secret"), time.Hour), rooms.DefaultPolicy)
+	router := chi.NewRouter()
+	router.Get("/room/{id}/ws", Sync(db, hub))
+	router.Get("/room/{id}/queue", Queue(db, db))
+	router.Get("/room/{id}/members", Members(db))
+	srv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		hub.Close(room.ID)
+		srv.Close()
+	})
+	return srv, db, room
+}
+
+// dialRoom подключается к каналу синхронизации комнаты с токеном token.
+func dialRoom(srv *httptest.Server, roomID int, token string) (*websocket.Conn, *http.Response, error) {
+	url := fmt.Sprintf("ws%s/room/%d/ws?token=%s", strings.TrimPrefix(srv.URL, "http"), roomID, token)
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+func TestSyncBroadcast(t *testing.T) {
+	srv, _, room := syncServer(t)
+
+	host, _, err := dialRoom(srv, room.ID, "host-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+	roomstest.ReadEvent(t, host, rooms.EventState, time.Second)
+
+	viewer, _, err := dialRoom(srv, room.ID, "viewer-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewer.Close()
+	state := roomstest.ReadEvent(t, viewer, rooms.EventState, time.Second)
+	if !state.State.Paused || state.State.Position != 0 {
+		t.Fatalf("начальное состояние = %+v, ожидалась пауза в начале", *state.State)
+	}
+	roomstest.ReadEvent(t, host, rooms.EventJoined, time.Second)
+
+	tests := []struct {
+		event    rooms.Event
+		position float64
+		paused   bool
+	}{
+		{rooms.Event{Type: rooms.EventPlay, Position: 10}, 10, false},
+		{rooms.Event{Type: rooms.EventPause, Position: 12}, 12, true},
+		{rooms.Event{Type: rooms.EventSeek, Position: 30}, 30, true},
+	}
+	for _, tt := range tests {
+		if err := host.WriteJSON(tt.event); err != nil {
+			t.Fatal(err)
+		}
+		got := roomstest.ReadEvent(t, viewer, tt.event.Type, time.Second)
+		if got.State == nil {
+			t.Fatalf("%s: нет состояния в событии", tt.event.Type)
+		}
+		// Между применением и проверкой play часы успевают уйти вперед
+		if got.State.Paused != tt.paused || got.State.Position < tt.position || got.State.Position > tt.position+0.5 {
+			t.Errorf("%s: состояние = %+v, ожидалась позиция %v, пауза %v", tt.event.Type, *got.State, tt.position, tt.paused)
+		}
+		if got.MemberName != "host-token" {
+			t.Errorf("%s: автор = %q, ожидался хозяин", tt.event.Type, got.MemberName)
+		}
+	}
+
+	// Следующее состояние после подключения рассылает heartbeat
+	heartbeat := roomstest.ReadEvent(t, viewer, rooms.EventState, 10*time.Second)
+	if !heartbeat.State.Paused || heartbeat.State.Position != 30 {
+		t.Errorf("heartbeat: состояние = %+v, ожидалась пауза на 30", *heartbeat.State)
+	}
+}
+
+func TestSyncRejectsBadToken(t *testing.T) {
+	srv, _, room := syncServer(t)
+
+	tests := []struct {
+		name   string
+		roomID int
+		token  string
+		status int
+	}{
+		{"нет токена", room.ID, "", http.StatusUnauthorized},
+		{"неизвестный токен", room.ID, "nope", http.StatusForbidden},
+		{"участник другой комнаты", room.ID + 1, "host-token", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, resp, err := dialRoom(srv, tt.roomID, tt.token)
+			if err == nil {
+				conn.Close()
+				t.Fatal("подключение с неверным токеном принято")
+			}
+			if resp == nil || resp.StatusCode != tt.status {
+				t.Fatalf("ответ = %v, ожидался статус %d", resp, tt.status)
+			}
+		})
+	}
+}
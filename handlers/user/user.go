@@ -0,0 +1,168 @@
+// Package user содержит HTTP-обработчики регистрации и входа пользователей.
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"video/auth"
+	"video/database"
+	"video/handlers"
+)
+
+// usernamePattern - допустимые имена пользователей.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,32}$`)
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Register создает пользователя и сразу выдает ему токены.
+// POST /user/register, тело: {"username": "...", "password": "..."}
+func Register(userStorage database.UserStorage, tokens *auth.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req credentials
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if !usernamePattern.MatchString(req.Username) {
+			http.Error(w, "Username must be 3-32 characters: letters, digits, '_', '.', '-'", http.StatusBadRequest)
+			return
+		}
+		if len(req.Password) < auth.MinPasswordLength || len(req.Password) > auth.MaxPasswordLength {
+			http.Error(w, fmt.Sprintf("Password must be %d-%d bytes long", auth.MinPasswordLength, auth.MaxPasswordLength), http.StatusBadRequest)
+			return
+		}
+
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			slog.Error("Не удалось захэшировать пароль", "ошибка", err)
+			http.Error(w, "Failed to register", http.StatusInternalServerError)
+			return
+		}
+		user, err := userStorage.InsertUser(r.Context(), req.Username, hash)
+		if errors.Is(err, database.ErrConflict) {
+			http.Error(w, "Username is already taken", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			slog.Error("Не удалось создать пользователя",
+				"username", req.Username,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			http.Error(w, "Failed to register", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("Зарегистрирован пользователь", "user_id", user.ID, "username", user.Username)
+		writeTokens(w, tokens, user, http.StatusCreated)
+	}
+}
+
+// Login проверяет пароль и выдает токены.
+// POST /user/login, тело: {"username": "...", "password": "..."}
+func Login(userStorage database.UserStorage, tokens *auth.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req credentials
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		user, err := userStorage.GetUserByUsername(r.Context(), req.Username)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			slog.Error("Не удалось получить пользователя", "username", req.Username, "ошибка", err)
+			handlers.StorageError(w, err, "")
+			return
+		}
+		hash := ""
+		if err == nil {
+			hash = user.PasswordHash
+		}
+		if !auth.CheckPassword(hash, req.Password) {
+			slog.Warn("Неудачная попытка входа",
+				"username", req.Username,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+			return
+		}
+
+		writeTokens(w, tokens, user, http.StatusOK)
+	}
+}
+
+// Refresh выдает новую пару токенов по refresh-токену.
+// POST /user/refresh, тело: {"refresh_token": "..."}
+func Refresh(userStorage database.UserStorage, tokens *auth.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req refreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "Missing required field: refresh_token", http.StatusBadRequest)
+			return
+		}
+
+		claims, err := tokens.Parse(req.RefreshToken, auth.RefreshToken)
+		if err != nil {
+			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		}
+		// Берем пользователя из базы: имя могло поменяться, а сам он - исчезнуть
+		user, err := userStorage.GetUserByID(r.Context(), claims.ID)
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			handlers.StorageError(w, err, "")
+			return
+		}
+
+		writeTokens(w, tokens, user, http.StatusOK)
+	}
+}
+
+// Me возвращает текущего пользователя.
+// GET /user/me
+func Me(userStorage database.UserStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, _ := auth.UserFrom(r.Context())
+		user, err := userStorage.GetUserByID(r.Context(), current.ID)
+		if err != nil {
+			handlers.StorageError(w, err, "User not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(user); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// writeTokens выдает пользователю токены и отправляет их со статусом status.
+func writeTokens(w http.ResponseWriter, tokens *auth.Manager, user *database.User, status int) {
+	pair, err := tokens.Issue(user)
+	if err != nil {
+		slog.Error("Не удалось выдать токены", "user_id", user.ID, "ошибка", err)
+		http.Error(w, "Failed to issue tokens", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(pair); err != nil {
+		slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+	}
+}
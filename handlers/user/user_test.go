@@ -0,0 +1,76 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"video/auth"
+	"video/database/dbtest"
+)
+
+// userServer возвращает обработчики /user на чистой базе с зарегистрированным "anna".
+func userServer(t *testing.T) (http.Handler, *auth.TokenPair) {
+	t.Helper()
+	db := dbtest.Open(t)
+	tokens := auth.NewManager([]byte("secret"), time.Hour, 24*time.Hour)
+	mux := http.NewServeMux()
+	mux.Handle("POST /user/register", Register(db, tokens))
+	mux.Handle("POST /user/login", Login(db, tokens))
+	mux.Handle("POST /user/refresh", Refresh(db, tokens))
+
+	w := post(mux, "/user/register", `{"username": "anna", "password": "correct horse"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("регистрация: ответ %d %s", w.Code, w.Body)
+	}
+	var pair auth.TokenPair
+	if err := json.NewDecoder(w.Body).Decode(&pair); err != nil {
+		t.Fatal(err)
+	}
+	return mux, &pair
+}
+
+func post(handler http.Handler, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return w
+}
+
+func TestUserHandlers(t *testing.T) {
+	handler, pair := userServer(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"занятое имя", "/user/register", `{"username": "anna", "password": "another pass"}`, http.StatusConflict},
+		{"занятое имя в другом регистре", "/user/register", `{"username": "ANNA", "password": "another pass"}`, http.StatusConflict},
+		{"короткое имя", "/user/register", `{"username": "an", "password": "another pass"}`, http.StatusBadRequest},
+		{"короткий пароль", "/user/register", `{"username": "boris", "password": "short"}`, http.StatusBadRequest},
+		{"не JSON", "/user/register", `username=boris`, http.StatusBadRequest},
+		{"новый пользователь", "/user/register", `{"username": "boris", "password": "another pass"}`, http.StatusCreated},
+		{"вход", "/user/login", `{"username": "anna", "password": "correct horse"}`, http.StatusOK},
+		{"неизвестный пользователь", "/user/login", `{"username": "nobody", "password": "correct horse"}`, http.StatusUnauthorized},
+		{"неверный пароль", "/user/login", `{"username": "anna", "password": "wrong horse"}`, http.StatusUnauthorized},
+		{"обновление", "/user/refresh", `{"refresh_token": "` + pair.RefreshToken + `"}`, http.StatusOK},
+		{"обновление access-токеном", "/user/refresh", `{"refresh_token": "` + pair.AccessToken + `"}`, http.StatusUnauthorized},
+		{"обновление без токена", "/user/refresh", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := post(handler, tt.path, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s: ответ %d %q, ожидался %d", tt.name, w.Code, strings.TrimSpace(w.Body.String()), tt.status)
+			continue
+		}
+		if w.Code == http.StatusOK || w.Code == http.StatusCreated {
+			var got auth.TokenPair
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil || got.AccessToken == "" || got.RefreshToken == "" {
+				t.Errorf("%s: в ответе нет токенов: %+v, %v", tt.name, got, err)
+			}
+		}
+	}
+}
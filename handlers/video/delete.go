@@ -2,18 +2,26 @@ package video
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"path/filepath"
+	"video/auth"
 	"video/config"
 	"video/database"
+	"video/handlers"
+	"video/storage"
 
 	"log/slog"
 )
 
-// Delete удаляет видео по имени файла.
+// Delete удаляет видео по имени файла вместе с его файлами в хранилище backend.
 // Ожидает GET-параметр: ?file_name=имя_файла.mp4
-func Delete(db *database.DB) http.HandlerFunc {
+// Задачи транскодирования видео удаляются базой вместе с ним, исходник еще
+// не начатой задачи удаляется здесь. Исходник выполняемой задачи удалит пул.
+// Пользователь может удалить только свое видео. У видео, загруженных
+// до появления учетных записей, владельца нет, и через API они не удаляются.
+func Delete(db *database.DB, backend storage.Backend) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		videoName := r.URL.Query().Get("file_name")
 		if videoName == "" {
@@ -27,27 +35,67 @@ func Delete(db *database.DB) http.HandlerFunc {
 		}
 
 		// Ищем видео в БД
-		video, err := db.GetVideoByFileName(videoName)
+		video, err := db.GetVideoByFileName(r.Context(), videoName)
 		if err != nil {
 			slog.Error("Видео не найдено в базе данных",
 				"video_name", videoName,
 				"error", err,
 				"remote_addr", r.RemoteAddr,
 			)
-			http.Error(w, "Video not found in database", http.StatusNotFound)
+			handlers.StorageError(w, err, "Video not found in database")
 			return
 		}
-		filePath := filepath.Join(config.UploadDir, video.FileName)
-		// Проверяем наличие файла на диске
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			slog.Warn("Файл видео отсутствует на диске — удаляем только из БД",
+		if user, _ := auth.UserFrom(r.Context()); video.OwnerID != user.ID {
+			slog.Warn("Попытка удалить чужое видео",
+				"video_id", video.ID,
+				"owner_id", video.OwnerID,
+				"user_id", user.ID,
+				"remote_addr", r.RemoteAddr,
+			)
+			http.Error(w, "You can only delete your own videos", http.StatusForbidden)
+			return
+		}
+		// Исходник запоминаем до удаления записи: вместе с видео база удалит и его задачу
+		var queuedSource string
+		job, err := db.GetLatestJobByVideoID(r.Context(), video.ID)
+		switch {
+		case err == nil && job.Status == database.JobQueued:
+			queuedSource = filepath.Join(config.TemporaryDir, job.FileName)
+		case err != nil && !errors.Is(err, database.ErrNotFound):
+			slog.Error("Не удалось получить задачу транскодирования видео",
+				"video_id", video.ID,
+				"error", err,
+				"remote_addr", r.RemoteAddr,
+			)
+			handlers.StorageError(w, err, "Failed to delete video")
+			return
+		}
+		// Папка HLS хранится под префиксом "<file_name>/", старые загрузки - одним файлом
+		objects, err := backend.List(r.Context(), video.FileName+"/")
+		if err == nil && len(objects) == 0 {
+			if _, statErr := backend.Stat(r.Context(), video.FileName); statErr == nil {
+				objects = append(objects, storage.ObjectInfo{Key: video.FileName})
+			}
+		}
+		if err != nil {
+			slog.Error("Не удалось получить список файлов видео в хранилище",
+				"file_path", video.FileName,
+				"error", err,
+				"remote_addr", r.RemoteAddr,
+			)
+			http.Error(w, "Failed to delete video file", http.StatusInternalServerError)
+			return
+		}
+		// Проверяем наличие файлов в хранилище
+		if len(objects) == 0 {
+			slog.Warn("Файл видео отсутствует в хранилище — удаляем только из БД",
 				"video_name", videoName,
 				"file_path", video.FileName,
 				"remote_addr", r.RemoteAddr,
 			)
 
 			// Удаляем из БД, даже если файла нет
-			if err := db.DeleteVideoByID(video.ID); err != nil {
+			if err := db.DeleteVideoByID(r.Context(), video.ID); err != nil {
 				slog.Error("Не удалось удалить видео из БД (файл уже отсутствует)",
 					"video_id", video.ID,
 					"error", err,
@@ -56,6 +104,7 @@ func Delete(db *database.DB) http.HandlerFunc {
 				http.Error(w, "Failed to clean up database record", http.StatusInternalServerError)
 				return
 			}
+			removeSource(queuedSource)
 
 			// Ответ: файл уже отсутствовал, но запись в БД удалена
 			w.WriteHeader(http.StatusOK)
@@ -66,19 +115,21 @@ func Delete(db *database.DB) http.HandlerFunc {
 			return
 		}
 
-		// Удаляем файл с диска
-		if err := os.RemoveAll(filePath); err != nil {
-			slog.Error("Не удалось удалить файл с диска",
-				"file_path", video.FileName,
-				"error", err,
-				"remote_addr", r.RemoteAddr,
-			)
-			http.Error(w, "Failed to delete video file", http.StatusInternalServerError)
-			return
+		// Удаляем файлы из хранилища
+		for _, obj := range objects {
+			if err := backend.Delete(r.Context(), obj.Key); err != nil {
+				slog.Error("Не удалось удалить файл из хранилища",
+					"file_path", obj.Key,
+					"error", err,
+					"remote_addr", r.RemoteAddr,
+				)
+				http.Error(w, "Failed to delete video file", http.StatusInternalServerError)
+				return
+			}
 		}
 
 		// Удаляем запись из БД
-		if err := db.DeleteVideoByID(video.ID); err != nil {
+		if err := db.DeleteVideoByID(r.Context(), video.ID); err != nil {
 			slog.Error("Не удалось удалить запись из БД после удаления файла",
 				"video_id", video.ID,
 				"file_path", video.FileName,
@@ -91,6 +142,8 @@ func Delete(db *database.DB) http.HandlerFunc {
 			return
 		}
 
+		removeSource(queuedSource)
+
 		// Успешный ответ
 		w.WriteHeader(http.StatusOK)
 		if err := json.NewEncoder(w).Encode(map[string]string{
@@ -101,3 +154,13 @@ func Delete(db *database.DB) http.HandlerFunc {
 		}
 	}
 }
+
+// removeSource удаляет исходник из временной папки, если он задан.
+func removeSource(path string) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("Не удалось удалить исходник удаленного видео", "file_path", path, "error", err)
+	}
+}
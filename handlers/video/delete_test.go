@@ -0,0 +1,83 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"video/auth"
+	"video/database"
+	"video/database/dbtest"
+	"video/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	tempDir(t)
+	db := dbtest.Open(t)
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Подключаем так же, как в video.go
+	router := chi.NewRouter()
+	router.With(auth.RequireUser).Get("/video/delete", Delete(db, backend))
+
+	videoID, err := db.InsertVideo(ctx, "Фильм", "film", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"film/master.m3u8", "film/720p/segment_000.ts"} {
+		if err := backend.Put(ctx, key, strings.NewReader("data"), 4, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	del := func(userID int, query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/video/delete"+query, nil)
+		if userID != 0 {
+			r = r.WithContext(auth.WithUser(r.Context(), auth.User{ID: userID}))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		userID int
+		query  string
+		code   int
+	}{
+		{"аноним", 0, "?file_name=film", http.StatusUnauthorized},
+		{"чужое видео", 2, "?file_name=film", http.StatusForbidden},
+		{"без file_name", 1, "", http.StatusBadRequest},
+		{"несуществующее видео", 1, "?file_name=missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := del(tt.userID, tt.query); w.Code != tt.code {
+			t.Errorf("%s: ответ %d, ожидался %d: %s", tt.name, w.Code, tt.code, w.Body)
+		}
+	}
+	// Отклоненные запросы не трогают ни запись, ни файлы
+	if _, err := db.GetVideoByID(ctx, videoID); err != nil {
+		t.Fatalf("видео после отклоненных запросов: %v", err)
+	}
+	if objects, err := backend.List(ctx, "film/"); err != nil || len(objects) != 2 {
+		t.Fatalf("файлы после отклоненных запросов: %v, %v", objects, err)
+	}
+
+	if w := del(1, "?file_name=film"); w.Code != http.StatusOK {
+		t.Fatalf("владелец: ответ %d: %s", w.Code, w.Body)
+	}
+	if _, err := db.GetVideoByID(ctx, videoID); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("видео после удаления: %v, ожидалось ErrNotFound", err)
+	}
+	if objects, err := backend.List(ctx, "film/"); err != nil || len(objects) != 0 {
+		t.Errorf("файлы после удаления: %v, %v", objects, err)
+	}
+}
@@ -2,15 +2,75 @@ package video
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"video/auth"
 	"video/database"
+	"video/handlers"
 )
-//get?
-func GetAllVideo(database *database.DB) http.HandlerFunc {
+
+// Размер страницы списка видео.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// GET /video/all?status=ready&q=кошки&sort=name&order=asc&limit=20&cursor=...
+// Все параметры необязательные: status фильтрует по статусу обработки,
+// q ищет по словам в названии и описании, sort - name, created или duration
+// (по умолчанию created), order - asc или desc (по умолчанию desc, для name - asc),
+// limit - размер страницы до 100, cursor - next_cursor из предыдущего ответа.
+// В списке только публичные видео и, для вошедшего пользователя, его собственные.
+func GetAllVideo(videoStorage database.VideoStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		videos, err := database.GetAllVideos()
+		query := r.URL.Query()
+		q := database.VideoQuery{
+			Status: query.Get("status"),
+			Search: query.Get("q"),
+			Sort:   query.Get("sort"),
+			Limit:  defaultPageSize,
+			Cursor: query.Get("cursor"),
+		}
+		if user, ok := auth.UserFrom(r.Context()); ok {
+			q.Viewer = user.ID
+		}
+		if q.Status != "" && !database.IsVideoStatus(q.Status) {
+			http.Error(w, fmt.Sprintf("Unknown status: %s", q.Status), http.StatusBadRequest)
+			return
+		}
+		if q.Sort == "" {
+			q.Sort = database.VideoSortCreated
+		}
+		if !database.IsVideoSort(q.Sort) {
+			http.Error(w, fmt.Sprintf("Unknown sort: %s", q.Sort), http.StatusBadRequest)
+			return
+		}
+		switch order := query.Get("order"); order {
+		case "":
+			q.Desc = q.Sort != database.VideoSortName
+		case "asc", "desc":
+			q.Desc = order == "desc"
+		default:
+			http.Error(w, fmt.Sprintf("Unknown order: %s", order), http.StatusBadRequest)
+			return
+		}
+		if limit := query.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 1 || n > maxPageSize {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxPageSize), http.StatusBadRequest)
+				return
+			}
+			q.Limit = n
+		}
+
+		page, err := videoStorage.ListVideos(r.Context(), q)
+		if errors.Is(err, database.ErrInvalidCursor) {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			slog.Error(fmt.Sprintf("Не удалось получить видео"),
 				"удалённый_адрес", r.RemoteAddr,
@@ -18,7 +78,7 @@ func GetAllVideo(database *database.DB) http.HandlerFunc {
 				"путь", r.URL.Path,
 				"ошибка", err.Error(),
 			)
-			http.Error(w, "Videos not get", http.StatusNotFound)
+			handlers.StorageError(w, err, "Videos not found")
 			return
 		}
 
@@ -26,7 +86,7 @@ func GetAllVideo(database *database.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 
-		err = json.NewEncoder(w).Encode(videos)
+		err = json.NewEncoder(w).Encode(page)
 		if err != nil {
 			slog.Error("Ошибка при отправке ответа с ключом комнаты",
 				"error", err,
@@ -0,0 +1,120 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"video/auth"
+	"video/database"
+	"video/database/dbtest"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestGetAllVideo(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	router := chi.NewRouter()
+	router.Get("/video/all", GetAllVideo(db))
+
+	for _, name := range []string{"Бета", "Альфа", "Гамма"} {
+		id, err := db.InsertVideo(ctx, name, name, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "Гамма" {
+			if err := db.UpdateVideoStatus(ctx, id, database.VideoReady, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	private, err := db.InsertVideo(ctx, "Дельта", "delta", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateVideoMetadata(ctx, private, database.VideoMetadata{VideoName: "Дельта", Visibility: database.VisibilityPrivate}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(userID int, query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/video/all?"+query, nil)
+		if userID != 0 {
+			r = r.WithContext(auth.WithUser(r.Context(), auth.User{ID: userID}))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	// page возвращает названия видео страницы, курсор следующей и общее число видео.
+	page := func(userID int, query string) ([]string, string, int) {
+		t.Helper()
+		w := get(userID, query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: ответ %d: %s", query, w.Code, w.Body)
+		}
+		// Ответ - объект, а не массив, как до пагинации
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+			t.Fatalf("%s: ответ не объект: %s", query, w.Body)
+		}
+		if len(raw) != 3 || raw["videos"] == nil || raw["next_cursor"] == nil || raw["total"] == nil {
+			t.Fatalf("%s: поля ответа %s, ожидались videos, next_cursor и total", query, w.Body)
+		}
+		var resp database.VideoPage
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, v := range resp.Videos {
+			names = append(names, v.VideoName)
+		}
+		return names, resp.NextCursor, resp.Total
+	}
+
+	names, cursor, total := page(0, "sort=name&limit=2")
+	if !slices.Equal(names, []string{"Альфа", "Бета"}) || cursor == "" || total != 3 {
+		t.Errorf("первая страница: %v, курсор %q, всего %d", names, cursor, total)
+	}
+	names, cursor, total = page(0, "sort=name&limit=2&cursor="+url.QueryEscape(cursor))
+	if !slices.Equal(names, []string{"Гамма"}) || cursor != "" || total != 3 {
+		t.Errorf("вторая страница: %v, курсор %q, всего %d", names, cursor, total)
+	}
+	if names, _, _ := page(0, "sort=name&order=desc"); !slices.Equal(names, []string{"Гамма", "Бета", "Альфа"}) {
+		t.Errorf("по убыванию названия: %v", names)
+	}
+	if names, _, total := page(0, "status=ready&sort=name"); !slices.Equal(names, []string{"Альфа", "Бета"}) || total != 2 {
+		t.Errorf("только готовые: %v, всего %d", names, total)
+	}
+	// Закрытое видео видит только владелец
+	if names, _, total := page(1, "sort=name"); !slices.Contains(names, "Дельта") || total != 4 {
+		t.Errorf("владелец: %v, всего %d", names, total)
+	}
+	if names, _, _ := page(2, "sort=name"); slices.Contains(names, "Дельта") {
+		t.Errorf("чужое закрытое видео в списке: %v", names)
+	}
+
+	// Курсор выдан для другой сортировки
+	_, nameCursor, _ := page(0, "sort=name&limit=1")
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"неизвестный статус", "status=deleted"},
+		{"неизвестная сортировка", "sort=size"},
+		{"неизвестный порядок", "order=up"},
+		{"limit ноль", "limit=0"},
+		{"limit больше предела", "limit=101"},
+		{"limit не число", "limit=ten"},
+		{"поврежденный курсор", "cursor=garbage"},
+		{"курсор другой сортировки", "sort=duration&cursor=" + url.QueryEscape(nameCursor)},
+	}
+	for _, tt := range tests {
+		if w := get(0, tt.query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: ответ %d, ожидался 400", tt.name, w.Code)
+		}
+	}
+}
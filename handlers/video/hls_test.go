@@ -0,0 +1,251 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"video/auth"
+	"video/database"
+	"video/database/dbtest"
+	"video/playback"
+	"video/storage"
+	"video/streamer"
+	"video/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// segment - содержимое тестового сегмента: 1000 байт, у каждого свое значение по модулю 256.
+var segment = func() []byte {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}()
+
+// hlsServer поднимает HLSHandler с видео "film", у которого в хранилище есть один сегмент.
+// Возвращает маршрутизатор и действующий токен этого видео.
+func hlsServer(t *testing.T) (http.Handler, string) {
+	t.Helper()
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Put(ctx, "film/360p_000.fmp4", bytes.NewReader(segment), int64(len(segment)), "video/iso.segment"); err != nil {
+		t.Fatal(err)
+	}
+	videoID, err := db.InsertVideo(ctx, "Фильм", "film", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := playback.NewSigner([]byte("secret"), time.Hour)
+	token, _ := signer.Issue(videoID, 0)
+	router := chi.NewRouter()
+	router.Get("/video/hls/*", HLSHandler(backend, &streamer.FileStreamer{Storage: backend}, db, db, db, signer))
+	return router, token
+}
+
+// getSegment запрашивает файл name видео с заголовками header.
+func getSegment(handler http.Handler, name, token string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/video/hls/film/"+name+"?token="+token, nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestHLSSegmentRanges(t *testing.T) {
+	handler, token := hlsServer(t)
+
+	full := getSegment(handler, "360p_000.fmp4", token, nil)
+	if full.Code != http.StatusOK || !bytes.Equal(full.Body.Bytes(), segment) {
+		t.Fatalf("сегмент целиком: ответ %d, %d байт", full.Code, full.Body.Len())
+	}
+	etag := full.Header().Get("ETag")
+	if etag == "" || full.Header().Get("Accept-Ranges") != "bytes" || full.Header().Get("Content-Type") != "video/iso.segment" {
+		t.Errorf("заголовки сегмента: %v", full.Header())
+	}
+
+	tests := []struct {
+		name         string
+		header       http.Header
+		code         int
+		body         []byte
+		contentRange string
+	}{
+		{"диапазон", http.Header{"Range": {"bytes=100-199"}}, http.StatusPartialContent, segment[100:200], "bytes 100-199/1000"},
+		{"до конца", http.Header{"Range": {"bytes=900-"}}, http.StatusPartialContent, segment[900:], "bytes 900-999/1000"},
+		{"последние байты", http.Header{"Range": {"bytes=-10"}}, http.StatusPartialContent, segment[990:], "bytes 990-999/1000"},
+		{"за концом файла", http.Header{"Range": {"bytes=1000-"}}, http.StatusRequestedRangeNotSatisfiable, nil, "bytes */1000"},
+		{"If-Range совпадает", http.Header{"Range": {"bytes=0-9"}, "If-Range": {etag}}, http.StatusPartialContent, segment[:10], "bytes 0-9/1000"},
+		{"If-Range устарел", http.Header{"Range": {"bytes=0-9"}, "If-Range": {`"old"`}}, http.StatusOK, segment, ""},
+		{"If-None-Match", http.Header{"If-None-Match": {etag}}, http.StatusNotModified, nil, ""},
+	}
+	for _, tt := range tests {
+		w := getSegment(handler, "360p_000.fmp4", token, tt.header)
+		if w.Code != tt.code {
+			t.Errorf("%s: ответ %d, ожидался %d", tt.name, w.Code, tt.code)
+			continue
+		}
+		if tt.body != nil && !bytes.Equal(w.Body.Bytes(), tt.body) {
+			t.Errorf("%s: тело из %d байт не совпадает с ожидаемым из %d", tt.name, w.Body.Len(), len(tt.body))
+		}
+		if got := w.Header().Get("Content-Range"); got != tt.contentRange {
+			t.Errorf("%s: Content-Range %q, ожидался %q", tt.name, got, tt.contentRange)
+		}
+	}
+}
+
+func TestHLSSegmentMultipartRanges(t *testing.T) {
+	handler, token := hlsServer(t)
+
+	w := getSegment(handler, "360p_000.fmp4", token, http.Header{"Range": {"bytes=0-9,500-509,-5"}})
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("ответ %d, ожидался 206", w.Code)
+	}
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Content-Type %q, ожидался multipart/byteranges", w.Header().Get("Content-Type"))
+	}
+
+	want := []struct {
+		contentRange string
+		body         []byte
+	}{
+		{"bytes 0-9/1000", segment[:10]},
+		{"bytes 500-509/1000", segment[500:510]},
+		{"bytes 995-999/1000", segment[995:]},
+	}
+	reader := multipart.NewReader(w.Body, params["boundary"])
+	for i, part := range want {
+		p, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("часть %d: %v", i, err)
+		}
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Header.Get("Content-Range") != part.contentRange || !bytes.Equal(body, part.body) {
+			t.Errorf("часть %d: Content-Range %q, %d байт; ожидалось %q", i, p.Header.Get("Content-Range"), len(body), part.contentRange)
+		}
+		if p.Header.Get("Content-Type") != "video/iso.segment" {
+			t.Errorf("часть %d: Content-Type %q", i, p.Header.Get("Content-Type"))
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("лишняя часть ответа: %v", err)
+	}
+}
+
+func TestHLSSegmentErrors(t *testing.T) {
+	handler, token := hlsServer(t)
+
+	if w := getSegment(handler, "360p_001.fmp4", token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("отсутствующий сегмент: ответ %d, ожидался 404", w.Code)
+	}
+	if w := getSegment(handler, "main.m3u8", token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("отсутствующий плейлист: ответ %d, ожидался 404", w.Code)
+	}
+	for _, bad := range []string{"", "7.0.1.sig", strings.ToUpper(token)} {
+		if w := getSegment(handler, "360p_000.fmp4", bad, nil); w.Code != http.StatusForbidden {
+			t.Errorf("токен %q: ответ %d, ожидался 403", bad, w.Code)
+		}
+	}
+}
+
+func TestPoster(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := db.InsertVideo(ctx, "Открытое", "public", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := db.InsertVideo(ctx, "Закрытое", "private", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateVideoMetadata(ctx, private, database.VideoMetadata{VideoName: "Закрытое", Visibility: database.VisibilityPrivate}); err != nil {
+		t.Fatal(err)
+	}
+	processing, err := db.InsertVideo(ctx, "Еще обрабатывается", "processing", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, folder := range []string{"public", "private"} {
+		if err := backend.Put(ctx, folder+"/"+utils.PosterFile, bytes.NewReader(segment), int64(len(segment)), "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := chi.NewRouter()
+	poster := Poster(db, &streamer.FileStreamer{Storage: backend})
+	router.Get("/video/{id}/poster", poster)
+	router.Head("/video/{id}/poster", poster)
+	get := func(method string, id, userID int, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, fmt.Sprintf("/video/%d/poster", id), nil)
+		r = r.WithContext(auth.WithUser(r.Context(), auth.User{ID: userID}))
+		for key, values := range header {
+			r.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	full := get(http.MethodGet, public, 0, nil)
+	if full.Code != http.StatusOK || !bytes.Equal(full.Body.Bytes(), segment) {
+		t.Fatalf("обложка: ответ %d, %d байт", full.Code, full.Body.Len())
+	}
+	etag := full.Header().Get("ETag")
+	if etag == "" || full.Header().Get("Last-Modified") == "" || full.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("заголовки обложки: %v", full.Header())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		id     int
+		userID int
+		header http.Header
+		code   int
+		body   []byte
+	}{
+		{"HEAD", http.MethodHead, public, 0, nil, http.StatusOK, []byte{}},
+		{"диапазон", http.MethodGet, public, 0, http.Header{"Range": {"bytes=10-19"}}, http.StatusPartialContent, segment[10:20]},
+		{"If-None-Match", http.MethodGet, public, 0, http.Header{"If-None-Match": {etag}}, http.StatusNotModified, nil},
+		{"свое закрытое видео", http.MethodGet, private, 1, nil, http.StatusOK, segment},
+		{"чужое закрытое видео", http.MethodGet, private, 2, nil, http.StatusNotFound, nil},
+		{"закрытое видео анониму", http.MethodGet, private, 0, nil, http.StatusNotFound, nil},
+		{"обложки еще нет", http.MethodGet, processing, 0, nil, http.StatusNotFound, nil},
+		{"несуществующее видео", http.MethodGet, processing + 100, 0, nil, http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		w := get(tt.method, tt.id, tt.userID, tt.header)
+		if w.Code != tt.code {
+			t.Errorf("%s: ответ %d, ожидался %d", tt.name, w.Code, tt.code)
+			continue
+		}
+		if tt.body != nil && !bytes.Equal(w.Body.Bytes(), tt.body) {
+			t.Errorf("%s: тело из %d байт, ожидалось %d", tt.name, w.Body.Len(), len(tt.body))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package video
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"video/auth"
+	"video/database"
+	"video/handlers"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// videoIDParam извлекает ID видео из параметра маршрута {id}.
+func videoIDParam(r *http.Request) (int, error) {
+	idParam := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("некорректный ID видео: %q", idParam)
+	}
+	return id, nil
+}
+
+// viewableVideo находит видео из параметра {id}, доступное текущему пользователю.
+// При отказе пишет ответ сам.
+func viewableVideo(w http.ResponseWriter, r *http.Request, videoStorage database.VideoStorage) (*database.Video, bool) {
+	videoID, err := videoIDParam(r)
+	if err != nil {
+		http.Error(w, "Invalid video id", http.StatusBadRequest)
+		return nil, false
+	}
+	video, err := videoStorage.GetVideoByID(r.Context(), videoID)
+	if err != nil {
+		handlers.StorageError(w, err, "Video not found")
+		return nil, false
+	}
+	if user, _ := auth.UserFrom(r.Context()); !video.CanView(user.ID) {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return nil, false
+	}
+	return video, true
+}
+
+// ownVideo находит видео из параметра {id}, принадлежащее текущему пользователю.
+// При отказе пишет ответ сам.
+func ownVideo(w http.ResponseWriter, r *http.Request, videoStorage database.VideoStorage) (*database.Video, bool) {
+	video, ok := viewableVideo(w, r, videoStorage)
+	if !ok {
+		return nil, false
+	}
+	if user, _ := auth.UserFrom(r.Context()); video.OwnerID != user.ID {
+		slog.Warn("Попытка изменить чужое видео",
+			"video_id", video.ID,
+			"owner_id", video.OwnerID,
+			"user_id", user.ID,
+			"удалённый_адрес", r.RemoteAddr,
+		)
+		http.Error(w, "You can only edit your own videos", http.StatusForbidden)
+		return nil, false
+	}
+	return video, true
+}
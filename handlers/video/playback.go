@@ -0,0 +1,68 @@
+package video
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"video/database"
+	"video/playback"
+	"video/storage"
+	"video/streamer"
+	"video/utils"
+)
+
+// Playback выдает подписанную ссылку на просмотр видео.
+// Приватное видео доступно только владельцу.
+// GET /video/{id}/playback
+func Playback(videoStorage database.VideoStorage, signer *playback.Signer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		video, ok := viewableVideo(w, r, videoStorage)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(signer.Link(video.ID, video.FileName, 0)); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// Poster отдает обложку видео. Адрес постоянный и без токена, поэтому подходит
+// для <img src> в списке видео. Файл читается из хранилища по частям через files,
+// Range, If-Range, ETag и Last-Modified обрабатывает http.ServeContent.
+// GET, HEAD /video/{id}/poster
+func Poster(videoStorage database.VideoStorage, files streamer.Streamer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		video, ok := viewableVideo(w, r, videoStorage)
+		if !ok {
+			return
+		}
+
+		key := video.FileName + "/" + utils.PosterFile
+		stream, err := files.Open(r.Context(), key)
+		if errors.Is(err, storage.ErrNotExist) {
+			// Обложка появляется только после обработки видео
+			http.Error(w, "Poster not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			slog.Error("Ошибка при открытии обложки",
+				"video_id", video.ID,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			http.Error(w, "Error retrieving poster", http.StatusInternalServerError)
+			return
+		}
+		defer stream.Close()
+
+		// Видимость видео могут поменять, поэтому общие кэши обложку не хранят,
+		// а браузер перепроверяет ее по ETag
+		w.Header().Set("Cache-Control", "private, no-cache")
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("ETag", stream.ETag)
+		http.ServeContent(w, r, utils.PosterFile, stream.ModTime, stream)
+	}
+}
@@ -0,0 +1,69 @@
+package video
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"video/database"
+	"video/handlers"
+)
+
+// Длина отрезка шкалы реакций.
+const (
+	defaultReactionBucket = 10 * time.Second
+	maxReactionBucket     = time.Hour
+)
+
+type reactionsResponse struct {
+	VideoID  int                       `json:"video_id"`
+	Bucket   int                       `json:"bucket"` // Длина отрезка, секунды
+	Timeline []database.ReactionBucket `json:"timeline"`
+}
+
+// Reactions возвращает шкалу реакций на видео, поставленных во всех комнатах:
+// сколько каких эмодзи пришлось на каждый отрезок видео. Отрезки без реакций пропускаются.
+// GET /video/{id}/reactions?bucket=10s (длина отрезка: "30s", "1m" или число секунд)
+func Reactions(videoStorage database.VideoStorage, reactionStorage database.ReactionStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		video, ok := viewableVideo(w, r, videoStorage)
+		if !ok {
+			return
+		}
+
+		bucket := defaultReactionBucket
+		if value := r.URL.Query().Get("bucket"); value != "" {
+			var err error
+			if seconds, convErr := strconv.Atoi(value); convErr == nil {
+				bucket = time.Duration(seconds) * time.Second
+			} else {
+				bucket, err = time.ParseDuration(value)
+			}
+			if err != nil || bucket < time.Second || bucket > maxReactionBucket || bucket%time.Second != 0 {
+				http.Error(w, "bucket must be a whole number of seconds between 1s and 1h, e.g. 10s", http.StatusBadRequest)
+				return
+			}
+		}
+		seconds := int(bucket / time.Second)
+
+		timeline, err := reactionStorage.GetReactionTimeline(r.Context(), video.ID, seconds)
+		if err != nil {
+			slog.Error("Не удалось получить шкалу реакций",
+				"video_id", video.ID,
+				"ошибка", err,
+			)
+			handlers.StorageError(w, err, "")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(reactionsResponse{
+			VideoID:  video.ID,
+			Bucket:   seconds,
+			Timeline: timeline,
+		}); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
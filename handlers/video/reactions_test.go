@@ -0,0 +1,135 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"video/auth"
+	"video/database"
+	"video/database/dbtest"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestReactions(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	router := chi.NewRouter()
+	router.Get("/video/{id}/reactions", Reactions(db, db))
+
+	videoID, err := db.InsertVideo(ctx, "Фильм", "film", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateID, err := db.InsertVideo(ctx, "Закрытое", "private", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateVideoMetadata(ctx, privateID, database.VideoMetadata{VideoName: "Закрытое", Visibility: database.VisibilityPrivate}); err != nil {
+		t.Fatal(err)
+	}
+	room, err := db.InsertRoom(ctx, "REACT1", videoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, err := db.InsertMember(ctx, room.ID, "Анна", "member-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []struct {
+		emoji    string
+		position float64
+	}{{"👍", 3}, {"👍", 5.5}, {"🎉", 9.9}, {"🎉", 12}, {"👍", 75}} {
+		err := db.InsertReaction(ctx, database.Reaction{VideoID: videoID, RoomID: room.ID, MemberID: member.ID, Emoji: r.emoji, Position: r.position})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(id, userID int, query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/video/%d/reactions%s", id, query), nil)
+		r = r.WithContext(auth.WithUser(r.Context(), auth.User{ID: userID}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string // Ожидаемый JSON ответа
+	}{
+		{"по умолчанию 10 секунд", "", fmt.Sprintf(`{"video_id": %d, "bucket": 10, "timeline": [
+			{"start": 0, "end": 10, "total": 3, "reactions": {"👍": 2, "🎉": 1}},
+			{"start": 10, "end": 20, "total": 1, "reactions": {"🎉": 1}},
+			{"start": 70, "end": 80, "total": 1, "reactions": {"👍": 1}}]}`, videoID)},
+		{"число секунд", "?bucket=10", fmt.Sprintf(`{"video_id": %d, "bucket": 10, "timeline": [
+			{"start": 0, "end": 10, "total": 3, "reactions": {"👍": 2, "🎉": 1}},
+			{"start": 10, "end": 20, "total": 1, "reactions": {"🎉": 1}},
+			{"start": 70, "end": 80, "total": 1, "reactions": {"👍": 1}}]}`, videoID)},
+		{"длительность Go", "?bucket=1m", fmt.Sprintf(`{"video_id": %d, "bucket": 60, "timeline": [
+			{"start": 0, "end": 60, "total": 4, "reactions": {"👍": 2, "🎉": 2}},
+			{"start": 60, "end": 120, "total": 1, "reactions": {"👍": 1}}]}`, videoID)},
+		{"ровно час", "?bucket=1h", fmt.Sprintf(`{"video_id": %d, "bucket": 3600, "timeline": [
+			{"start": 0, "end": 3600, "total": 5, "reactions": {"👍": 3, "🎉": 2}}]}`, videoID)},
+	}
+	for _, tt := range tests {
+		w := get(videoID, 0, tt.query)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: ответ %d: %s", tt.name, w.Code, w.Body)
+			continue
+		}
+		var got, want any
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\n%s\nожидалось\n%s", tt.name, w.Body, tt.want)
+		}
+	}
+
+	// Видео без реакций отдает пустую шкалу, а не null
+	if w := get(privateID, 1, ""); w.Code != http.StatusOK || !reflect.DeepEqual(decodeTimeline(t, w), []any{}) {
+		t.Errorf("свое видео без реакций: ответ %d: %s", w.Code, w.Body)
+	}
+
+	rejected := []struct {
+		name   string
+		id     int
+		userID int
+		query  string
+		code   int
+	}{
+		{"дробные секунды", videoID, 0, "?bucket=1500ms", http.StatusBadRequest},
+		{"меньше секунды", videoID, 0, "?bucket=500ms", http.StatusBadRequest},
+		{"ноль", videoID, 0, "?bucket=0", http.StatusBadRequest},
+		{"отрицательное", videoID, 0, "?bucket=-10", http.StatusBadRequest},
+		{"больше часа", videoID, 0, "?bucket=3601", http.StatusBadRequest},
+		{"не длительность", videoID, 0, "?bucket=ten", http.StatusBadRequest},
+		{"чужое закрытое видео", privateID, 2, "", http.StatusNotFound},
+		{"закрытое видео анониму", privateID, 0, "", http.StatusNotFound},
+		{"несуществующее видео", privateID + 100, 0, "", http.StatusNotFound},
+	}
+	for _, tt := range rejected {
+		if w := get(tt.id, tt.userID, tt.query); w.Code != tt.code {
+			t.Errorf("%s: ответ %d, ожидался %d", tt.name, w.Code, tt.code)
+		}
+	}
+}
+
+// decodeTimeline возвращает поле timeline ответа Reactions.
+func decodeTimeline(t *testing.T, w *httptest.ResponseRecorder) any {
+	t.Helper()
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp["timeline"]
+}
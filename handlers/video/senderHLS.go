@@ -2,19 +2,30 @@ package video
 
 import (
 	// "fmt"
+	"context"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
-	"video/config"
+	"time"
+	"video/database"
+	"video/handlers"
+	"video/playback"
+	"video/storage"
+	"video/streamer"
+	"video/utils"
 
 	"github.com/go-chi/chi/v5"
 )
 
-// HLSHandler обслуживает HLS-файлы (манифесты .m3u8 и сегменты .ts).
-// Ожидаемый формат URL: /hls/{video_id}/{filename.m3u8_or_ts}
-func HLSHandler() http.HandlerFunc {
+// HLSHandler обслуживает HLS-файлы (манифесты .m3u8 и сегменты .ts),
+// а также обложку poster.jpg, спрайты превью и их дорожку thumbnails.vtt.
+// Ожидаемый формат URL: /hls/{video_id}/{filename.m3u8_or_ts}?token=...
+// Плейлисты читаются из хранилища backend целиком, остальные файлы отдаются
+// через files по частям. Токен выдает Playback.
+func HLSHandler(backend storage.Backend, files streamer.Streamer, videoStorage database.VideoStorage, roomStorage database.RoomStorage, subtitleStorage database.SubtitleStorage, signer *playback.Signer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Отрезаем префикс "/hls/"
 		relativePath := chi.URLParam(r, "*")
@@ -38,39 +49,13 @@ func HLSHandler() http.HandlerFunc {
 			http.Error(w, "Invalid HLS path", http.StatusBadRequest)
 			return
 		}
-		// videoID := parts[0] // Первый элемент - это video_id
+		folder := parts[0]                       // Первый элемент - папка видео, она же его file_name
 		fileName := strings.Join(parts[1:], "/") // Остальное - это имя файла и его подпуть
 
-		// fmt.Println("Debug: videoID:", videoID, "fileName:", fileName)
-
-		// filepath.Join автоматически обработает config.UploadDir/videoID/fileName
-		filePath := filepath.Join(config.UploadDir, relativePath)
-		// fmt.Println("Debug: filePath:", filePath)
-
-		fileInfo, err := os.Stat(filePath)
-		if os.IsNotExist(err) {
-			slog.Warn("HLS файл не найден",
-				"полный_путь_файла", filePath,
-				"удалённый_адрес", r.RemoteAddr,
-			)
-			http.Error(w, "HLS file not found", http.StatusNotFound)
-			return
-		}
-		if err != nil {
-			slog.Error("Ошибка при доступе к HLS файлу",
-				"полный_путь_файла", filePath,
-				"ошибка", err,
-				"удалённый_адрес", r.RemoteAddr,
-			)
-			http.Error(w, "Error accessing HLS file", http.StatusInternalServerError)
-			return
-		}
-		if fileInfo.IsDir() {
-			slog.Warn("Запрошенный HLS путь является директорией, а не файлом",
-				"полный_путь_файла", filePath,
-				"удалённый_адрес", r.RemoteAddr,
-			)
-			http.Error(w, "Not a file", http.StatusForbidden)
+		// Каждый файл отдается только по действующему токену этого видео
+		token := r.URL.Query().Get(playback.TokenParam)
+		video, ok := authorizePlayback(w, r, videoStorage, roomStorage, signer, folder, token)
+		if !ok {
 			return
 		}
 
@@ -79,6 +64,10 @@ func HLSHandler() http.HandlerFunc {
 			w.Header().Set("Content-Type", "application/x-mpegURL")
 		} else if strings.HasSuffix(fileName, ".fmp4") {
 			w.Header().Set("Content-Type", "video/iso.segment")
+		} else if strings.HasSuffix(fileName, ".jpg") {
+			w.Header().Set("Content-Type", "image/jpeg")
+		} else if strings.HasSuffix(fileName, ".vtt") {
+			w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
 		} else {
 			slog.Warn("Неизвестное расширение файла HLS, Content-Type не установлен явно",
 				"путь_файла", relativePath, // Здесь лучше использовать relativePath
@@ -86,7 +75,40 @@ func HLSHandler() http.HandlerFunc {
 			)
 		}
 
-		http.ServeFile(w, r, filePath)
+		// В плейлисты дописываем токен, иначе плеер запросит варианты и сегменты без него
+		// Субтитры можно добавить после кодирования, поэтому в мастер-плейлист они попадают при отдаче
+		if fileName == "main.m3u8" {
+			subtitles, err := subtitleStorage.GetSubtitles(r.Context(), video.ID)
+			if err != nil {
+				handlers.StorageError(w, err, "")
+				return
+			}
+			servePlaylist(w, r, backend, relativePath, token, func(master []byte, token string) []byte {
+				return playback.RewritePlaylist(playback.AddSubtitles(master, subtitles), token)
+			})
+			return
+		}
+		if strings.HasSuffix(fileName, ".m3u8") {
+			servePlaylist(w, r, backend, relativePath, token, playback.RewritePlaylist)
+			return
+		}
+		// То же для дорожки превью: спрайты без токена не загрузятся
+		if fileName == utils.ThumbnailsFile {
+			servePlaylist(w, r, backend, relativePath, token, playback.RewriteThumbnails)
+			return
+		}
+
+		// Range, multipart/byteranges, If-Range, If-None-Match и If-Modified-Since
+		// обрабатывает http.ServeContent, читая только запрошенные байты
+		stream, err := files.Open(r.Context(), relativePath)
+		if err != nil {
+			hlsFileError(w, r, relativePath, err)
+			return
+		}
+		defer stream.Close()
+
+		w.Header().Set("ETag", stream.ETag)
+		http.ServeContent(w, r, fileName, stream.ModTime, stream)
 
 		slog.Info("HLS файл успешно отдан",
 			"файл", relativePath,
@@ -94,3 +116,96 @@ func HLSHandler() http.HandlerFunc {
 		)
 	}
 }
+
+// authorizePlayback проверяет, что token подписан для видео из папки folder
+// и не истек, а комната из токена еще существует. Возвращает видео из папки.
+// При отказе пишет ответ сам.
+func authorizePlayback(w http.ResponseWriter, r *http.Request, videoStorage database.VideoStorage, roomStorage database.RoomStorage, signer *playback.Signer, folder, token string) (*database.Video, bool) {
+	claims, err := signer.Verify(token, time.Now())
+	if err != nil {
+		slog.Warn("Отклонен запрос HLS без действующего токена",
+			"папка", folder,
+			"ошибка", err,
+			"удалённый_адрес", r.RemoteAddr,
+		)
+		http.Error(w, "Invalid or expired playback token", http.StatusForbidden)
+		return nil, false
+	}
+
+	video, err := videoStorage.GetVideoByFileName(r.Context(), folder)
+	if err != nil {
+		handlers.StorageError(w, err, "HLS file not found")
+		return nil, false
+	}
+	if video.ID != claims.VideoID {
+		slog.Warn("Токен просмотра выдан для другого видео",
+			"video_id", video.ID,
+			"token_video_id", claims.VideoID,
+			"удалённый_адрес", r.RemoteAddr,
+		)
+		http.Error(w, "Invalid or expired playback token", http.StatusForbidden)
+		return nil, false
+	}
+	// Ссылки комнаты перестают работать, когда комнату закрыли
+	if claims.RoomID != 0 {
+		_, err := roomStorage.GetRoomByID(r.Context(), claims.RoomID)
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "Room is closed", http.StatusForbidden)
+			return nil, false
+		}
+		if err != nil {
+			handlers.StorageError(w, err, "")
+			return nil, false
+		}
+	}
+	return video, true
+}
+
+// servePlaylist отдает плейлист key, дописав rewrite токен во все его ссылки.
+// Content-Type выставляет вызывающий.
+func servePlaylist(w http.ResponseWriter, r *http.Request, backend storage.Backend, key string, token string, rewrite func([]byte, string) []byte) {
+	playlist, err := readObject(r.Context(), backend, key)
+	if err != nil {
+		hlsFileError(w, r, key, err)
+		return
+	}
+
+	// В плейлисте токен этого запроса, а плейлист типа event еще и дописывается во время
+	// кодирования. Поэтому его нельзя кэшировать и нельзя отвечать 304 по Last-Modified
+	// файла: клиент остался бы с плейлистом, токен которого уже истек
+	body := rewrite(playlist, token)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// hlsFileError отвечает на ошибку открытия файла key: 404, если его нет в хранилище, иначе 500.
+func hlsFileError(w http.ResponseWriter, r *http.Request, key string, err error) {
+	if errors.Is(err, storage.ErrNotExist) {
+		slog.Warn("HLS файл не найден",
+			"файл", key,
+			"удалённый_адрес", r.RemoteAddr,
+		)
+		http.Error(w, "HLS file not found", http.StatusNotFound)
+		return
+	}
+	slog.Error("Ошибка при доступе к HLS файлу",
+		"файл", key,
+		"ошибка", err,
+		"удалённый_адрес", r.RemoteAddr,
+	)
+	http.Error(w, "Error accessing HLS file", http.StatusInternalServerError)
+}
+
+// readObject читает объект хранилища целиком. Подходит только для небольших файлов.
+func readObject(ctx context.Context, backend storage.Backend, key string) ([]byte, error) {
+	file, err := backend.Get(ctx, key, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
@@ -0,0 +1,140 @@
+package video
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"video/database"
+	"video/handlers"
+	"video/jobs"
+	"video/utils"
+)
+
+// keepAlivePeriod - как часто SSE-поток шлет комментарий, чтобы прокси не рвали соединение.
+const keepAlivePeriod = 15 * time.Second
+
+type statusResponse struct {
+	VideoID  int             `json:"video_id"`
+	Job      *database.Job   `json:"job"`
+	Progress *utils.Progress `json:"progress"` // null, если видео сейчас не кодируется
+}
+
+// Status возвращает статус обработки видео и прогресс транскодирования.
+// Чужое приватное видео выглядит так же, как несуществующее.
+// GET /video/{id}/status
+func Status(videoStorage database.VideoStorage, jobStorage database.JobStorage, pool *jobs.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		video, ok := viewableVideo(w, r, videoStorage)
+		if !ok {
+			return
+		}
+		videoID := video.ID
+
+		job, err := jobStorage.GetLatestJobByVideoID(r.Context(), videoID)
+		if err != nil {
+			slog.Warn("Задача обработки видео не найдена",
+				"video_id", videoID,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			handlers.StorageError(w, err, "Video job not found")
+			return
+		}
+
+		response := statusResponse{VideoID: videoID, Job: job}
+		if progress, ok := pool.Progress(videoID); ok {
+			response.Progress = &progress
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// Events отдает прогресс транскодирования видео как Server-Sent Events.
+// События "progress" приходят по мере кодирования, событие "status"
+// с итоговой задачей приходит в конце, после чего поток закрывается.
+// GET /video/{id}/events
+func Events(videoStorage database.VideoStorage, jobStorage database.JobStorage, pool *jobs.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		video, ok := viewableVideo(w, r, videoStorage)
+		if !ok {
+			return
+		}
+		videoID := video.ID
+
+		// Подписываемся до чтения статуса, чтобы не пропустить завершение задачи
+		updates, cancel := pool.Subscribe(videoID)
+		defer cancel()
+
+		job, err := jobStorage.GetLatestJobByVideoID(r.Context(), videoID)
+		if err != nil {
+			slog.Warn("Задача обработки видео не найдена",
+				"video_id", videoID,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			handlers.StorageError(w, err, "Video job not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		rc := http.NewResponseController(w)
+		// Клиент узнает, что поток открыт, не дожидаясь первого события
+		rc.Flush()
+
+		if job.Status == database.JobSucceeded || job.Status == database.JobFailed {
+			writeEvent(w, rc, "status", job)
+			return
+		}
+
+		ticker := time.NewTicker(keepAlivePeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case progress, ok := <-updates:
+				if !ok {
+					job, err := jobStorage.GetLatestJobByVideoID(r.Context(), videoID)
+					if err != nil {
+						slog.Error("Не удалось получить итоговый статус задачи",
+							"video_id", videoID,
+							"ошибка", err,
+						)
+						return
+					}
+					writeEvent(w, rc, "status", job)
+					return
+				}
+				if err := writeEvent(w, rc, "progress", progress); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+				rc.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// writeEvent записывает одно SSE-событие с JSON-данными и сразу отправляет его клиенту.
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
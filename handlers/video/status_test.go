@@ -0,0 +1,211 @@
+package video
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"video/config"
+	"video/database"
+	"video/database/dbtest"
+	"video/jobs"
+	"video/storage"
+	"video/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeFFmpegEnv заставляет тестовый бинарник вести себя как ffprobe и ffmpeg:
+// ffprobe описывает видео 640x360 на 10 секунд без звука,
+// а ffmpeg сообщает о прогрессе кодирования и ничего не создает.
+const fakeFFmpegEnv = "VIDEO_TEST_FAKE_FFMPEG"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeFFmpegEnv) != "" {
+		if slices.Contains(os.Args, "-show_streams") {
+			fmt.Print(`{"streams": [{"codec_type": "video", "width": 640, "height": 360, "avg_frame_rate": "25/1"}],
+				"format": {"duration": "10"}}`)
+		} else if slices.Contains(os.Args, "pipe:1") {
+			fmt.Print("out_time_us=N/A\nprogress=continue\nout_time_us=5000000\nspeed=2x\nprogress=continue\nprogress=end\n")
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// sseEvent - событие из потока /video/{id}/events.
+type sseEvent struct {
+	name string
+	data string
+}
+
+// eventsServer поднимает обработчик Events поверх пула, который еще не запущен.
+func eventsServer(t *testing.T) (*httptest.Server, *database.DB, *jobs.Pool) {
+	t.Helper()
+	tempDir(t)
+	db := dbtest.Open(t)
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldFFmpeg, oldFFprobe := utils.FFmpegPath, utils.FFprobePath
+	utils.FFmpegPath, utils.FFprobePath = os.Args[0], os.Args[0]
+	t.Setenv(fakeFFmpegEnv, "1")
+	t.Cleanup(func() { utils.FFmpegPath, utils.FFprobePath = oldFFmpeg, oldFFprobe })
+
+	pool := jobs.NewPool(db, db, db, backend, config.Default())
+	router := chi.NewRouter()
+	router.Get("/video/{id}/events", Events(db, db, pool))
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv, db, pool
+}
+
+// addVideo создает видео пользователя 1 с исходником и ставит его в очередь.
+func addVideo(t *testing.T, db *database.DB, pool *jobs.Pool, name string) int {
+	t.Helper()
+	ctx := context.Background()
+	if err := os.WriteFile(filepath.Join(config.TemporaryDir, name+".mp4"), []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	videoID, err := db.InsertVideo(ctx, name, name+".mp4", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Enqueue(ctx, videoID, name+".mp4", ""); err != nil {
+		t.Fatal(err)
+	}
+	return videoID
+}
+
+// openEvents подключается к потоку событий видео videoID.
+func openEvents(t *testing.T, srv *httptest.Server, videoID int) *http.Response {
+	t.Helper()
+	resp, err := http.Get(fmt.Sprintf("%s/video/%d/events", srv.URL, videoID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// readEvents читает события до конца потока.
+func readEvents(t *testing.T, resp *http.Response) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current.name != "" {
+				events = append(events, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestEvents(t *testing.T) {
+	srv, db, pool := eventsServer(t)
+	videoID := addVideo(t, db, pool, "film")
+
+	resp := openEvents(t, srv, videoID)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("ответ %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	// Заголовки приходят после подписки, поэтому пул можно запускать: прогресс не потеряется
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := pool.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		waitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		pool.Wait(waitCtx)
+	})
+
+	events := readEvents(t, resp)
+	if len(events) < 2 {
+		t.Fatalf("события = %+v, ожидались прогресс и итоговый статус", events)
+	}
+	var last utils.Progress
+	for _, e := range events[:len(events)-1] {
+		if e.name != "progress" {
+			t.Fatalf("событие %q до итогового статуса", e.name)
+		}
+		var progress utils.Progress
+		if err := json.Unmarshal([]byte(e.data), &progress); err != nil {
+			t.Fatal(err)
+		}
+		if progress.Quality != "360p" || progress.Percent < last.Percent {
+			t.Errorf("прогресс = %+v после %+v", progress, last)
+		}
+		last = progress
+	}
+	if last.Percent != 100 || last.OutTime != 5 || last.Speed != 2 {
+		t.Errorf("последний прогресс = %+v, ожидалось 100%% на 5 секундах", last)
+	}
+
+	status := events[len(events)-1]
+	var job database.Job
+	if err := json.Unmarshal([]byte(status.data), &job); err != nil {
+		t.Fatal(err)
+	}
+	if status.name != "status" || job.VideoID != videoID || job.Status != database.JobSucceeded {
+		t.Errorf("итоговое событие %q = %+v, ожидалась успешная задача", status.name, job)
+	}
+}
+
+func TestEventsFinishedJob(t *testing.T) {
+	srv, db, pool := eventsServer(t)
+	videoID := addVideo(t, db, pool, "film")
+	ctx := context.Background()
+	job, err := db.ClaimNextJob(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FinishJob(ctx, job.ID, database.JobFailed, "ffmpeg упал"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Поток завершенной задачи сразу отдает статус и закрывается, пул для этого не нужен
+	events := readEvents(t, openEvents(t, srv, videoID))
+	if len(events) != 1 || events[0].name != "status" || !strings.Contains(events[0].data, "ffmpeg упал") {
+		t.Errorf("события = %+v, ожидался один статус с ошибкой", events)
+	}
+}
+
+func TestEventsPrivateVideo(t *testing.T) {
+	srv, db, pool := eventsServer(t)
+	videoID := addVideo(t, db, pool, "film")
+	meta := database.VideoMetadata{VideoName: "film", Visibility: database.VisibilityPrivate}
+	if err := db.UpdateVideoMetadata(context.Background(), videoID, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := openEvents(t, srv, videoID); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("события чужого приватного видео: ответ %d, ожидался 404", resp.StatusCode)
+	}
+	if resp := openEvents(t, srv, videoID+1); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("события несуществующего видео: ответ %d, ожидался 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,232 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"video/config"
+	"video/database"
+	"video/handlers"
+	"video/storage"
+	"video/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxSubtitleSize - предельный размер файла субтитров. Даже у длинного фильма это сотни килобайт.
+const maxSubtitleSize = 5 << 20
+
+// subtitleExtensions - форматы, которые принимает UploadSubtitle.
+var subtitleExtensions = []string{".srt", ".ass", ".ssa", ".vtt"}
+
+// languageTag - код языка по BCP 47 в упрощенном виде: "en", "rus", "pt-BR".
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// Subtitles возвращает дорожки субтитров видео.
+// GET /video/{id}/subtitles
+func Subtitles(videoStorage database.VideoStorage, subtitleStorage database.SubtitleStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		video, ok := viewableVideo(w, r, videoStorage)
+		if !ok {
+			return
+		}
+
+		subtitles, err := subtitleStorage.GetSubtitles(r.Context(), video.ID)
+		if err != nil {
+			slog.Error("Не удалось получить субтитры видео",
+				"video_id", video.ID,
+				"ошибка", err,
+			)
+			handlers.StorageError(w, err, "")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(subtitles); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// UploadSubtitle добавляет к обработанному видео субтитры из файла SRT, ASS или WebVTT.
+// Файл переводится в WebVTT, нарезается на сегменты и появляется в мастер-плейлисте.
+// POST /video/{id}/subtitles, multipart-форма: file, необязательные language и name.
+// Добавлять субтитры можно только к своему видео.
+func UploadSubtitle(videoStorage database.VideoStorage, subtitleStorage database.SubtitleStorage, backend storage.Backend, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		video, ok := ownVideo(w, r, videoStorage)
+		if !ok {
+			return
+		}
+		if video.Status != database.VideoReady {
+			http.Error(w, "Video is not processed yet", http.StatusConflict)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxSubtitleSize+1<<10)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing subtitle file in form field 'file' or file is too large", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		ext := strings.ToLower(filepath.Ext(header.Filename))
+		if !slices.Contains(subtitleExtensions, ext) {
+			http.Error(w, fmt.Sprintf("Unsupported subtitle format %q, expected one of %s", ext, strings.Join(subtitleExtensions, ", ")), http.StatusBadRequest)
+			return
+		}
+		language := strings.TrimSpace(r.FormValue("language"))
+		if language != "" && !languageTag.MatchString(language) {
+			http.Error(w, fmt.Sprintf("Invalid language code: %s", language), http.StatusBadRequest)
+			return
+		}
+		name := strings.TrimSpace(r.FormValue("name"))
+		if name == "" {
+			name = language
+		}
+		if name == "" {
+			name = strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename))
+		}
+		if utf8.RuneCountInString(name) > maxTitleLength {
+			http.Error(w, fmt.Sprintf("name must be at most %d characters", maxTitleLength), http.StatusBadRequest)
+			return
+		}
+
+		vtt, err := convertSubtitle(file, ext)
+		if err != nil {
+			slog.Warn("Не удалось перевести субтитры в WebVTT",
+				"video_id", video.ID,
+				"файл", header.Filename,
+				"ошибка", err,
+			)
+			http.Error(w, "Unsupported or broken subtitle file", http.StatusBadRequest)
+			return
+		}
+
+		subtitle, err := subtitleStorage.InsertSubtitle(r.Context(), video.ID, language, name, database.SubtitleUploaded, false)
+		if err != nil {
+			slog.Error("Не удалось сохранить субтитры",
+				"video_id", video.ID,
+				"ошибка", err,
+			)
+			handlers.StorageError(w, err, "")
+			return
+		}
+
+		profile, _ := cfg.Profile("")
+		if err := storeSubtitle(r.Context(), backend, video, subtitle.ID, vtt, profile.SegmentDuration); err != nil {
+			slog.Error("Не удалось сохранить субтитры в хранилище",
+				"video_id", video.ID,
+				"subtitle_id", subtitle.ID,
+				"ошибка", err,
+			)
+			if err := subtitleStorage.DeleteSubtitle(context.WithoutCancel(r.Context()), subtitle.ID); err != nil {
+				slog.Error("Не удалось удалить запись о субтитрах", "subtitle_id", subtitle.ID, "ошибка", err)
+			}
+			http.Error(w, "Failed to save subtitles", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("Субтитры добавлены",
+			"video_id", video.ID,
+			"subtitle_id", subtitle.ID,
+			"language", language,
+		)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(subtitle); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// DeleteSubtitle удаляет дорожку субтитров видео вместе с ее файлами.
+// DELETE /video/{id}/subtitles/{subtitleID}
+func DeleteSubtitle(videoStorage database.VideoStorage, subtitleStorage database.SubtitleStorage, backend storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		video, ok := ownVideo(w, r, videoStorage)
+		if !ok {
+			return
+		}
+		subtitleID, err := strconv.Atoi(chi.URLParam(r, "subtitleID"))
+		if err != nil || subtitleID <= 0 {
+			http.Error(w, "Invalid subtitle id", http.StatusBadRequest)
+			return
+		}
+
+		subtitle, err := subtitleStorage.GetSubtitleByID(r.Context(), subtitleID)
+		if err == nil && subtitle.VideoID != video.ID {
+			err = database.ErrNotFound
+		}
+		if err != nil {
+			handlers.StorageError(w, err, "Subtitles not found")
+			return
+		}
+
+		// Сначала убираем дорожку из мастер-плейлиста, потом ее файлы
+		if err := subtitleStorage.DeleteSubtitle(r.Context(), subtitle.ID); err != nil {
+			handlers.StorageError(w, err, "Subtitles not found")
+			return
+		}
+		base := path.Join(video.FileName, utils.SubtitleBaseName(subtitle.ID))
+		err = backend.Delete(r.Context(), base+".m3u8")
+		if err == nil || errors.Is(err, storage.ErrNotExist) {
+			_, err = storage.DeletePrefix(r.Context(), backend, base+"_")
+		}
+		if err != nil {
+			slog.Error("Не удалось удалить файлы субтитров из хранилища",
+				"video_id", video.ID,
+				"subtitle_id", subtitle.ID,
+				"ошибка", err,
+			)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// convertSubtitle сохраняет загруженный файл во временную папку и переводит его в WebVTT.
+func convertSubtitle(file io.Reader, ext string) ([]byte, error) {
+	tmp, err := os.CreateTemp(config.TemporaryDir, "subtitle-*"+ext)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, file)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return utils.ConvertToWebVTT(tmp.Name(), 0)
+}
+
+// storeSubtitle нарезает WebVTT на сегменты и загружает их в папку HLS видео.
+func storeSubtitle(ctx context.Context, backend storage.Backend, video *database.Video, subtitleID int, vtt []byte, segmentDuration int) error {
+	dir, err := os.MkdirTemp(config.TemporaryDir, "subtitle-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := utils.WriteSubtitlePlaylist(dir, utils.SubtitleBaseName(subtitleID), vtt, video.Duration, segmentDuration); err != nil {
+		return err
+	}
+	return storage.PutDir(ctx, backend, dir, video.FileName)
+}
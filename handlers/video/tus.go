@@ -0,0 +1,407 @@
+package video
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"video/auth"
+	"video/config"
+	"video/database"
+	"video/handlers"
+	"video/jobs"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,termination,expiration"
+	tusContentType = "application/offset+octet-stream"
+	// cleanupPeriod - как часто удаляются брошенные загрузки.
+	cleanupPeriod = time.Hour
+)
+
+// Tus обслуживает возобновляемые загрузки по протоколу tus 1.0
+// (https://tus.io/protocols/resumable-upload) с расширениями creation,
+// termination и expiration. Части файла копятся в config.TemporaryDir,
+// а собранный файл уходит в тот же конвейер, что и обычная загрузка.
+//
+// Метаданные загрузки (Upload-Metadata): filename - обязательное имя файла,
+// profile - необязательное имя профиля кодирования.
+//
+// Все запросы, кроме OPTIONS, выполняются от имени вошедшего пользователя,
+// и продолжить или отменить загрузку может только тот, кто ее начал.
+type Tus struct {
+	videos  database.VideoStorage
+	uploads database.UploadStorage
+	pool    *jobs.Pool
+	maxSize int64
+	expiry  time.Duration
+
+	mu     sync.Mutex
+	locked map[string]bool // Загрузки, в которые сейчас идет запись
+}
+
+// NewTus создает обработчик tus-загрузок с ограничениями из cfg.
+func NewTus(videoStorage database.VideoStorage, uploadStorage database.UploadStorage, pool *jobs.Pool, cfg *config.Config) *Tus {
+	return &Tus{
+		videos:  videoStorage,
+		uploads: uploadStorage,
+		pool:    pool,
+		maxSize: cfg.MaxUploadSize,
+		expiry:  cfg.UploadExpiry,
+		locked:  make(map[string]bool),
+	}
+}
+
+// Middleware проверяет версию протокола и добавляет обязательные tus-заголовки.
+func (t *Tus) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		// OPTIONS - запрос возможностей сервера, версию в нем клиент не обязан присылать
+		if r.Method != http.MethodOptions && r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			http.Error(w, "Unsupported tus version", http.StatusPreconditionFailed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Options сообщает поддерживаемые версию и расширения протокола.
+// OPTIONS /video/tus/
+func (t *Tus) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(t.maxSize, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Create создает новую загрузку.
+// POST /video/tus/, заголовки Upload-Length и Upload-Metadata
+func (t *Tus) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		http.Error(w, "Upload-Defer-Length is not supported", http.StatusBadRequest)
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		http.Error(w, "Invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if length > t.maxSize {
+		http.Error(w, "Upload is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	rawMetadata := r.Header.Get("Upload-Metadata")
+	metadata, err := parseTusMetadata(rawMetadata)
+	if err != nil {
+		http.Error(w, "Invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+	videoName := metadata["filename"]
+	if !allowedExtensions[filepath.Ext(videoName)] {
+		slog.Warn("Запрещённое расширение файла",
+			"filename", videoName,
+			"remote_addr", r.RemoteAddr,
+		)
+		http.Error(w, fmt.Sprintf("Тип файла %s не разрешён", filepath.Ext(videoName)), http.StatusBadRequest)
+		return
+	}
+	profile := metadata["profile"]
+	if !t.pool.HasProfile(profile) {
+		http.Error(w, fmt.Sprintf("Профиль %s не найден", profile), http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFrom(r.Context())
+	upload := &database.Upload{
+		ID:        rand.Text(),
+		OwnerID:   user.ID,
+		Length:    length,
+		Metadata:  rawMetadata,
+		FileName:  videoName,
+		Profile:   profile,
+		ExpiresAt: time.Now().Add(t.expiry),
+	}
+	part, err := os.Create(partPath(upload.ID))
+	if err != nil {
+		slog.Error("Ошибка создания файла загрузки", "error", err, "upload_id", upload.ID)
+		http.Error(w, "Не удалось создать загрузку", http.StatusInternalServerError)
+		return
+	}
+	part.Close()
+	if err := t.uploads.InsertUpload(r.Context(), upload); err != nil {
+		slog.Error("Ошибка сохранения загрузки", "error", err, "upload_id", upload.ID)
+		os.Remove(partPath(upload.ID))
+		http.Error(w, "Не удалось создать загрузку", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Создана возобновляемая загрузка",
+		"upload_id", upload.ID,
+		"filename", videoName,
+		"size", length,
+		"remote_addr", r.RemoteAddr,
+	)
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+upload.ID)
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Head возвращает, сколько байт загрузки уже получено.
+// HEAD /video/tus/{uploadID}
+func (t *Tus) Head(w http.ResponseWriter, r *http.Request) {
+	upload, offset, ok := t.load(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.Metadata != "" {
+		w.Header().Set("Upload-Metadata", upload.Metadata)
+	}
+	if upload.VideoID == 0 {
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Patch дописывает тело запроса в загрузку с позиции Upload-Offset.
+// Когда файл получен целиком, он ставится в очередь на конвертацию в HLS.
+// PATCH /video/tus/{uploadID}
+func (t *Tus) Patch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != tusContentType {
+		http.Error(w, "Content-Type must be "+tusContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	clientOffset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || clientOffset < 0 {
+		http.Error(w, "Invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+
+	uploadID := chi.URLParam(r, "uploadID")
+	if !t.lock(uploadID) {
+		http.Error(w, "Upload is locked by another request", http.StatusLocked)
+		return
+	}
+	defer t.unlock(uploadID)
+
+	upload, offset, ok := t.load(w, r)
+	if !ok {
+		return
+	}
+	if clientOffset != offset || upload.VideoID != 0 {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		http.Error(w, "Upload-Offset does not match", http.StatusConflict)
+		return
+	}
+
+	part, err := os.OpenFile(partPath(upload.ID), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		slog.Error("Ошибка открытия файла загрузки", "error", err, "upload_id", upload.ID)
+		http.Error(w, "Ошибка записи файла", http.StatusInternalServerError)
+		return
+	}
+	written, err := io.Copy(part, io.LimitReader(r.Body, upload.Length-offset))
+	if closeErr := part.Close(); err == nil {
+		err = closeErr
+	}
+	offset += written
+	if err != nil {
+		// Полученная часть уже на диске: клиент узнает смещение через HEAD и продолжит
+		slog.Warn("Загрузка части прервана",
+			"error", err,
+			"upload_id", upload.ID,
+			"offset", offset,
+			"remote_addr", r.RemoteAddr,
+		)
+		http.Error(w, "Ошибка записи файла", http.StatusInternalServerError)
+		return
+	}
+
+	if offset < upload.Length {
+		upload.ExpiresAt = time.Now().Add(t.expiry)
+		if err := t.uploads.UpdateUploadExpiration(r.Context(), upload.ID, upload.ExpiresAt); err != nil {
+			slog.Error("Не удалось продлить загрузку", "error", err, "upload_id", upload.ID)
+		}
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	} else if err := t.complete(context.WithoutCancel(r.Context()), upload); err != nil {
+		http.Error(w, "Не удалось начать обработку видео", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Terminate отменяет загрузку и удаляет полученные данные.
+// DELETE /video/tus/{uploadID}
+func (t *Tus) Terminate(w http.ResponseWriter, r *http.Request) {
+	uploadID := chi.URLParam(r, "uploadID")
+	if !t.lock(uploadID) {
+		http.Error(w, "Upload is locked by another request", http.StatusLocked)
+		return
+	}
+	defer t.unlock(uploadID)
+
+	upload, _, ok := t.load(w, r)
+	if !ok {
+		return
+	}
+	t.remove(r.Context(), upload)
+	slog.Info("Возобновляемая загрузка отменена", "upload_id", upload.ID, "remote_addr", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunCleanup периодически удаляет загрузки с истекшим сроком жизни.
+// Блокируется до отмены ctx.
+func (t *Tus) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(cleanupPeriod)
+	defer ticker.Stop()
+
+	for {
+		expired, err := t.uploads.GetExpiredUploads(ctx, time.Now())
+		if err != nil {
+			slog.Error("Не удалось получить просроченные загрузки", "error", err)
+		}
+		for i := range expired {
+			if t.lock(expired[i].ID) {
+				t.remove(ctx, &expired[i])
+				t.unlock(expired[i].ID)
+			}
+		}
+		if len(expired) > 0 {
+			slog.Info("Удалены просроченные загрузки", "количество", len(expired))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// load получает загрузку текущего пользователя из URL и текущее смещение.
+// При ошибке сам отвечает клиенту.
+func (t *Tus) load(w http.ResponseWriter, r *http.Request) (*database.Upload, int64, bool) {
+	uploadID := chi.URLParam(r, "uploadID")
+	upload, err := t.uploads.GetUploadByID(r.Context(), uploadID)
+	// Чужую загрузку не показываем вовсе, как и несуществующую
+	if user, _ := auth.UserFrom(r.Context()); err == nil && upload.OwnerID != user.ID {
+		err = database.ErrNotFound
+	}
+	if err != nil {
+		handlers.StorageError(w, err, "Upload not found")
+		return nil, 0, false
+	}
+	if upload.VideoID != 0 {
+		return upload, upload.Length, true
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		http.Error(w, "Upload expired", http.StatusGone)
+		return nil, 0, false
+	}
+
+	info, err := os.Stat(partPath(upload.ID))
+	if err != nil {
+		slog.Error("Файл загрузки отсутствует на диске", "error", err, "upload_id", upload.ID)
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return nil, 0, false
+	}
+	return upload, info.Size(), true
+}
+
+// complete переносит собранный файл к обычным загрузкам и запускает обработку.
+// Файл уже получен целиком, поэтому ctx не должен отменяться вместе с запросом.
+func (t *Tus) complete(ctx context.Context, upload *database.Upload) error {
+	filename := upload.ID + filepath.Ext(upload.FileName)
+	if err := os.Rename(partPath(upload.ID), filepath.Join(config.TemporaryDir, filename)); err != nil {
+		slog.Error("Не удалось перенести собранный файл", "error", err, "upload_id", upload.ID)
+		return err
+	}
+
+	videoID, _, err := startProcessing(ctx, t.videos, t.pool, upload.FileName, filename, upload.Profile, upload.OwnerID)
+	if err != nil {
+		// startProcessing уже удалил файл, повторить загрузку можно только заново
+		t.uploads.DeleteUpload(ctx, upload.ID)
+		return err
+	}
+	if err := t.uploads.CompleteUpload(ctx, upload.ID, videoID); err != nil {
+		slog.Error("Не удалось отметить загрузку завершенной", "error", err, "upload_id", upload.ID)
+	}
+
+	slog.Info("Возобновляемая загрузка завершена",
+		"upload_id", upload.ID,
+		"video_id", videoID,
+		"original_filename", upload.FileName,
+		"size", upload.Length,
+	)
+	return nil
+}
+
+// remove удаляет запись о загрузке и недокачанный файл.
+func (t *Tus) remove(ctx context.Context, upload *database.Upload) {
+	if upload.VideoID == 0 {
+		os.Remove(partPath(upload.ID))
+	}
+	if err := t.uploads.DeleteUpload(ctx, upload.ID); err != nil {
+		slog.Error("Не удалось удалить загрузку", "error", err, "upload_id", upload.ID)
+	}
+}
+
+func (t *Tus) lock(uploadID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.locked[uploadID] {
+		return false
+	}
+	t.locked[uploadID] = true
+	return true
+}
+
+func (t *Tus) unlock(uploadID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.locked, uploadID)
+}
+
+// partPath возвращает путь к недокачанному файлу загрузки.
+func partPath(uploadID string) string {
+	return filepath.Join(config.TemporaryDir, uploadID+".part")
+}
+
+// parseTusMetadata разбирает Upload-Metadata вида "key base64value,key2 base64value2".
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, fmt.Errorf("пустой ключ в Upload-Metadata")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное значение ключа %q: %w", key, err)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,256 @@
+package video
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"video/auth"
+	"video/config"
+	"video/database"
+	"video/database/dbtest"
+	"video/jobs"
+	"video/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// tempDir направляет config.TemporaryDir во временную папку на время теста.
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, old := t.TempDir(), config.TemporaryDir
+	config.TemporaryDir = dir
+	t.Cleanup(func() { config.TemporaryDir = old })
+	return dir
+}
+
+// tusServer - обработчики tus, подключенные так же, как в video.go.
+// Пул не запущен, поэтому поставленные задачи остаются в очереди.
+type tusServer struct {
+	handler http.Handler
+	db      *database.DB
+	tokens  *auth.Manager
+}
+
+func newTusServer(t *testing.T) *tusServer {
+	t.Helper()
+	tempDir(t)
+	db := dbtest.Open(t)
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	tus := NewTus(db, db, jobs.NewPool(db, db, db, backend, cfg), cfg)
+	tokens := auth.NewManager([]byte("secret"), time.Hour, 24*time.Hour)
+
+	router := chi.NewRouter()
+	router.Use(tokens.Authenticate)
+	router.Route("/video/tus", func(r chi.Router) {
+		r.Use(tus.Middleware)
+		r.Use(auth.RequireUser)
+		r.Post("/", tus.Create)
+		r.Head("/{uploadID}", tus.Head)
+		r.Patch("/{uploadID}", tus.Patch)
+		r.Delete("/{uploadID}", tus.Terminate)
+	})
+	return &tusServer{handler: router, db: db, tokens: tokens}
+}
+
+// do выполняет tus-запрос от имени пользователя userID.
+func (s *tusServer) do(t *testing.T, userID int, method, path string, body io.Reader, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	pair, err := s.tokens.Issue(&database.User{ID: userID, Username: "user" + strconv.Itoa(userID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(method, path, body)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	r.Header.Set("Tus-Resumable", tusVersion)
+	r.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, r)
+	return w
+}
+
+// create создает загрузку длиной length и возвращает ее адрес.
+func (s *tusServer) create(t *testing.T, userID int, length int) string {
+	t.Helper()
+	w := s.do(t, userID, http.MethodPost, "/video/tus/", nil, http.Header{
+		"Upload-Length":   {strconv.Itoa(length)},
+		"Upload-Metadata": {"filename " + base64.StdEncoding.EncodeToString([]byte("film.mp4"))},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("создание загрузки: ответ %d %s", w.Code, w.Body)
+	}
+	return w.Header().Get("Location")
+}
+
+// patch отправляет часть data с позиции offset.
+func (s *tusServer) patch(t *testing.T, userID int, location string, offset int, data io.Reader) *httptest.ResponseRecorder {
+	t.Helper()
+	return s.do(t, userID, http.MethodPatch, location, data, http.Header{
+		"Content-Type":  {tusContentType},
+		"Upload-Offset": {strconv.Itoa(offset)},
+	})
+}
+
+// uploadID возвращает идентификатор загрузки из ее адреса.
+func uploadID(location string) string {
+	return location[strings.LastIndex(location, "/")+1:]
+}
+
+func TestTusOffsetMismatch(t *testing.T) {
+	s := newTusServer(t)
+	location := s.create(t, 1, 10)
+
+	if w := s.patch(t, 1, location, 0, strings.NewReader("0123")); w.Code != http.StatusNoContent {
+		t.Fatalf("первая часть: ответ %d %s", w.Code, w.Body)
+	}
+	for _, offset := range []int{0, 2, 6} {
+		w := s.patch(t, 1, location, offset, strings.NewReader("4567"))
+		if w.Code != http.StatusConflict || w.Header().Get("Upload-Offset") != "4" {
+			t.Errorf("часть с позиции %d: ответ %d, Upload-Offset %q; ожидался 409 и 4", offset, w.Code, w.Header().Get("Upload-Offset"))
+		}
+	}
+	if w := s.do(t, 1, http.MethodHead, location, nil, nil); w.Header().Get("Upload-Offset") != "4" {
+		t.Errorf("HEAD после отклоненных частей: Upload-Offset %q, ожидалось 4", w.Header().Get("Upload-Offset"))
+	}
+}
+
+func TestTusExpired(t *testing.T) {
+	s := newTusServer(t)
+	location := s.create(t, 1, 10)
+	if err := s.db.UpdateUploadExpiration(context.Background(), uploadID(location), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := s.patch(t, 1, location, 0, strings.NewReader("0123")); w.Code != http.StatusGone {
+		t.Errorf("PATCH просроченной загрузки: ответ %d, ожидался 410", w.Code)
+	}
+	if w := s.do(t, 1, http.MethodHead, location, nil, nil); w.Code != http.StatusGone {
+		t.Errorf("HEAD просроченной загрузки: ответ %d, ожидался 410", w.Code)
+	}
+}
+
+func TestTusOtherUser(t *testing.T) {
+	s := newTusServer(t)
+	location := s.create(t, 1, 10)
+
+	tests := []struct {
+		method string
+		w      func() *httptest.ResponseRecorder
+	}{
+		{http.MethodHead, func() *httptest.ResponseRecorder { return s.do(t, 2, http.MethodHead, location, nil, nil) }},
+		{http.MethodPatch, func() *httptest.ResponseRecorder { return s.patch(t, 2, location, 0, strings.NewReader("0123")) }},
+		{http.MethodDelete, func() *httptest.ResponseRecorder { return s.do(t, 2, http.MethodDelete, location, nil, nil) }},
+	}
+	for _, tt := range tests {
+		if w := tt.w(); w.Code != http.StatusNotFound {
+			t.Errorf("%s чужой загрузки: ответ %d, ожидался 404", tt.method, w.Code)
+		}
+	}
+	if w := s.do(t, 1, http.MethodHead, location, nil, nil); w.Code != http.StatusOK || w.Header().Get("Upload-Offset") != "0" {
+		t.Errorf("загрузка изменилась после запросов другого пользователя: ответ %d, Upload-Offset %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+}
+
+func TestTusParallelPatch(t *testing.T) {
+	s := newTusServer(t)
+	location := s.create(t, 1, 10)
+
+	// Первый PATCH держит загрузку, пока в его тело пишутся данные
+	body, writer := io.Pipe()
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- s.patch(t, 1, location, 0, body) }()
+	// Запись возвращается, только когда обработчик уже читает тело
+	if _, err := writer.Write([]byte("012")); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := s.patch(t, 1, location, 0, strings.NewReader("0123")); w.Code != http.StatusLocked {
+		t.Errorf("параллельный PATCH: ответ %d, ожидался 423", w.Code)
+	}
+	if w := s.do(t, 1, http.MethodDelete, location, nil, nil); w.Code != http.StatusLocked {
+		t.Errorf("DELETE во время PATCH: ответ %d, ожидался 423", w.Code)
+	}
+
+	writer.Close()
+	if w := <-first; w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "3" {
+		t.Fatalf("первый PATCH: ответ %d, Upload-Offset %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	if w := s.patch(t, 1, location, 3, strings.NewReader("3")); w.Code != http.StatusNoContent {
+		t.Errorf("PATCH после снятия блокировки: ответ %d", w.Code)
+	}
+}
+
+func TestTusTerminate(t *testing.T) {
+	s := newTusServer(t)
+	location := s.create(t, 1, 10)
+	if w := s.patch(t, 1, location, 0, strings.NewReader("0123")); w.Code != http.StatusNoContent {
+		t.Fatalf("первая часть: ответ %d %s", w.Code, w.Body)
+	}
+	part := partPath(uploadID(location))
+	if _, err := os.Stat(part); err != nil {
+		t.Fatalf("нет файла загрузки: %v", err)
+	}
+
+	if w := s.do(t, 1, http.MethodDelete, location, nil, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: ответ %d %s", w.Code, w.Body)
+	}
+	if _, err := os.Stat(part); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("файл загрузки остался после DELETE: %v", err)
+	}
+	if w := s.do(t, 1, http.MethodHead, location, nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("HEAD отмененной загрузки: ответ %d, ожидался 404", w.Code)
+	}
+}
+
+func TestTusComplete(t *testing.T) {
+	s := newTusServer(t)
+	ctx := context.Background()
+	location := s.create(t, 1, 10)
+
+	for _, part := range []struct {
+		offset int
+		data   string
+	}{{0, "0123"}, {4, "456789"}} {
+		w := s.patch(t, 1, location, part.offset, strings.NewReader(part.data))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("часть с позиции %d: ответ %d %s", part.offset, w.Code, w.Body)
+		}
+	}
+	// Повтор последней части после завершения не ставит видео в очередь второй раз
+	if w := s.patch(t, 1, location, 4, strings.NewReader("456789")); w.Code != http.StatusConflict {
+		t.Errorf("повтор последней части: ответ %d, ожидался 409", w.Code)
+	}
+	if w := s.do(t, 1, http.MethodHead, location, nil, nil); w.Code != http.StatusOK || w.Header().Get("Upload-Offset") != "10" {
+		t.Errorf("HEAD завершенной загрузки: ответ %d, Upload-Offset %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+
+	job, err := s.db.ClaimNextJob(ctx)
+	if err != nil || job == nil {
+		t.Fatalf("задача не поставлена в очередь: %v", err)
+	}
+	if extra, err := s.db.ClaimNextJob(ctx); extra != nil || err != nil {
+		t.Errorf("лишняя задача в очереди: %+v, %v", extra, err)
+	}
+	data, err := os.ReadFile(filepath.Join(config.TemporaryDir, job.FileName))
+	if err != nil || string(data) != "0123456789" {
+		t.Errorf("исходник задачи = %q, %v", data, err)
+	}
+	video, err := s.db.GetVideoByID(ctx, job.VideoID)
+	if err != nil || video.VideoName != "film.mp4" || video.OwnerID != 1 {
+		t.Errorf("видео загрузки = %+v, %v", video, err)
+	}
+}
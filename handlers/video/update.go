@@ -0,0 +1,130 @@
+package video
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+	"video/database"
+	"video/handlers"
+)
+
+// Ограничения на редактируемые поля видео.
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 5000
+	maxTags              = 20
+	maxTagLength         = 32
+)
+
+// updateRequest - изменяемые поля видео. Отсутствующее поле не меняется.
+type updateRequest struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+	Visibility  *string   `json:"visibility"`
+}
+
+// Update меняет название, описание, теги и видимость видео.
+// PATCH /video/{id}, тело: {"title": "...", "description": "...", "tags": ["..."], "visibility": "public"}
+// Редактировать можно только свое видео, чужое закрытое видео отвечает 404.
+// Теги приводятся к нижнему регистру, список тегов заменяется целиком.
+func Update(videoStorage database.VideoStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		video, ok := ownVideo(w, r, videoStorage)
+		if !ok {
+			return
+		}
+
+		var req updateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		meta := database.VideoMetadata{
+			VideoName:   video.VideoName,
+			Description: video.Description,
+			Tags:        video.Tags,
+			Visibility:  video.Visibility,
+		}
+		if req.Title != nil {
+			meta.VideoName = strings.TrimSpace(*req.Title)
+			if meta.VideoName == "" || utf8.RuneCountInString(meta.VideoName) > maxTitleLength {
+				http.Error(w, fmt.Sprintf("title must be 1 to %d characters", maxTitleLength), http.StatusBadRequest)
+				return
+			}
+		}
+		if req.Description != nil {
+			meta.Description = strings.TrimSpace(*req.Description)
+			if utf8.RuneCountInString(meta.Description) > maxDescriptionLength {
+				http.Error(w, fmt.Sprintf("description must be at most %d characters", maxDescriptionLength), http.StatusBadRequest)
+				return
+			}
+		}
+		if req.Tags != nil {
+			tags, err := normalizeTags(*req.Tags)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			meta.Tags = tags
+		}
+		if req.Visibility != nil {
+			if !database.IsVisibility(*req.Visibility) {
+				http.Error(w, fmt.Sprintf("Unknown visibility: %s", *req.Visibility), http.StatusBadRequest)
+				return
+			}
+			meta.Visibility = *req.Visibility
+		}
+
+		if err := videoStorage.UpdateVideoMetadata(r.Context(), video.ID, meta); err != nil {
+			slog.Error("Не удалось обновить данные видео",
+				"video_id", video.ID,
+				"ошибка", err,
+				"удалённый_адрес", r.RemoteAddr,
+			)
+			handlers.StorageError(w, err, "Video not found")
+			return
+		}
+
+		updated, err := videoStorage.GetVideoByID(r.Context(), video.ID)
+		if err != nil {
+			handlers.StorageError(w, err, "Video not found")
+			return
+		}
+
+		slog.Info("Данные видео обновлены",
+			"video_id", video.ID,
+			"visibility", updated.Visibility,
+		)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(updated); err != nil {
+			slog.Error("Не удалось отправить JSON-ответ", "ошибка", err)
+		}
+	}
+}
+
+// normalizeTags приводит теги к нижнему регистру, убирает пустые и повторы и сортирует.
+func normalizeTags(raw []string) ([]string, error) {
+	tags := make([]string, 0, len(raw))
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag must be at most %d characters: %s", maxTagLength, tag)
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxTags)
+	}
+	slices.Sort(tags)
+	return tags, nil
+}
@@ -0,0 +1,114 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"video/auth"
+	"video/database"
+	"video/database/dbtest"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	many := make([]string, maxTags+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("tag%02d", i)
+	}
+
+	tests := []struct {
+		name    string
+		raw     []string
+		want    []string
+		wantErr bool
+	}{
+		{"пустой список", nil, []string{}, false},
+		{"нижний регистр и сортировка", []string{"Кино", "ANIME"}, []string{"anime", "кино"}, false},
+		{"повторы без учета регистра", []string{"go", "Go", " GO "}, []string{"go"}, false},
+		{"пустые теги пропускаются", []string{"", "  ", "a"}, []string{"a"}, false},
+		{"ровно предел тегов", many[:maxTags], many[:maxTags], false},
+		{"больше предела тегов", many, nil, true},
+		{"повторы не считаются в предел", append(slices.Clone(many[:maxTags]), strings.ToUpper(many[0])), many[:maxTags], false},
+		// Кириллица занимает два байта на символ: длина считается в символах
+		{"ровно предел длины", []string{strings.Repeat("я", maxTagLength)}, []string{strings.Repeat("я", maxTagLength)}, false},
+		{"длиннее предела", []string{strings.Repeat("я", maxTagLength+1)}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeTags(tt.raw)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: normalizeTags = %q, %v; ожидалось %q, ошибка %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	router := chi.NewRouter()
+	router.Patch("/video/{id}", Update(db))
+
+	videoID, err := db.InsertVideo(ctx, "Исходное", "source.mp4", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := database.VideoMetadata{VideoName: "Исходное", Description: "описание", Tags: []string{"старый"}, Visibility: database.VisibilityPublic}
+	if err := db.UpdateVideoMetadata(ctx, videoID, meta); err != nil {
+		t.Fatal(err)
+	}
+	privateID, err := db.InsertVideo(ctx, "Закрытое", "private.mp4", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta = database.VideoMetadata{VideoName: "Закрытое", Visibility: database.VisibilityPrivate}
+	if err := db.UpdateVideoMetadata(ctx, privateID, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := func(userID, id int, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/video/%d", id), strings.NewReader(body))
+		r = r.WithContext(auth.WithUser(r.Context(), auth.User{ID: userID}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		userID int
+		id     int
+		body   string
+		code   int
+	}{
+		{"чужое видео", 2, videoID, `{"title": "Чужое"}`, http.StatusForbidden},
+		{"чужое закрытое видео", 2, privateID, `{"title": "Чужое"}`, http.StatusNotFound},
+		{"несуществующее видео", 1, privateID + 100, `{"title": "Нет"}`, http.StatusNotFound},
+		{"неизвестная видимость", 1, videoID, `{"visibility": "secret"}`, http.StatusBadRequest},
+		{"пустое название", 1, videoID, `{"title": "  "}`, http.StatusBadRequest},
+		{"некорректное тело", 1, videoID, `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := patch(tt.userID, tt.id, tt.body); w.Code != tt.code {
+			t.Errorf("%s: ответ %d, ожидался %d: %s", tt.name, w.Code, tt.code, w.Body)
+		}
+	}
+
+	// Отклоненные запросы ничего не меняют, а частичное обновление не трогает остальные поля
+	w := patch(1, videoID, `{"title": " Новое ", "tags": ["Новый", "новый"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("частичное обновление: ответ %d: %s", w.Code, w.Body)
+	}
+	var got database.Video
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.VideoName != "Новое" || got.Description != "описание" || got.Visibility != database.VisibilityPublic ||
+		!slices.Equal(got.Tags, []string{"новый"}) {
+		t.Errorf("после обновления: %+v", got)
+	}
+}
@@ -1,6 +1,7 @@
 package video
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
@@ -9,8 +10,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"video/auth"
 	"video/config"
 	database "video/database"
+	"video/jobs"
 	"video/utils"
 
 	"log/slog" // <-- добавлен
@@ -18,9 +21,12 @@ import (
 
 
 // Метод загрузки видео на сервер
-// post?video
-func Upload(videoStorage database.VideoStorage) http.HandlerFunc {
+// post?video, необязательное поле формы profile - имя профиля кодирования
+// Доступен только вошедшим пользователям: видео записывается на загрузившего.
+func Upload(videoStorage database.VideoStorage, pool *jobs.Pool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := auth.UserFrom(r.Context())
+
 		// Получаем файл из формы
 		file, handler, err := r.FormFile("video")
 		if err != nil {
@@ -36,6 +42,17 @@ func Upload(videoStorage database.VideoStorage) http.HandlerFunc {
 		defer file.Close()
 		videoName := handler.Filename
 
+		// Профиль кодирования можно выбрать полем формы profile
+		profile := r.FormValue("profile")
+		if !pool.HasProfile(profile) {
+			slog.Warn("Неизвестный профиль кодирования",
+				"profile", profile,
+				"remote_addr", r.RemoteAddr,
+			)
+			http.Error(w, fmt.Sprintf("Профиль %s не найден", profile), http.StatusBadRequest)
+			return
+		}
+
 		// Логируем информацию о файле
 		slog.Info("Получен файл для загрузки",
 			"filename", videoName,
@@ -45,10 +62,6 @@ func Upload(videoStorage database.VideoStorage) http.HandlerFunc {
 		)
 
 		// Валидация расширения
-		allowedExtensions := map[string]bool{
-			".mp4": true, ".avi": true, ".mov": true,
-			".mkv": true, ".webm": true,
-		}
 		ext := filepath.Ext(videoName)
 		if !allowedExtensions[ext] {
 			slog.Warn("Запрещённое расширение файла",
@@ -85,6 +98,10 @@ func Upload(videoStorage database.VideoStorage) http.HandlerFunc {
 
 		// Копируем содержимое
 		_, err = io.Copy(dst, file)
+		if err == nil {
+			// Закрываем сразу: файл может уйти в конвертацию до выхода из обработчика
+			err = dst.Close()
+		}
 		if err != nil {
 			slog.Error("Ошибка записи файла на диск",
 				"error", err,
@@ -102,34 +119,61 @@ func Upload(videoStorage database.VideoStorage) http.HandlerFunc {
 			"size", handler.Size,
 		)
 
-		videoStorage.InsertVideo(videoName, uniqueName)
-		go func(filename string) {
-			slog.Info("Запускается фоновая конвертация в HLS", "filename", filename)
-			hlsErr := utils.GenerateAdaptiveHLS(config.TemporaryDir, config.UploadDir, filename)
-			if hlsErr != nil {
-				slog.Error("Ошибка конвертации MP4 в HLS",
-					"error", hlsErr,
-					"mp4_filename", filename,
-				)
-				videoStorage.DeleteVideoByFileName(uniqueName)
-
-			} else {
-				slog.Info("HLS конвертация завершена успешно", "mp4_filename", filename)
-			}
-			os.Remove(filePath)
-
-		}(filename)
+		// Файл уже на диске: обрыв соединения не должен прервать постановку в очередь
+		videoID, job, err := startProcessing(context.WithoutCancel(r.Context()), videoStorage, pool, videoName, filename, profile, user.ID)
+		if err != nil {
+			http.Error(w, "Не удалось начать обработку видео", http.StatusInternalServerError)
+			return
+		}
 
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{
+		json.NewEncoder(w).Encode(map[string]any{
 			"message":           "Видео успешно загружено и начинается обработка.",
 			"filename_for_hls":  strings.TrimSuffix(filename, filepath.Ext(filename)),
 			"original_filename": videoName,
+			"video_id":          videoID,
+			"job_id":            job.ID,
 		})
 
 	}
 }
 
+// allowedExtensions - расширения загружаемых файлов, которые умеет обрабатывать ffmpeg-конвейер.
+var allowedExtensions = map[string]bool{
+	".mp4": true, ".avi": true, ".mov": true,
+	".mkv": true, ".webm": true,
+}
+
+// startProcessing регистрирует загруженный файл filename из config.TemporaryDir
+// как видео videoName пользователя ownerID и ставит его в очередь на конвертацию в HLS.
+// При ошибке запись о видео и файл удаляются.
+func startProcessing(ctx context.Context, videoStorage database.VideoStorage, pool *jobs.Pool, videoName, filename, profile string, ownerID int) (int, *database.Job, error) {
+	filePath := filepath.Join(config.TemporaryDir, filename)
+	uniqueName := strings.TrimSuffix(filename, filepath.Ext(filename))
+
+	videoID, err := videoStorage.InsertVideo(ctx, videoName, uniqueName, ownerID)
+	if err != nil {
+		slog.Error("Не удалось сохранить видео в базе данных",
+			"error", err,
+			"filename", filename,
+		)
+		os.Remove(filePath)
+		return 0, nil, err
+	}
+	job, err := pool.Enqueue(ctx, videoID, filename, profile)
+	if err != nil {
+		slog.Error("Не удалось поставить видео в очередь на конвертацию",
+			"error", err,
+			"video_id", videoID,
+			"filename", filename,
+		)
+		videoStorage.DeleteVideoByID(ctx, videoID)
+		os.Remove(filePath)
+		return 0, nil, err
+	}
+	return videoID, job, nil
+}
+
 func startConvertVideo(filePath, supportedFilePath string, done chan error) {
 	err := utils.ConvertToMP4(filePath, supportedFilePath)
 	done <- err
@@ -0,0 +1,436 @@
+// Package jobs выполняет задачи транскодирования из персистентной очереди.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"video/config"
+	"video/database"
+	"video/storage"
+	"video/utils"
+)
+
+const (
+	// maxAttempts - после стольких попыток задача считается неудачной.
+	// До этого ошибки конвертации повторяются, а лимит защищает
+	// от бесконечного перезапуска файла, который роняет сервер.
+	maxAttempts = 3
+	// retryDelay - пауза перед второй попыткой, перед каждой следующей она удваивается.
+	retryDelay = time.Minute
+	// pollInterval - как часто воркеры проверяют очередь без уведомлений.
+	pollInterval = 30 * time.Second
+)
+
+// Pool - пул воркеров, разбирающих очередь задач из базы данных.
+// Очередь хранится в таблице jobs, поэтому незавершенные задачи
+// переживают перезапуск сервера.
+type Pool struct {
+	jobs      database.JobStorage
+	videos    database.VideoStorage
+	subtitles database.SubtitleStorage
+	storage   storage.Backend
+	cfg       *config.Config
+	workers   int
+	backoff   time.Duration // Пауза перед повтором, в тестах короче retryDelay
+	wake      chan struct{}
+	progress  *tracker
+	running   sync.WaitGroup // Запущенные воркеры
+}
+
+// NewPool создает пул, выполняющий не более cfg.Workers задач одновременно
+// с профилями кодирования из cfg. Готовые HLS-потоки сохраняются в backend.
+func NewPool(jobStorage database.JobStorage, videoStorage database.VideoStorage, subtitleStorage database.SubtitleStorage, backend storage.Backend, cfg *config.Config) *Pool {
+	workers := max(cfg.Workers, 1)
+	return &Pool{
+		jobs:      jobStorage,
+		videos:    videoStorage,
+		subtitles: subtitleStorage,
+		storage:   backend,
+		cfg:       cfg,
+		workers:   workers,
+		backoff:   retryDelay,
+		wake:      make(chan struct{}, workers),
+		progress:  newTracker(),
+	}
+}
+
+// Start возвращает в очередь задачи, прерванные прошлой остановкой сервера,
+// и запускает воркеры. После отмены ctx воркеры доделывают текущие задачи
+// и останавливаются, дождаться этого можно через Wait.
+func (p *Pool) Start(ctx context.Context) error {
+	requeued, err := p.jobs.RequeueRunningJobs(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось восстановить очередь задач: %w", err)
+	}
+	if requeued > 0 {
+		slog.Info("Прерванные задачи возвращены в очередь", "количество", requeued)
+	}
+
+	for i := 0; i < p.workers; i++ {
+		p.running.Add(1)
+		go p.work(ctx)
+	}
+	slog.Info("Пул транскодирования запущен", "воркеров", p.workers)
+	return nil
+}
+
+// Wait ждет остановки воркеров после отмены контекста Start.
+// Если ctx отменяется раньше, возвращает его ошибку: недоделанные задачи
+// остаются в статусе running, и следующий Start вернет их в очередь.
+func (p *Pool) Wait(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		p.running.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HasProfile сообщает, есть ли профиль кодирования с таким именем.
+// Пустое имя означает профиль по умолчанию.
+func (p *Pool) HasProfile(name string) bool {
+	_, ok := p.cfg.Profile(name)
+	return ok
+}
+
+// Enqueue ставит в очередь транскодирование файла fileName из config.TemporaryDir
+// для видео videoID с профилем profile и будит свободный воркер.
+func (p *Pool) Enqueue(ctx context.Context, videoID int, fileName, profile string) (*database.Job, error) {
+	if !p.HasProfile(profile) {
+		return nil, fmt.Errorf("неизвестный профиль кодирования: %q", profile)
+	}
+	job, err := p.jobs.InsertJob(ctx, videoID, fileName, profile)
+	if err != nil {
+		return nil, err
+	}
+	p.notify()
+	return job, nil
+}
+
+// notify будит свободный воркер.
+func (p *Pool) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default: // Все воркеры уже разбуждены
+	}
+}
+
+// Progress возвращает прогресс транскодирования видео, если оно сейчас обрабатывается.
+func (p *Pool) Progress(videoID int) (utils.Progress, bool) {
+	return p.progress.get(videoID)
+}
+
+// Subscribe подписывается на прогресс транскодирования видео.
+// Канал закрывается, когда задача завершается; cancel освобождает подписку раньше.
+func (p *Pool) Subscribe(videoID int) (updates <-chan utils.Progress, cancel func()) {
+	return p.progress.subscribe(videoID)
+}
+
+// work забирает задачи из очереди, пока она не опустеет, затем ждет уведомления.
+func (p *Pool) work(ctx context.Context) {
+	defer p.running.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			job, err := p.jobs.ClaimNextJob(ctx)
+			if err != nil {
+				slog.Error("Не удалось получить задачу из очереди", "ошибка", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			// Начатая задача доделывается и после отмены ctx, чтобы записать ее итог
+			p.run(context.WithoutCancel(ctx), job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// run выполняет задачу и записывает ее итоговый статус.
+func (p *Pool) run(ctx context.Context, job *database.Job) {
+	slog.Info("Запускается конвертация в HLS",
+		"job_id", job.ID,
+		"video_id", job.VideoID,
+		"filename", job.FileName,
+		"profile", job.Profile,
+		"попытка", job.Attempts,
+	)
+
+	// Видео могли удалить, пока задача ждала в очереди
+	if p.videoDeleted(ctx, job) {
+		p.abandon(ctx, job)
+		return
+	}
+	if err := p.videos.UpdateVideoStatus(ctx, job.VideoID, database.VideoProcessing, ""); err != nil {
+		slog.Error("Не удалось обновить статус видео",
+			"video_id", job.VideoID,
+			"status", database.VideoProcessing,
+			"error", err,
+		)
+	}
+	p.saveDuration(ctx, job)
+
+	// Профиль могли удалить из конфигурации, пока задача ждала в очереди
+	profile, ok := p.cfg.Profile(job.Profile)
+	if !ok {
+		slog.Warn("Профиль задачи не найден, используется профиль по умолчанию",
+			"job_id", job.ID,
+			"profile", job.Profile,
+		)
+		profile, _ = p.cfg.Profile("")
+	}
+
+	var hlsErr error
+	if job.Attempts > maxAttempts {
+		hlsErr = fmt.Errorf("превышено число попыток: %d", maxAttempts)
+	} else {
+		hlsErr = p.transcode(ctx, job, profile)
+	}
+	// Видео могли удалить и во время кодирования, тогда готовый HLS уже не нужен
+	if p.videoDeleted(ctx, job) {
+		videoFolderName := strings.TrimSuffix(job.FileName, filepath.Ext(job.FileName))
+		if _, err := storage.DeletePrefix(ctx, p.storage, videoFolderName+"/"); err != nil {
+			slog.Error("Не удалось удалить HLS удаленного видео из хранилища",
+				"video", videoFolderName,
+				"error", err,
+			)
+		}
+		p.abandon(ctx, job)
+		return
+	}
+
+	// Ошибка может быть временной, например у S3, поэтому пока попытки не кончились,
+	// задача возвращается в очередь с паузой, а исходник остается во временной папке
+	retry := hlsErr != nil && job.Attempts < maxAttempts
+	status, videoStatus, errorMessage := database.JobSucceeded, database.VideoReady, ""
+	switch {
+	case retry:
+		videoStatus, errorMessage = database.VideoUploaded, hlsErr.Error()
+		slog.Warn("Ошибка конвертации в HLS, задача будет повторена",
+			"job_id", job.ID,
+			"error", hlsErr,
+			"filename", job.FileName,
+			"попытка", job.Attempts,
+		)
+	case hlsErr != nil:
+		status, videoStatus, errorMessage = database.JobFailed, database.VideoFailed, hlsErr.Error()
+		slog.Error("Ошибка конвертации в HLS",
+			"job_id", job.ID,
+			"error", hlsErr,
+			"filename", job.FileName,
+		)
+	default:
+		slog.Info("HLS конвертация завершена успешно",
+			"job_id", job.ID,
+			"filename", job.FileName,
+		)
+	}
+
+	if err := p.videos.UpdateVideoStatus(ctx, job.VideoID, videoStatus, errorMessage); err != nil {
+		slog.Error("Не удалось обновить статус видео",
+			"video_id", job.VideoID,
+			"status", videoStatus,
+			"error", err,
+		)
+	}
+	if retry {
+		// Подписчики дождутся прогресса следующей попытки
+		p.retryLater(ctx, job, errorMessage)
+		return
+	}
+	if err := p.jobs.FinishJob(ctx, job.ID, status, errorMessage); err != nil {
+		slog.Error("Не удалось сохранить статус задачи",
+			"job_id", job.ID,
+			"status", status,
+			"error", err,
+		)
+	}
+	// Подписчики узнают итоговый статус из базы, поэтому закрываем их после FinishJob
+	p.progress.finish(job.VideoID)
+	os.Remove(filepath.Join(config.TemporaryDir, job.FileName))
+}
+
+// retryLater возвращает задачу в очередь. Пауза удваивается с каждой попыткой:
+// сбой хранилища или сети, из-за которого упала попытка, успевает пройти.
+// Когда пауза кончается, воркер будится, не дожидаясь опроса очереди.
+func (p *Pool) retryLater(ctx context.Context, job *database.Job, errorMessage string) {
+	delay := p.backoff << max(job.Attempts-1, 0)
+	if err := p.jobs.RetryJob(ctx, job.ID, errorMessage, delay); err != nil {
+		slog.Error("Не удалось вернуть задачу в очередь",
+			"job_id", job.ID,
+			"error", err,
+		)
+		return
+	}
+	slog.Info("Задача повторится после паузы", "job_id", job.ID, "пауза", delay)
+	time.AfterFunc(delay, p.notify)
+}
+
+// videoDeleted сообщает, удалено ли видео задачи. Если проверить не удалось,
+// считается, что видео есть: задача не должна пропасть из-за сбоя базы.
+func (p *Pool) videoDeleted(ctx context.Context, job *database.Job) bool {
+	_, err := p.videos.GetVideoByID(ctx, job.VideoID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		slog.Error("Не удалось проверить видео задачи",
+			"job_id", job.ID,
+			"video_id", job.VideoID,
+			"error", err,
+		)
+	}
+	return errors.Is(err, database.ErrNotFound)
+}
+
+// abandon завершает задачу удаленного видео: помечает ее неудачной,
+// если запись задачи еще есть, и удаляет исходник и извлеченные субтитры.
+func (p *Pool) abandon(ctx context.Context, job *database.Job) {
+	slog.Warn("Видео удалено, задача отменена",
+		"job_id", job.ID,
+		"video_id", job.VideoID,
+		"filename", job.FileName,
+	)
+	// Вместе с видео задачу обычно удаляет база, тогда обновлять нечего
+	err := p.jobs.FinishJob(ctx, job.ID, database.JobFailed, "видео удалено")
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		slog.Error("Не удалось сохранить статус задачи",
+			"job_id", job.ID,
+			"status", database.JobFailed,
+			"error", err,
+		)
+	}
+	if err := p.subtitles.DeleteSubtitlesBySource(ctx, job.VideoID, database.SubtitleEmbedded); err != nil {
+		slog.Error("Не удалось удалить субтитры удаленного видео", "video_id", job.VideoID, "error", err)
+	}
+	p.progress.finish(job.VideoID)
+	os.Remove(filepath.Join(config.TemporaryDir, job.FileName))
+}
+
+// saveDuration запоминает длительность исходника для сортировки списка видео.
+// Ошибка не мешает конвертации: ffmpeg сам сообщит о нечитаемом файле.
+func (p *Pool) saveDuration(ctx context.Context, job *database.Job) {
+	info, err := utils.Probe(filepath.Join(config.TemporaryDir, job.FileName))
+	if err != nil {
+		slog.Warn("Не удалось узнать длительность видео",
+			"video_id", job.VideoID,
+			"error", err,
+		)
+		return
+	}
+	if err := p.videos.UpdateVideoDuration(ctx, job.VideoID, info.Duration); err != nil {
+		slog.Error("Не удалось сохранить длительность видео",
+			"video_id", job.VideoID,
+			"error", err,
+		)
+	}
+}
+
+// transcode кодирует исходник задачи в HLS и сохраняет результат в хранилище.
+func (p *Pool) transcode(ctx context.Context, job *database.Job, profile utils.HLSProfile) error {
+	onProgress := func(pr utils.Progress) {
+		p.progress.publish(job.VideoID, pr)
+	}
+	videoFolderName := strings.TrimSuffix(job.FileName, filepath.Ext(job.FileName))
+	// В локальное хранилище ffmpeg пишет сразу, тогда видео видно еще во время кодирования
+	if dir, ok := p.storage.(storage.Dir); ok {
+		if err := utils.GenerateAdaptiveHLS(config.TemporaryDir, dir.Root(), job.FileName, profile, onProgress); err != nil {
+			return err
+		}
+		p.extractSubtitles(ctx, job, profile, filepath.Join(dir.Root(), videoFolderName))
+		return nil
+	}
+
+	// Иначе кодируем рядом с исходником и загружаем готовую папку целиком
+	if err := utils.GenerateAdaptiveHLS(config.TemporaryDir, config.TemporaryDir, job.FileName, profile, onProgress); err != nil {
+		return err
+	}
+	outputDir := filepath.Join(config.TemporaryDir, videoFolderName)
+	defer os.RemoveAll(outputDir)
+	p.extractSubtitles(ctx, job, profile, outputDir)
+
+	if err := storage.PutDir(ctx, p.storage, outputDir, videoFolderName); err != nil {
+		// Не оставляем в хранилище половину видео
+		if _, delErr := storage.DeletePrefix(ctx, p.storage, videoFolderName+"/"); delErr != nil {
+			slog.Error("Не удалось удалить частично загруженное видео из хранилища",
+				"video", videoFolderName,
+				"error", delErr,
+			)
+		}
+		return fmt.Errorf("не удалось сохранить HLS в хранилище: %w", err)
+	}
+	return nil
+}
+
+// extractSubtitles переводит текстовые дорожки субтитров исходника в HLS-субтитры
+// в папке outputDir и записывает их в базу. Ошибки только логируются:
+// видео можно смотреть и без субтитров.
+func (p *Pool) extractSubtitles(ctx context.Context, job *database.Job, profile utils.HLSProfile, outputDir string) {
+	inputPath := filepath.Join(config.TemporaryDir, job.FileName)
+	info, err := utils.Probe(inputPath)
+	if err != nil {
+		slog.Warn("Не удалось прочитать дорожки субтитров", "video_id", job.VideoID, "error", err)
+		return
+	}
+	// Дорожки, извлеченные прошлой попыткой обработки
+	if err := p.subtitles.DeleteSubtitlesBySource(ctx, job.VideoID, database.SubtitleEmbedded); err != nil {
+		slog.Error("Не удалось удалить старые субтитры видео", "video_id", job.VideoID, "error", err)
+		return
+	}
+
+	for _, stream := range info.Subtitles {
+		if !stream.IsText() {
+			slog.Info("Пропущены субтитры-картинки", "video_id", job.VideoID, "stream", stream.Index, "codec", stream.Codec)
+			continue
+		}
+		vtt, err := utils.ConvertToWebVTT(inputPath, stream.Index)
+		if err != nil {
+			slog.Warn("Не удалось извлечь субтитры", "video_id", job.VideoID, "stream", stream.Index, "error", err)
+			continue
+		}
+
+		language := stream.Language
+		if language == "und" {
+			language = ""
+		}
+		name := stream.Title
+		if name == "" {
+			name = language
+		}
+		if name == "" {
+			name = fmt.Sprintf("Subtitles %d", stream.Index+1)
+		}
+		subtitle, err := p.subtitles.InsertSubtitle(ctx, job.VideoID, language, name, database.SubtitleEmbedded, stream.Default)
+		if err != nil {
+			slog.Error("Не удалось сохранить субтитры", "video_id", job.VideoID, "stream", stream.Index, "error", err)
+			continue
+		}
+		err = utils.WriteSubtitlePlaylist(outputDir, utils.SubtitleBaseName(subtitle.ID), vtt, info.Duration, profile.SegmentDuration)
+		if err != nil {
+			slog.Warn("Не удалось нарезать субтитры", "video_id", job.VideoID, "stream", stream.Index, "error", err)
+			if err := p.subtitles.DeleteSubtitle(ctx, subtitle.ID); err != nil {
+				slog.Error("Не удалось удалить запись о субтитрах", "subtitle_id", subtitle.ID, "error", err)
+			}
+			continue
+		}
+		slog.Info("Извлечены субтитры", "video_id", job.VideoID, "subtitle_id", subtitle.ID, "language", language)
+	}
+}
@@ -0,0 +1,303 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"video/config"
+	"video/database"
+	"video/database/dbtest"
+	"video/storage"
+	"video/utils"
+)
+
+// fakeFFprobeEnv заставляет тестовый бинарник вести себя как ffprobe,
+// который думает probeDelay и падает. Так задача занимает воркер и завершается ошибкой
+// без настоящего ffmpeg.
+const (
+	fakeFFprobeEnv = "VIDEO_TEST_FAKE_FFPROBE"
+	probeDelay     = 50 * time.Millisecond
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeFFprobeEnv) != "" {
+		time.Sleep(probeDelay)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// testPool создает пул с workers воркерами поверх чистой базы.
+// ffmpeg и ffprobe подменяются тестовым бинарником, поэтому каждая попытка падает.
+func testPool(t *testing.T, workers int) (*Pool, *database.DB) {
+	t.Helper()
+	db := dbtest.Open(t)
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldTemp, oldFFmpeg, oldFFprobe := config.TemporaryDir, utils.FFmpegPath, utils.FFprobePath
+	config.TemporaryDir = t.TempDir()
+	utils.FFmpegPath, utils.FFprobePath = os.Args[0], os.Args[0]
+	t.Setenv(fakeFFprobeEnv, "1")
+	t.Cleanup(func() {
+		config.TemporaryDir, utils.FFmpegPath, utils.FFprobePath = oldTemp, oldFFmpeg, oldFFprobe
+	})
+
+	cfg := config.Default()
+	cfg.Workers = workers
+	p := NewPool(db, db, db, backend, cfg)
+	p.backoff = time.Millisecond // Паузы короче секунды SQLite не различает, повтор идет сразу
+	return p, db
+}
+
+// addJob создает видео с исходником во временной папке и ставит его задачу в очередь, не запуская пул.
+func addJob(t *testing.T, db *database.DB, name string) *database.Job {
+	t.Helper()
+	ctx := context.Background()
+	fileName := name + ".mp4"
+	if err := os.WriteFile(filepath.Join(config.TemporaryDir, fileName), []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	videoID, err := db.InsertVideo(ctx, name, name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := db.InsertJob(ctx, videoID, fileName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// start запускает пул и останавливает его в конце теста.
+func start(t *testing.T, p *Pool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := p.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		waitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := p.Wait(waitCtx); err != nil {
+			t.Errorf("воркеры не остановились: %v", err)
+		}
+	})
+}
+
+// waitFinished ждет, пока все задачи jobs завершатся, и возвращает их итоговое состояние.
+func waitFinished(t *testing.T, db *database.DB, jobs []*database.Job) []*database.Job {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		current := make([]*database.Job, len(jobs))
+		finished := true
+		for i, job := range jobs {
+			got, err := db.GetJobByID(context.Background(), job.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			current[i] = got
+			finished = finished && (got.Status == database.JobSucceeded || got.Status == database.JobFailed)
+		}
+		if finished {
+			return current
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("задачи не завершились: %+v", current)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// countingJobs считает задачи, которые воркеры взяли из очереди и еще не вернули.
+// Статусы в базе для этого не годятся: задачи читаются по одной,
+// и одна проверка может застать и завершенную задачу, и следующую за ней.
+type countingJobs struct {
+	database.JobStorage
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+}
+
+func (c *countingJobs) ClaimNextJob(ctx context.Context) (*database.Job, error) {
+	job, err := c.JobStorage.ClaimNextJob(ctx)
+	if job != nil {
+		c.mu.Lock()
+		c.running++
+		c.maxRunning = max(c.maxRunning, c.running)
+		c.mu.Unlock()
+	}
+	return job, err
+}
+
+func (c *countingJobs) FinishJob(ctx context.Context, id int, status, errorMessage string) error {
+	c.release()
+	return c.JobStorage.FinishJob(ctx, id, status, errorMessage)
+}
+
+func (c *countingJobs) RetryJob(ctx context.Context, id int, errorMessage string, delay time.Duration) error {
+	c.release()
+	return c.JobStorage.RetryJob(ctx, id, errorMessage, delay)
+}
+
+func (c *countingJobs) release() {
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+}
+
+func TestPoolConcurrency(t *testing.T) {
+	const workers = 2
+	p, db := testPool(t, workers)
+	counter := &countingJobs{JobStorage: db}
+	p.jobs = counter
+	var jobs []*database.Job
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		jobs = append(jobs, addJob(t, db, name))
+	}
+	start(t, p)
+	waitFinished(t, db, jobs)
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	if counter.maxRunning > workers {
+		t.Errorf("одновременно выполнялось %d задач при %d воркерах", counter.maxRunning, workers)
+	}
+	if counter.maxRunning < workers {
+		t.Errorf("одновременно выполнялось не больше %d задач, воркеры простаивали", counter.maxRunning)
+	}
+}
+
+func TestPoolRetries(t *testing.T) {
+	p, db := testPool(t, 1)
+	job := addJob(t, db, "broken")
+	start(t, p)
+
+	finished := waitFinished(t, db, []*database.Job{job})[0]
+	if finished.Status != database.JobFailed || finished.Attempts != maxAttempts {
+		t.Errorf("задача = %+v, ожидался статус failed после %d попыток", *finished, maxAttempts)
+	}
+	// Если бы исходник удалялся после первой ошибки, следующие попытки не нашли бы файл
+	if strings.Contains(finished.ErrorMessage, "входной файл не найден") {
+		t.Errorf("исходник удален до последней попытки: %s", finished.ErrorMessage)
+	}
+	if _, err := os.Stat(filepath.Join(config.TemporaryDir, job.FileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("исходник остался после последней попытки: %v", err)
+	}
+	video, err := db.GetVideoByID(context.Background(), job.VideoID)
+	if err != nil || video.Status != database.VideoFailed || video.ErrorMessage == "" {
+		t.Errorf("видео = %+v, %v; ожидался статус failed с текстом ошибки", video, err)
+	}
+}
+
+func TestPoolRequeuesRunningJobs(t *testing.T) {
+	ctx := context.Background()
+	p, db := testPool(t, 1)
+
+	// Задача, на которой сервер падал каждую попытку: ее больше не запускают
+	crashing := addJob(t, db, "crashing")
+	for i := 0; i < maxAttempts; i++ {
+		if _, err := db.RequeueRunningJobs(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if claimed, err := db.ClaimNextJob(ctx); err != nil || claimed.ID != crashing.ID {
+			t.Fatalf("ClaimNextJob = %+v, %v", claimed, err)
+		}
+	}
+	// Задача, которую прошлый запуск сервера взял в работу и не доделал
+	interrupted := addJob(t, db, "interrupted")
+	if claimed, err := db.ClaimNextJob(ctx); err != nil || claimed.ID != interrupted.ID {
+		t.Fatalf("ClaimNextJob = %+v, %v", claimed, err)
+	}
+	start(t, p)
+
+	finished := waitFinished(t, db, []*database.Job{interrupted, crashing})
+	if job := finished[0]; job.Status != database.JobFailed || job.Attempts != maxAttempts ||
+		strings.Contains(job.ErrorMessage, "превышено число попыток") {
+		t.Errorf("прерванная задача = %+v, ожидались оставшиеся попытки после возврата в очередь", *job)
+	}
+	if job := finished[1]; job.Status != database.JobFailed || job.Attempts != maxAttempts+1 ||
+		!strings.Contains(job.ErrorMessage, "превышено число попыток") {
+		t.Errorf("падающая задача = %+v, ожидался отказ без запуска", *job)
+	}
+	if _, err := os.Stat(filepath.Join(config.TemporaryDir, crashing.FileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("исходник падающей задачи остался: %v", err)
+	}
+}
+
+func TestPoolSkipsDeletedVideo(t *testing.T) {
+	ctx := context.Background()
+	p, db := testPool(t, 1)
+
+	// Задача уже взята воркером, когда видео удаляют
+	job := addJob(t, db, "deleted")
+	claimed, err := db.ClaimNextJob(ctx)
+	if err != nil || claimed.ID != job.ID {
+		t.Fatalf("ClaimNextJob = %+v, %v", claimed, err)
+	}
+	if err := db.DeleteVideoByID(ctx, job.VideoID); err != nil {
+		t.Fatal(err)
+	}
+	updates, cancel := p.Subscribe(job.VideoID)
+	defer cancel()
+	p.run(ctx, claimed)
+
+	if _, err := os.Stat(filepath.Join(config.TemporaryDir, job.FileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("исходник удаленного видео остался: %v", err)
+	}
+	if _, err := db.GetJobByID(ctx, job.ID); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("задача удаленного видео осталась: %v", err)
+	}
+	if _, err := db.GetVideoByID(ctx, job.VideoID); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("видео появилось снова: %v", err)
+	}
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("подписчик получил прогресс удаленного видео")
+		}
+	default:
+		t.Error("подписка на удаленное видео не закрыта")
+	}
+}
+
+func TestPoolRetryBackoff(t *testing.T) {
+	p, db := testPool(t, 1)
+	p.backoff = time.Hour
+	job := addJob(t, db, "flaky")
+	start(t, p)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		got, err := db.GetJobByID(context.Background(), job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status == database.JobQueued && got.Attempts == 1 {
+			if got.RunAfter == nil || time.Until(*got.RunAfter) < 50*time.Minute {
+				t.Errorf("повтор назначен на %v, ожидалась пауза около часа", got.RunAfter)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("задача не вернулась в очередь: %+v", *got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// До конца паузы воркер задачу не берет, даже если его разбудить
+	p.notify()
+	time.Sleep(2 * probeDelay)
+	if got, err := db.GetJobByID(context.Background(), job.ID); err != nil || got.Attempts != 1 {
+		t.Errorf("задача = %+v, %v; ожидалась одна попытка до конца паузы", got, err)
+	}
+}
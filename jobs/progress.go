@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"sync"
+	"video/utils"
+)
+
+// tracker хранит последний прогресс выполняющихся задач в памяти
+// и рассылает обновления подписчикам. Ключ - ID видео.
+type tracker struct {
+	mu       sync.Mutex
+	progress map[int]utils.Progress
+	subs     map[int]map[chan utils.Progress]struct{}
+}
+
+func newTracker() *tracker {
+	return &tracker{
+		progress: make(map[int]utils.Progress),
+		subs:     make(map[int]map[chan utils.Progress]struct{}),
+	}
+}
+
+// publish запоминает прогресс видео и отправляет его подписчикам.
+// Медленный подписчик получает только самое свежее значение.
+func (t *tracker) publish(videoID int, p utils.Progress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.progress[videoID] = p
+	for ch := range t.subs[videoID] {
+		select {
+		case <-ch: // Выбрасываем устаревшее значение
+		default:
+		}
+		ch <- p
+	}
+}
+
+// finish забывает прогресс видео и закрывает каналы подписчиков.
+func (t *tracker) finish(videoID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.progress, videoID)
+	for ch := range t.subs[videoID] {
+		close(ch)
+	}
+	delete(t.subs, videoID)
+}
+
+// get возвращает последний прогресс видео, если оно сейчас обрабатывается.
+func (t *tracker) get(videoID int) (utils.Progress, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.progress[videoID]
+	return p, ok
+}
+
+// subscribe подписывается на прогресс видео. Канал закрывается,
+// когда задача завершается. cancel нужно вызвать, если подписка больше не нужна.
+func (t *tracker) subscribe(videoID int) (updates <-chan utils.Progress, cancel func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ch := make(chan utils.Progress, 1)
+	if t.subs[videoID] == nil {
+		t.subs[videoID] = make(map[chan utils.Progress]struct{})
+	}
+	t.subs[videoID][ch] = struct{}{}
+	if p, ok := t.progress[videoID]; ok {
+		ch <- p
+	}
+
+	return ch, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if _, ok := t.subs[videoID][ch]; ok {
+			delete(t.subs[videoID], ch)
+			close(ch)
+			if len(t.subs[videoID]) == 0 {
+				delete(t.subs, videoID)
+			}
+		}
+	}
+}
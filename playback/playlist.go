@@ -0,0 +1,103 @@
+package playback
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"video/database"
+	"video/utils"
+)
+
+// TokenParam - параметр строки запроса, в котором передается токен.
+const TokenParam = "token"
+
+// uriAttribute находит ссылки в атрибутах тегов, например #EXT-X-MAP:URI="360p_init.mp4".
+var uriAttribute = regexp.MustCompile(`URI="([^"]*)"`)
+
+// RewritePlaylist добавляет токен ко всем ссылкам плейлиста HLS:
+// к строкам с URI вариантов и сегментов и к атрибутам URI="..." в тегах.
+// Без этого плеер запросил бы дочерние файлы без токена и получил отказ.
+func RewritePlaylist(playlist []byte, token string) []byte {
+	lines := bytes.Split(playlist, []byte("\n"))
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case len(trimmed) == 0:
+		case trimmed[0] == '#':
+			lines[i] = uriAttribute.ReplaceAllFunc(line, func(attr []byte) []byte {
+				uri := uriAttribute.FindSubmatch(attr)[1]
+				return []byte(`URI="` + WithToken(string(uri), token) + `"`)
+			})
+		default:
+			lines[i] = []byte(WithToken(string(trimmed), token))
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// SubtitlesGroup - GROUP-ID дорожек субтитров в мастер-плейлисте.
+const SubtitlesGroup = "subs"
+
+// AddSubtitles объявляет в мастер-плейлисте дорожки субтитров через
+// #EXT-X-MEDIA:TYPE=SUBTITLES и привязывает их ко всем вариантам качества.
+// По умолчанию включается первая дорожка с IsDefault.
+func AddSubtitles(master []byte, subtitles []database.Subtitle) []byte {
+	if len(subtitles) == 0 {
+		return master
+	}
+
+	var media bytes.Buffer
+	hasDefault := false
+	for _, s := range subtitles {
+		isDefault := s.IsDefault && !hasDefault
+		hasDefault = hasDefault || isDefault
+		fmt.Fprintf(&media, `#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="%s",NAME="%s"`, SubtitlesGroup, utils.QuotedString(s.Name))
+		if s.Language != "" {
+			fmt.Fprintf(&media, `,LANGUAGE="%s"`, utils.QuotedString(s.Language))
+		}
+		fmt.Fprintf(&media, ",DEFAULT=%s,AUTOSELECT=YES,URI=\"%s.m3u8\"\n", utils.YesNo(isDefault), utils.SubtitleBaseName(s.ID))
+	}
+
+	lines := bytes.Split(master, []byte("\n"))
+	out := make([][]byte, 0, len(lines)+1)
+	inserted := false
+	for _, line := range lines {
+		if bytes.HasPrefix(line, []byte("#EXT-X-STREAM-INF:")) {
+			if !inserted {
+				out = append(out, bytes.TrimSuffix(media.Bytes(), []byte("\n")))
+				inserted = true
+			}
+			line = []byte(string(bytes.TrimRight(line, "\r")) + `,SUBTITLES="` + SubtitlesGroup + `"`)
+		}
+		out = append(out, line)
+	}
+	return bytes.Join(out, []byte("\n"))
+}
+
+// RewriteThumbnails добавляет токен к ссылкам на спрайты в дорожке превью WebVTT.
+// Ссылки - это строки текста реплик, например "sprite_001.jpg#xywh=0,0,160,90".
+func RewriteThumbnails(vtt []byte, token string) []byte {
+	lines := bytes.Split(vtt, []byte("\n"))
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || bytes.HasPrefix(trimmed, []byte("WEBVTT")) || bytes.Contains(trimmed, []byte("-->")) {
+			continue
+		}
+		lines[i] = []byte(WithToken(string(trimmed), token))
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// WithToken добавляет токен к относительной ссылке.
+// Абсолютные ссылки ведут на чужие серверы и остаются как есть.
+func WithToken(uri, token string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.IsAbs() || u.Host != "" {
+		return uri
+	}
+	query := u.Query()
+	query.Set(TokenParam, token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
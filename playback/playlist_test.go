@@ -0,0 +1,127 @@
+package playback
+
+import (
+	"strings"
+	"testing"
+	"video/database"
+)
+
+func TestRewriteMasterPlaylist(t *testing.T) {
+	master := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="Русский",LANGUAGE="ru",DEFAULT=YES,URI="audio_0.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360,CODECS="avc1.64001e,mp4a.40.2",AUDIO="audio"
+360p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2500000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",AUDIO="audio"
+720p/index.m3u8?v=2
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080
+https://cdn.example.com/1080p.m3u8
+`
+	want := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="Русский",LANGUAGE="ru",DEFAULT=YES,URI="audio_0.m3u8?token=7.0.1.sig"
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360,CODECS="avc1.64001e,mp4a.40.2",AUDIO="audio"
+360p.m3u8?token=7.0.1.sig
+#EXT-X-STREAM-INF:BANDWIDTH=2500000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",AUDIO="audio"
+720p/index.m3u8?token=7.0.1.sig&v=2
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080
+https://cdn.example.com/1080p.m3u8
+`
+	if got := string(RewritePlaylist([]byte(master), "7.0.1.sig")); got != want {
+		t.Errorf("RewritePlaylist:\n%s\nожидалось:\n%s", got, want)
+	}
+}
+
+func TestRewriteVariantPlaylist(t *testing.T) {
+	// Строки с \r\n, как в плейлистах, сохраненных в Windows
+	variant := "#EXTM3U\r\n" +
+		"#EXT-X-TARGETDURATION:10\r\n" +
+		"#EXT-X-MAP:URI=\"360p_init.mp4\"\r\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"https://keys.example.com/k\",IV=0x1\r\n" +
+		"#EXTINF:10.000,\r\n" +
+		"360p_000.fmp4\r\n" +
+		"#EXTINF:4.500,\r\n" +
+		"  ../shared/360p_001.fmp4  \r\n" +
+		"#EXT-X-ENDLIST\r\n"
+	got := string(RewritePlaylist([]byte(variant), "tok"))
+
+	for _, line := range []string{
+		`#EXT-X-MAP:URI="360p_init.mp4?token=tok"`,
+		`URI="https://keys.example.com/k",`,
+		"\n360p_000.fmp4?token=tok\n",
+		"\n../shared/360p_001.fmp4?token=tok\n",
+		"#EXTINF:10.000,\r\n",
+		"#EXT-X-ENDLIST",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("в плейлисте нет %q:\n%s", line, got)
+		}
+	}
+	if strings.Count(got, "token=") != 3 {
+		t.Errorf("токен добавлен не к трем ссылкам:\n%s", got)
+	}
+}
+
+func TestRewriteThumbnails(t *testing.T) {
+	vtt := "WEBVTT\n\n" +
+		"00:00:00.000 --> 00:00:10.000\n" +
+		"sprite_001.jpg#xywh=0,0,160,90\n\n" +
+		"00:00:10.000 --> 00:00:20.000\n" +
+		"sprite_001.jpg#xywh=160,0,160,90\n\n" +
+		"00:16:40.000 --> 00:16:45.000\n" +
+		"https://cdn.example.com/sprite_002.jpg#xywh=0,0,160,90\n"
+	want := "WEBVTT\n\n" +
+		"00:00:00.000 --> 00:00:10.000\n" +
+		"sprite_001.jpg?token=tok#xywh=0,0,160,90\n\n" +
+		"00:00:10.000 --> 00:00:20.000\n" +
+		"sprite_001.jpg?token=tok#xywh=160,0,160,90\n\n" +
+		"00:16:40.000 --> 00:16:45.000\n" +
+		"https://cdn.example.com/sprite_002.jpg#xywh=0,0,160,90\n"
+	if got := string(RewriteThumbnails([]byte(vtt), "tok")); got != want {
+		t.Errorf("RewriteThumbnails:\n%s\nожидалось:\n%s", got, want)
+	}
+}
+
+func TestWithToken(t *testing.T) {
+	tests := []struct {
+		uri, want string
+	}{
+		{"360p.m3u8", "360p.m3u8?token=tok"},
+		{"sub/360p.m3u8?token=old", "sub/360p.m3u8?token=tok"},
+		{"/video/hls/film/main.m3u8", "/video/hls/film/main.m3u8?token=tok"},
+		{"https://cdn.example.com/a.ts", "https://cdn.example.com/a.ts"},
+		{"//cdn.example.com/a.ts", "//cdn.example.com/a.ts"},
+	}
+	for _, tt := range tests {
+		if got := WithToken(tt.uri, "tok"); got != tt.want {
+			t.Errorf("WithToken(%q) = %q, ожидалось %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestAddSubtitles(t *testing.T) {
+	master := "#EXTM3U\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=800000\r\n" +
+		"360p.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2500000\n" +
+		"720p.m3u8\n"
+	subtitles := []database.Subtitle{
+		{ID: 1, Name: `Русские "полные"`, Language: "ru", IsDefault: true},
+		{ID: 2, Name: "English", IsDefault: true},
+	}
+	got := string(RewritePlaylist(AddSubtitles([]byte(master), subtitles), "tok"))
+
+	want := "#EXTM3U\n" +
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="Русские 'полные'",LANGUAGE="ru",DEFAULT=YES,AUTOSELECT=YES,URI="sub_1.m3u8?token=tok"` + "\n" +
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",DEFAULT=NO,AUTOSELECT=YES,URI="sub_2.m3u8?token=tok"` + "\n" +
+		`#EXT-X-STREAM-INF:BANDWIDTH=800000,SUBTITLES="subs"` + "\n" +
+		"360p.m3u8?token=tok\n" +
+		`#EXT-X-STREAM-INF:BANDWIDTH=2500000,SUBTITLES="subs"` + "\n" +
+		"720p.m3u8?token=tok\n"
+	if got != want {
+		t.Errorf("AddSubtitles:\n%s\nожидалось:\n%s", got, want)
+	}
+	if out := AddSubtitles([]byte(master), nil); string(out) != master {
+		t.Errorf("AddSubtitles без субтитров изменил плейлист:\n%s", out)
+	}
+}
@@ -0,0 +1,119 @@
+// Package playback выдает и проверяет подписанные ссылки на просмотр HLS.
+// Токен несет ID видео, срок действия и, для просмотра в комнате, ID комнаты.
+// Подпись HMAC-SHA256 не дает подделать токен для чужого видео,
+// а срок действия - раздавать ссылку после окончания просмотра.
+package playback
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"video/utils"
+)
+
+// Ошибки проверки токена.
+var (
+	ErrInvalidToken = errors.New("некорректный токен просмотра")
+	ErrExpiredToken = errors.New("срок действия токена просмотра истек")
+)
+
+// Claims - данные, которые подписываются в токене.
+type Claims struct {
+	VideoID   int       `json:"video_id"`
+	RoomID    int       `json:"room_id,omitempty"` // 0 - просмотр вне комнаты
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Signer подписывает и проверяет токены одним секретным ключом.
+type Signer struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewSigner создает подписчика с ключом key; токены живут ttl.
+func NewSigner(key []byte, ttl time.Duration) *Signer {
+	return &Signer{key: key, ttl: ttl}
+}
+
+// Issue выдает токен на просмотр видео videoID, в комнате roomID, если он не 0.
+func (s *Signer) Issue(videoID, roomID int) (string, Claims) {
+	claims := Claims{
+		VideoID:   videoID,
+		RoomID:    roomID,
+		ExpiresAt: time.Now().Add(s.ttl).Truncate(time.Second),
+	}
+	return s.Sign(claims), claims
+}
+
+// Link - подписанная ссылка на мастер-плейлист видео.
+type Link struct {
+	URL        string    `json:"url"`        // Ссылка на main.m3u8 с токеном
+	Token      string    `json:"token"`      // Тот же токен отдельно
+	Poster     string    `json:"poster"`     // Обложка видео
+	Thumbnails string    `json:"thumbnails"` // Дорожка WebVTT с превью для перемотки
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Link выдает ссылку на просмотр видео videoID с HLS в папке fileName.
+func (s *Signer) Link(videoID int, fileName string, roomID int) Link {
+	token, claims := s.Issue(videoID, roomID)
+	folder := "/video/hls/" + fileName + "/"
+	return Link{
+		URL:        WithToken(folder+"main.m3u8", token),
+		Token:      token,
+		Poster:     WithToken(folder+utils.PosterFile, token),
+		Thumbnails: WithToken(folder+utils.ThumbnailsFile, token),
+		ExpiresAt:  claims.ExpiresAt,
+	}
+}
+
+// Sign кодирует claims в токен вида "<видео>.<комната>.<срок>.<подпись>".
+// Токен состоит из символов, безопасных в строке запроса.
+func (s *Signer) Sign(claims Claims) string {
+	payload := fmt.Sprintf("%d.%d.%d", claims.VideoID, claims.RoomID, claims.ExpiresAt.Unix())
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// Verify проверяет подпись и срок действия токена на момент now.
+func (s *Signer) Verify(token string, now time.Time) (Claims, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return Claims{}, ErrInvalidToken
+	}
+	payload, signature := token[:i], token[i+1:]
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.mac(payload)) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	fields := strings.Split(payload, ".")
+	if len(fields) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+	var numbers [3]int64
+	for i, field := range fields {
+		if numbers[i], err = strconv.ParseInt(field, 10, 64); err != nil {
+			return Claims{}, ErrInvalidToken
+		}
+	}
+	claims := Claims{
+		VideoID:   int(numbers[0]),
+		RoomID:    int(numbers[1]),
+		ExpiresAt: time.Unix(numbers[2], 0),
+	}
+	if !now.Before(claims.ExpiresAt) {
+		return claims, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+func (s *Signer) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
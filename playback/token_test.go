@@ -0,0 +1,124 @@
+package playback
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	signer := NewSigner([]byte("secret"), time.Hour)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		videoID int
+		roomID  int
+	}{
+		{"вне комнаты", 7, 0},
+		{"в комнате", 7, 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, issued := signer.Issue(tt.videoID, tt.roomID)
+			if strings.ContainsAny(token, "+/=?&# ") {
+				t.Errorf("токен %q небезопасен в строке запроса", token)
+			}
+			claims, err := signer.Verify(token, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.VideoID != tt.videoID || claims.RoomID != tt.roomID || !claims.ExpiresAt.Equal(issued.ExpiresAt) {
+				t.Errorf("Verify = %+v, ожидалось %+v", claims, issued)
+			}
+			if d := issued.ExpiresAt.Sub(now); d < time.Hour-time.Second || d > time.Hour {
+				t.Errorf("токен живет %v, ожидался час", d)
+			}
+		})
+	}
+}
+
+func TestVerifyExpired(t *testing.T) {
+	signer := NewSigner([]byte("secret"), time.Hour)
+	expiresAt := time.Unix(1700000000, 0)
+	token := signer.Sign(Claims{VideoID: 7, RoomID: 42, ExpiresAt: expiresAt})
+
+	if _, err := signer.Verify(token, expiresAt.Add(-time.Second)); err != nil {
+		t.Errorf("за секунду до срока: %v", err)
+	}
+	for _, now := range []time.Time{expiresAt, expiresAt.Add(time.Hour)} {
+		claims, err := signer.Verify(token, now)
+		if !errors.Is(err, ErrExpiredToken) {
+			t.Errorf("Verify на %v: %v, ожидалась ErrExpiredToken", now, err)
+		}
+		// Данные истекшего токена пригодны для журнала
+		if claims.VideoID != 7 || claims.RoomID != 42 {
+			t.Errorf("Verify истекшего = %+v", claims)
+		}
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	signer := NewSigner([]byte("secret"), time.Hour)
+	now := time.Unix(1700000000, 0)
+	claims := Claims{VideoID: 7, RoomID: 42, ExpiresAt: now.Add(time.Hour)}
+	token := signer.Sign(claims)
+	i := strings.LastIndexByte(token, '.')
+	payload, signature := token[:i], token[i+1:]
+	// Первый символ подписи задает ее старшие биты, поэтому его замена всегда меняет подпись
+	flipped := "A" + signature[1:]
+	if signature[0] == 'A' {
+		flipped = "B" + signature[1:]
+	}
+
+	// signed подписывает произвольную строку тем же ключом
+	signed := func(payload string) string {
+		return payload + "." + base64.RawURLEncoding.EncodeToString(signer.mac(payload))
+	}
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"пустой", ""},
+		{"без подписи", payload},
+		{"другое видео", strings.Replace(token, "7.", "8.", 1)},
+		{"другая комната", strings.Replace(token, ".42.", ".43.", 1)},
+		{"без комнаты", strings.Replace(token, ".42.", ".0.", 1)},
+		{"продленный срок", strings.Replace(token, ".1700003600.", ".1800000000.", 1)},
+		{"измененная подпись", payload + "." + flipped},
+		{"обрезанная подпись", token[:len(token)-2]},
+		{"подпись не base64", payload + ".!!!"},
+		{"другой ключ", NewSigner([]byte("other"), time.Hour).Sign(claims)},
+		{"лишнее поле", signed("7.42.1700003600.1")},
+		{"не хватает поля", signed("7.1700003600")},
+		{"не число", signed("7.x.1700003600")},
+	}
+	for _, tt := range tests {
+		if tt.token == token {
+			t.Fatalf("%s: токен не изменился", tt.name)
+		}
+		if _, err := signer.Verify(tt.token, now); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: Verify(%q) = %v, ожидалась ErrInvalidToken", tt.name, tt.token, err)
+		}
+	}
+}
+
+func TestLink(t *testing.T) {
+	signer := NewSigner([]byte("secret"), time.Hour)
+	link := signer.Link(7, "film", 42)
+
+	claims, err := signer.Verify(link.Token, time.Now())
+	if err != nil || claims.VideoID != 7 || claims.RoomID != 42 {
+		t.Fatalf("Verify токена ссылки = %+v, %v", claims, err)
+	}
+	for _, url := range []string{link.URL, link.Poster, link.Thumbnails} {
+		if !strings.HasPrefix(url, "/video/hls/film/") || !strings.HasSuffix(url, "?token="+link.Token) {
+			t.Errorf("ссылка %q не в папке видео или без токена", url)
+		}
+	}
+	if !strings.HasPrefix(link.URL, "/video/hls/film/main.m3u8?") {
+		t.Errorf("URL = %q, ожидался мастер-плейлист", link.URL)
+	}
+}
@@ -0,0 +1,100 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"video/database"
+)
+
+// maxChatLength - максимальная длина сообщения чата в символах.
+const maxChatLength = 1000
+
+// Ошибки проверки текста сообщения чата.
+var (
+	errChatEmpty   = errors.New("пустое сообщение чата")
+	errChatTooLong = fmt.Errorf("сообщение чата длиннее %d символов", maxChatLength)
+)
+
+// chat сохраняет сообщение клиента c и рассылает его всем участникам комнаты
+// вместе с позицией воспроизведения в момент получения.
+func (h *Hub) chat(s *session, c *client, text string) {
+	author := s.member(c)
+	if !h.policy.Allows(author.Role, ActionChat) {
+		c.reject("Your role is not allowed to chat")
+		return
+	}
+	text, err := sanitizeChat(text)
+	switch {
+	case errors.Is(err, errChatEmpty):
+		c.reject("Message is empty")
+		return
+	case errors.Is(err, errChatTooLong):
+		c.reject(fmt.Sprintf("Message must be at most %d characters", maxChatLength))
+		return
+	}
+
+	s.mu.Lock()
+	position := s.clock.Snapshot().Position
+	videoID := 0
+	if s.video != nil {
+		videoID = s.video.ID
+	}
+	s.mu.Unlock()
+
+	message, err := h.chatStorage.InsertMessage(context.Background(), database.Message{
+		RoomID:     author.RoomID,
+		MemberID:   author.ID,
+		MemberName: author.Name,
+		VideoID:    videoID,
+		Position:   position,
+		Text:       text,
+	})
+	if err != nil {
+		slog.Error("Не удалось сохранить сообщение чата",
+			"room_id", author.RoomID,
+			"member_id", author.ID,
+			"ошибка", err,
+		)
+		c.reject("Failed to send message")
+		return
+	}
+	s.broadcast(Event{Type: EventChat, MemberID: author.ID, MemberName: author.Name, Message: message})
+}
+
+// sanitizeChat приводит текст сообщения к безопасному виду: убирает управляющие
+// символы и символы смены направления текста, которыми подделывают чужой текст,
+// схлопывает пустые строки и обрезает пробелы по краям. Разметку сервер не трогает:
+// клиент обязан выводить сообщение как обычный текст.
+// Возвращает errChatEmpty или errChatTooLong, если текст не годится.
+func sanitizeChat(text string) (string, error) {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case r == '\t':
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Bidi_Control, r):
+			return -1
+		}
+		return r
+	}, text)
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	text = strings.TrimSpace(text)
+
+	if text == "" {
+		return "", errChatEmpty
+	}
+	if utf8.RuneCountInString(text) > maxChatLength {
+		return "", errChatTooLong
+	}
+	return text, nil
+}
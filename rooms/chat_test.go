@@ -0,0 +1,45 @@
+package rooms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeChat(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+		err  error
+	}{
+		{"обычный текст", "Привет!", "Привет!", nil},
+		{"пробелы по краям", "  \n привет \t\n", "привет", nil},
+		{"табуляция", "a\tb", "a b", nil},
+		{"управляющие символы", "a\x00b\x07c\x1bd\x7f", "abcd", nil},
+		{"смена направления текста", "abc\u202edcba\u2066x\u2069", "abcdcbax", nil},
+		{"CRLF", "первая\r\nвторая", "первая\nвторая", nil},
+		{"одиночный CR", "a\rb", "ab", nil},
+		{"пустые строки схлопываются", "a\n\n\n\n\nb", "a\n\nb", nil},
+		{"две пустые строки остаются", "a\n\nb", "a\n\nb", nil},
+		{"некорректный UTF-8", "a\xffb", "ab", nil},
+		{"разметка не трогается", "<b>жирный</b>", "<b>жирный</b>", nil},
+		{"пустое", "", "", errChatEmpty},
+		{"только пробелы", " \n\t ", "", errChatEmpty},
+		{"только управляющие символы", "\x00\u202e", "", errChatEmpty},
+		{"ровно предел латиницей", strings.Repeat("a", maxChatLength), strings.Repeat("a", maxChatLength), nil},
+		{"длиннее предела", strings.Repeat("a", maxChatLength+1), "", errChatTooLong},
+		// Кириллица занимает два байта на символ: предел считается в символах, а не в байтах
+		{"ровно предел кириллицей", strings.Repeat("я", maxChatLength), strings.Repeat("я", maxChatLength), nil},
+		{"кириллица длиннее предела", strings.Repeat("я", maxChatLength+1), "", errChatTooLong},
+		{"эмодзи считаются символами", strings.Repeat("🎉", maxChatLength), strings.Repeat("🎉", maxChatLength), nil},
+		{"предел после обрезки пробелов", "  " + strings.Repeat("a", maxChatLength) + "  ", strings.Repeat("a", maxChatLength), nil},
+		{"предел после удаления управляющих символов", strings.Repeat("a\x00", maxChatLength), strings.Repeat("a", maxChatLength), nil},
+	}
+	for _, tt := range tests {
+		got, err := sanitizeChat(tt.text)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%s: sanitizeChat = %q, %v; ожидалось %q, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
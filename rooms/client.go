@@ -0,0 +1,133 @@
+package rooms
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+	"video/database"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second    // Время на запись одного сообщения
+	pongWait       = 60 * time.Second    // Сколько ждём pong от клиента
+	pingPeriod     = (pongWait * 9) / 10 // Как часто шлём ping, должно быть меньше pongWait
+	maxMessageSize = 16 << 10            // Максимальный размер входящего сообщения, с запасом на чат в \uXXXX
+	sendBufferSize = 64                  // Размер очереди исходящих сообщений
+)
+
+// client - одно WebSocket-подключение участника комнаты.
+// Роль в member меняется, пока клиент подключен, поэтому member читается под session.mu.
+type client struct {
+	conn     *websocket.Conn
+	member   database.Member
+	outgoing chan Event
+
+	mu     sync.Mutex // Защищает отправку в outgoing от его закрытия
+	closed bool
+}
+
+func newClient(conn *websocket.Conn, member database.Member) *client {
+	return &client{
+		conn:     conn,
+		member:   member,
+		outgoing: make(chan Event, sendBufferSize),
+	}
+}
+
+// send ставит событие в очередь на отправку.
+// Медленный клиент, у которого переполнилась очередь, отключается.
+func (c *client) send(e Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.outgoing <- e:
+	default:
+		slog.Warn("Очередь клиента переполнена, отключаем",
+			"room_id", c.member.RoomID,
+			"member_id", c.member.ID,
+		)
+		c.conn.Close()
+	}
+}
+
+// reject сообщает клиенту, что его событие отклонено, и почему.
+func (c *client) reject(reason string) {
+	c.send(Event{Type: EventError, Error: reason, ServerTime: serverTime()})
+}
+
+// close закрывает очередь отправки, после чего writePump завершается.
+// Отправленное после закрытия отбрасывается.
+func (c *client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.outgoing)
+	}
+}
+
+// readPump читает события клиента и передает корректные в handle.
+// Возвращается, когда соединение закрыто.
+func (c *client) readPump(handle func(Event)) {
+	defer c.conn.Close()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var e Event
+		if err := c.conn.ReadJSON(&e); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				slog.Warn("Ошибка чтения из WebSocket",
+					"member_id", c.member.ID,
+					"ошибка", err,
+				)
+			}
+			return
+		}
+		if err := e.validate(); err != nil {
+			slog.Warn("Некорректное событие синхронизации",
+				"member_id", c.member.ID,
+				"ошибка", err,
+			)
+			continue
+		}
+		handle(e)
+	}
+}
+
+// writePump отправляет события из очереди и периодически пингует клиента.
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case e, ok := <-c.outgoing:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package rooms
+
+import (
+	"sync"
+	"time"
+)
+
+// State - снимок состояния воспроизведения в комнате на момент Event.ServerTime.
+// Клиент вычисляет текущую позицию как
+// Position + (серверное_время_сейчас - ServerTime) * Rate, если не Paused.
+type State struct {
+	Position float64 `json:"position"` // Позиция в видео, секунды
+	Paused   bool    `json:"paused"`   // Стоит ли воспроизведение на паузе
+	Rate     float64 `json:"rate"`     // Скорость воспроизведения
+}
+
+// Clock - авторитетные часы воспроизведения комнаты.
+// Позиция хранится на момент последнего изменения и экстраполируется
+// по монотонному времени, поэтому перевод системных часов на нее не влияет.
+type Clock struct {
+	mu        sync.Mutex
+	position  float64
+	paused    bool
+	rate      float64
+	updatedAt time.Time        // Содержит монотонные показания time.Now()
+	now       func() time.Time // Источник времени, в тестах подменяется
+}
+
+// NewClock создает часы, стоящие на паузе в начале видео.
+func NewClock() *Clock {
+	return newClock(time.Now)
+}
+
+// newClock создает часы на паузе в начале видео, берущие время из now.
+func newClock(now func() time.Time) *Clock {
+	return &Clock{paused: true, rate: 1, updatedAt: now(), now: now}
+}
+
+// Snapshot возвращает состояние воспроизведения на текущий момент.
+func (c *Clock) Snapshot() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stateAt(c.now())
+}
+
+// Apply применяет событие управления воспроизведением и возвращает новое состояние.
+func (c *Clock) Apply(e Event) State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	current := c.stateAt(now)
+	switch e.Type {
+	case EventPlay:
+		c.position, c.paused = e.Position, false
+	case EventPause:
+		c.position, c.paused = e.Position, true
+	case EventSeek:
+		c.position = e.Position
+	case EventRate:
+		c.position, c.rate = current.Position, e.Rate
+	}
+	c.updatedAt = now
+	return c.stateAt(now)
+}
+
+// Restart запускает воспроизведение нового видео с начала в момент startAt.
+// До startAt позиция остается нулевой, скорость сохраняется.
+func (c *Clock) Restart(startAt time.Time) State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.position, c.paused, c.updatedAt = 0, false, startAt
+	return c.stateAt(c.now())
+}
+
+// EndsAt возвращает момент, когда воспроизведение дойдет до позиции duration.
+// ok = false, если воспроизведение стоит на паузе.
+func (c *Clock) EndsAt(duration float64) (endsAt time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		return time.Time{}, false
+	}
+	remaining := (duration - c.position) / c.rate
+	return c.updatedAt.Add(time.Duration(remaining * float64(time.Second))), true
+}
+
+// stateAt экстраполирует позицию на момент now. Вызывается под c.mu.
+func (c *Clock) stateAt(now time.Time) State {
+	position := c.position
+	if !c.paused && now.After(c.updatedAt) {
+		position += now.Sub(c.updatedAt).Seconds() * c.rate
+	}
+	return State{Position: position, Paused: c.paused, Rate: c.rate}
+}
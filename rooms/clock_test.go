@@ -0,0 +1,110 @@
+package rooms
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeTime - управляемый тестом источник времени для Clock.
+type fakeTime struct {
+	t time.Time
+}
+
+func (f *fakeTime) now() time.Time { return f.t }
+
+func (f *fakeTime) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func TestClockApply(t *testing.T) {
+	clock := &fakeTime{t: time.Unix(1_700_000_000, 0)}
+	c := newClock(clock.now)
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		event   *Event // nil - только Snapshot
+		want    State
+	}{
+		{"начальное состояние", 0, nil, State{Position: 0, Paused: true, Rate: 1}},
+		{"на паузе позиция стоит", 5 * time.Second, nil, State{Position: 0, Paused: true, Rate: 1}},
+		{"play", 0, &Event{Type: EventPlay, Position: 10}, State{Position: 10, Paused: false, Rate: 1}},
+		{"воспроизведение идет", 2 * time.Second, nil, State{Position: 12, Paused: false, Rate: 1}},
+		{"rate от текущей позиции", 0, &Event{Type: EventRate, Rate: 2}, State{Position: 12, Paused: false, Rate: 2}},
+		{"двойная скорость", 3 * time.Second, nil, State{Position: 18, Paused: false, Rate: 2}},
+		{"seek во время воспроизведения", 0, &Event{Type: EventSeek, Position: 100}, State{Position: 100, Paused: false, Rate: 2}},
+		{"после seek", time.Second, nil, State{Position: 102, Paused: false, Rate: 2}},
+		{"pause", 0, &Event{Type: EventPause, Position: 50}, State{Position: 50, Paused: true, Rate: 2}},
+		{"на паузе позиция стоит при любой скорости", 10 * time.Second, nil, State{Position: 50, Paused: true, Rate: 2}},
+		{"seek на паузе", 0, &Event{Type: EventSeek, Position: 70}, State{Position: 70, Paused: true, Rate: 2}},
+		{"rate на паузе", 0, &Event{Type: EventRate, Rate: 0.5}, State{Position: 70, Paused: true, Rate: 0.5}},
+		{"play с нуля", 0, &Event{Type: EventPlay, Position: 0}, State{Position: 0, Paused: false, Rate: 0.5}},
+		{"половинная скорость", 4 * time.Second, nil, State{Position: 2, Paused: false, Rate: 0.5}},
+	}
+	for _, tt := range tests {
+		clock.advance(tt.advance)
+		var got State
+		if tt.event != nil {
+			got = c.Apply(*tt.event)
+		} else {
+			got = c.Snapshot()
+		}
+		if got != tt.want {
+			t.Errorf("%s: состояние = %+v, ожидалось %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClockRestart(t *testing.T) {
+	clock := &fakeTime{t: time.Unix(1_700_000_000, 0)}
+	c := newClock(clock.now)
+	c.Apply(Event{Type: EventRate, Rate: 2})
+	c.Apply(Event{Type: EventPlay, Position: 40})
+
+	startAt := clock.now().Add(3 * time.Second)
+	if got, want := c.Restart(startAt), (State{Position: 0, Paused: false, Rate: 2}); got != want {
+		t.Errorf("Restart = %+v, ожидалось %+v", got, want)
+	}
+	// До старта позиция остается в начале
+	clock.advance(2 * time.Second)
+	if got := c.Snapshot(); got.Position != 0 || got.Paused {
+		t.Errorf("до старта: %+v, ожидалось воспроизведение с нуля", got)
+	}
+	clock.advance(2 * time.Second)
+	if got := c.Snapshot(); got.Position != 2 {
+		t.Errorf("через секунду после старта: позиция %v, ожидалась 2", got.Position)
+	}
+	if endsAt, ok := c.EndsAt(10); !ok || !endsAt.Equal(startAt.Add(5*time.Second)) {
+		t.Errorf("EndsAt(10) = %v, %v; ожидалось %v", endsAt, ok, startAt.Add(5*time.Second))
+	}
+}
+
+func TestClockEndsAt(t *testing.T) {
+	clock := &fakeTime{t: time.Unix(1_700_000_000, 0)}
+	c := newClock(clock.now)
+
+	if _, ok := c.EndsAt(100); ok {
+		t.Error("EndsAt на паузе вернул ok")
+	}
+
+	tests := []struct {
+		rate float64
+		want time.Duration
+	}{
+		{1, 90 * time.Second},
+		{2, 45 * time.Second},
+		{0.5, 180 * time.Second},
+		{1.5, 60 * time.Second},
+	}
+	for _, tt := range tests {
+		c.Apply(Event{Type: EventRate, Rate: tt.rate})
+		c.Apply(Event{Type: EventPlay, Position: 10})
+		endsAt, ok := c.EndsAt(100)
+		if !ok || !endsAt.Equal(clock.now().Add(tt.want)) {
+			t.Errorf("скорость %v: EndsAt = %v, %v; ожидалось через %v", tt.rate, endsAt, ok, tt.want)
+		}
+		// Конец не сдвигается, пока воспроизведение идет без изменений
+		clock.advance(5 * time.Second)
+		if later, _ := c.EndsAt(100); !later.Equal(endsAt) {
+			t.Errorf("скорость %v: через 5 секунд EndsAt = %v, было %v", tt.rate, later, endsAt)
+		}
+	}
+}
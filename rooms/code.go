@@ -0,0 +1,26 @@
+// Package rooms содержит логику комнат совместного просмотра.
+package rooms
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// CodeLength - длина кода приглашения в комнату.
+const CodeLength = 6
+
+// NewInviteCode генерирует короткий код приглашения в комнату.
+// Код состоит из символов base32 (A-Z, 2-7), поэтому его легко продиктовать.
+func NewInviteCode() string {
+	return rand.Text()[:CodeLength]
+}
+
+// NormalizeCode приводит введённый пользователем код к каноническому виду.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// NewMemberToken генерирует секретный токен участника комнаты.
+func NewMemberToken() string {
+	return rand.Text()
+}
@@ -0,0 +1,107 @@
+package rooms
+
+import (
+	"fmt"
+	"time"
+	"video/database"
+	"video/playback"
+)
+
+// Типы событий синхронизации воспроизведения.
+const (
+	EventPlay   = "play"   // Запуск воспроизведения с позиции Position
+	EventPause  = "pause"  // Пауза на позиции Position
+	EventSeek   = "seek"   // Перемотка на позицию Position
+	EventRate   = "rate"   // Изменение скорости воспроизведения на Rate
+	EventPing   = "ping"   // Замер задержки: клиент присылает ClientTime
+	EventPong   = "pong"   // Ответ на ping, отправляется только автору
+	EventState  = "state"  // Текущее состояние: при подключении и периодически
+	EventJoined = "joined" // Участник подключился к комнате (рассылает сервер)
+	EventLeft   = "left"   // Участник отключился от комнаты (рассылает сервер)
+	EventEnded  = "ended"  // Видео VideoID досмотрено; от сервера - очередь закончилась
+	EventNext   = "next"   // Комната переключилась на VideoID, старт в StartAt (рассылает сервер)
+	EventQueue  = "queue"  // Очередь комнаты изменилась (рассылает сервер)
+	EventKick   = "kick"   // Исключить участника TargetID; сервер рассылает, когда его исключили
+	EventRole   = "role"   // Назначить участнику TargetID роль Role; сервер рассылает, когда роль сменилась
+	EventError  = "error"  // Событие отклонено, отправляется только автору
+	EventChat   = "chat"   // Сообщение чата: клиент присылает Text, сервер рассылает Message
+	EventReact  = "react"  // Реакция Emoji: сервер рассылает ее с позицией и видео
+)
+
+// Event - сообщение канала синхронизации комнаты.
+// Клиент присылает Type, Position, Rate, VideoID, TargetID, Role, Text, Emoji и ClientTime,
+// остальные поля заполняет сервер. События без автора (MemberID) создал сам сервер.
+//
+// Ping/pong устроен как в NTP: клиент отправляет ping в момент t0 (ClientTime),
+// сервер получает его в t1 (ReceiveTime) и отвечает в t2 (ServerTime),
+// клиент получает pong в t3. Тогда задержка = ((t3 - t0) - (t2 - t1)) / 2,
+// а смещение часов = ((t1 - t0) + (t2 - t3)) / 2.
+type Event struct {
+	Type        string  `json:"type"`
+	Position    float64 `json:"position"`               // Позиция в видео, секунды; 0 - тоже позиция, поэтому без omitempty
+	Rate        float64 `json:"rate"`                   // Скорость воспроизведения, 1 - обычная
+	State       *State  `json:"state,omitempty"`        // Авторитетное состояние после события
+	VideoID     int     `json:"video_id,omitempty"`     // Видео, к которому относится событие
+	MemberID    int     `json:"member_id,omitempty"`    // Автор события
+	MemberName  string  `json:"member_name,omitempty"`  // Имя автора события
+	TargetID    int     `json:"target_id,omitempty"`    // Участник, к которому относится EventKick и EventRole
+	Role        string  `json:"role,omitempty"`         // Роль для EventRole и EventJoined
+	Text        string  `json:"text,omitempty"`         // Текст сообщения EventChat от клиента
+	Emoji       string  `json:"emoji,omitempty"`        // Эмодзи реакции EventReact
+	ClientTime  int64   `json:"client_time,omitempty"`  // Время клиента при отправке ping, Unix мс
+	ReceiveTime int64   `json:"receive_time,omitempty"` // Время сервера при получении ping, Unix мс
+	ServerTime  int64   `json:"server_time"`            // Время сервера в момент отправки, Unix мс
+
+	// Только в событиях от сервера
+	Playback *playback.Link       `json:"playback,omitempty"` // Ссылка на новое видео для EventNext
+	StartAt  int64                `json:"start_at,omitempty"` // Когда всем начать новое видео, Unix мс
+	Queue    []database.QueueItem `json:"queue,omitempty"`    // Очередь после EventQueue, нет поля - очередь пуста
+	Error    string               `json:"error,omitempty"`    // Почему отклонено событие для EventError
+	Message  *database.Message    `json:"message,omitempty"`  // Сохраненное сообщение для EventChat
+}
+
+// validate проверяет событие, присланное клиентом.
+func (e *Event) validate() error {
+	switch e.Type {
+	case EventPlay, EventPause, EventSeek:
+		if e.Position < 0 {
+			return fmt.Errorf("отрицательная позиция: %f", e.Position)
+		}
+	case EventRate:
+		if e.Rate <= 0 || e.Rate > 4 {
+			return fmt.Errorf("недопустимая скорость: %f", e.Rate)
+		}
+	case EventEnded:
+		if e.VideoID <= 0 {
+			return fmt.Errorf("не указано досмотренное видео")
+		}
+	case EventKick:
+		if e.TargetID <= 0 {
+			return fmt.Errorf("не указан исключаемый участник")
+		}
+	case EventRole:
+		if e.TargetID <= 0 {
+			return fmt.Errorf("не указан участник для смены роли")
+		}
+		if database.RoleRank(e.Role) == 0 {
+			return fmt.Errorf("неизвестная роль: %q", e.Role)
+		}
+	case EventChat:
+		if e.Text == "" {
+			return fmt.Errorf("пустое сообщение чата")
+		}
+	case EventReact:
+		if !isEmoji(e.Emoji) {
+			return fmt.Errorf("реакция не эмодзи: %q", e.Emoji)
+		}
+	case EventPing:
+	default:
+		return fmt.Errorf("неизвестный тип события: %q", e.Type)
+	}
+	return nil
+}
+
+// serverTime возвращает текущее время сервера в Unix миллисекундах.
+func serverTime() int64 {
+	return time.Now().UnixMilli()
+}
@@ -0,0 +1,20 @@
+package rooms
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEventZeroPosition(t *testing.T) {
+	// Перемотка в начало должна дойти до клиента с позицией, а не без поля
+	data, err := json.Marshal(Event{Type: EventSeek, Position: 0, Rate: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"position":0`, `"rate":0`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("в событии %s нет %s", data, field)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package rooms
+
+import "time"
+
+// SetHostGracePeriod задает, сколько Hub ждет переподключения хозяина.
+// Вызывается до подключения участников.
+func (h *Hub) SetHostGracePeriod(d time.Duration) {
+	h.hostGrace = d
+}
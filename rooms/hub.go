@@ -0,0 +1,478 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"video/database"
+	"video/playback"
+
+	"github.com/gorilla/websocket"
+)
+
+// heartbeatPeriod - как часто сервер рассылает авторитетное состояние.
+// Должен быть заметно меньше длительности HLS-сегмента (10 секунд),
+// чтобы клиенты успевали подстроиться до загрузки следующего сегмента.
+const heartbeatPeriod = 5 * time.Second
+
+// nextVideoDelay - через сколько после переключения на следующее видео всем его запускать.
+// За это время клиенты успевают загрузить мастер-плейлист и первые сегменты.
+const nextVideoDelay = 3 * time.Second
+
+// sessionIdleTimeout - сколько сессия опустевшей комнаты хранится в памяти.
+// За это время участники успевают переподключиться и продолжить с той же позиции.
+const sessionIdleTimeout = time.Minute
+
+// Hub хранит активные комнаты: их часы воспроизведения и WebSocket-подключения.
+// Когда видео комнаты заканчивается, Hub переключает всех на следующее из очереди.
+type Hub struct {
+	roomStorage     database.RoomStorage
+	queueStorage    database.QueueStorage
+	videoStorage    database.VideoStorage
+	chatStorage     database.MessageStorage
+	reactionStorage database.ReactionStorage
+	signer          *playback.Signer
+	policy          Policy
+	hostGrace       time.Duration // hostGracePeriod, в тестах короче
+
+	mu    sync.Mutex
+	rooms map[int]*session
+}
+
+// session - активная комната с подключенными клиентами.
+// Опустевшая сессия живет еще sessionIdleTimeout, чтобы позиция воспроизведения
+// переживала кратковременные отключения всех участников, а затем забывается.
+type session struct {
+	clock *Clock
+	onEnd func(seq int) // Вызывается, когда досмотрено видео с номером переключения seq
+
+	mu       sync.Mutex
+	clients  map[*client]struct{}
+	stop     chan struct{}    // Закрывается, когда отключается последний клиент
+	idle     *time.Timer      // Забывает опустевшую сессию через sessionIdleTimeout
+	video    *database.Video  // Текущее видео комнаты, nil - еще не загружено
+	videoSeq int              // Room.VideoSeq текущего видео
+	endTimer *time.Timer      // Срабатывает в конце текущего видео
+	endGen   int              // Номер последнего запуска endTimer, отсекает устаревшие срабатывания
+	limits   map[int]*limiter // Ограничение частоты реакций по ID участника
+}
+
+// NewHub создает пустой Hub. policy задает, что разрешено участникам с каждой ролью.
+func NewHub(roomStorage database.RoomStorage, queueStorage database.QueueStorage, videoStorage database.VideoStorage, chatStorage database.MessageStorage, reactionStorage database.ReactionStorage, signer *playback.Signer, policy Policy) *Hub {
+	return &Hub{
+		roomStorage:     roomStorage,
+		queueStorage:    queueStorage,
+		videoStorage:    videoStorage,
+		chatStorage:     chatStorage,
+		reactionStorage: reactionStorage,
+		signer:          signer,
+		policy:          policy,
+		hostGrace:       hostGracePeriod,
+		rooms:           make(map[int]*session),
+	}
+}
+
+// Serve обслуживает подключение участника member к его комнате
+// и блокируется до отключения клиента.
+func (h *Hub) Serve(conn *websocket.Conn, member database.Member) {
+	c := newClient(conn, member)
+	s := h.attach(member.RoomID, c)
+	h.loadVideo(s, member.RoomID)
+
+	slog.Info("Участник подключился к синхронизации",
+		"room_id", member.RoomID,
+		"member_id", member.ID,
+	)
+	state := s.clock.Snapshot()
+	c.send(Event{Type: EventState, State: &state, ServerTime: serverTime()})
+	s.broadcast(Event{Type: EventJoined, MemberID: member.ID, MemberName: member.Name, Role: member.Role})
+
+	go c.writePump()
+	c.readPump(func(e Event) {
+		switch e.Type {
+		case EventPing:
+			receivedAt := serverTime()
+			c.send(Event{
+				Type:        EventPong,
+				ClientTime:  e.ClientTime,
+				ReceiveTime: receivedAt,
+				ServerTime:  serverTime(),
+			})
+			return
+		case EventEnded:
+			// Окончание видео переключает очередь за всех, поэтому учитывается только
+			// от тех, кому разрешено управлять очередью. Зрителям ошибка не отправляется:
+			// их плееры сообщают о конце видео сами.
+			if h.policy.Allows(s.member(c).Role, ActionQueue) {
+				s.clientEnded(e.VideoID)
+			}
+			return
+		case EventKick:
+			h.kick(s, c, e.TargetID)
+			return
+		case EventRole:
+			h.setRole(s, c, e.TargetID, e.Role)
+			return
+		case EventChat:
+			h.chat(s, c, e.Text)
+			return
+		case EventReact:
+			h.react(s, c, e.Emoji)
+			return
+		}
+
+		author := s.member(c)
+		if !h.policy.Allows(author.Role, e.Type) {
+			c.reject("Your role is not allowed to " + e.Type)
+			return
+		}
+		e.MemberID = author.ID
+		e.MemberName = author.Name
+		s.apply(e)
+	})
+
+	wasHost := s.member(c).Role == database.RoleHost
+	h.detach(member.RoomID, c)
+	s.broadcast(Event{Type: EventLeft, MemberID: member.ID, MemberName: member.Name})
+	slog.Info("Участник отключился от синхронизации",
+		"room_id", member.RoomID,
+		"member_id", member.ID,
+	)
+	if wasHost {
+		// Хозяин мог просто перезагрузить страницу, поэтому роль передается не сразу
+		time.AfterFunc(h.hostGrace, func() {
+			h.handOverHost(context.Background(), member.RoomID, member.ID, true)
+		})
+	}
+}
+
+// Close закрывает комнату: отключает всех клиентов и забывает ее состояние.
+func (h *Hub) Close(roomID int) {
+	h.mu.Lock()
+	s, ok := h.rooms[roomID]
+	delete(h.rooms, roomID)
+	h.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopEndTimer()
+	s.stopHeartbeat()
+	s.stopIdle()
+	for c := range s.clients {
+		c.conn.Close()
+	}
+}
+
+// Skip переключает комнату на следующее видео из очереди, не дожидаясь конца текущего.
+// Если очередь пуста, возвращает database.ErrQueueEmpty.
+func (h *Hub) Skip(ctx context.Context, roomID int) (*database.Room, error) {
+	room, err := h.roomStorage.GetRoomByID(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	return h.next(ctx, roomID, room.VideoSeq)
+}
+
+// QueueChanged рассылает участникам комнаты ее новую очередь.
+func (h *Hub) QueueChanged(roomID int, queue []database.QueueItem) {
+	if s := h.session(roomID); s != nil {
+		s.broadcast(Event{Type: EventQueue, Queue: queue})
+	}
+}
+
+// session возвращает сессию комнаты или nil, если к ней никто не подключался.
+func (h *Hub) session(roomID int) *session {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rooms[roomID]
+}
+
+// loadVideo загружает текущее видео комнаты, если сессия его еще не знает.
+// Без видео сессия работает, но не замечает его окончания.
+func (h *Hub) loadVideo(s *session, roomID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.video != nil {
+		return
+	}
+
+	ctx := context.Background()
+	room, err := h.roomStorage.GetRoomByID(ctx, roomID)
+	if err == nil {
+		s.video, err = h.videoStorage.GetVideoByID(ctx, room.VideoID)
+		s.videoSeq = room.VideoSeq
+	}
+	if err != nil {
+		slog.Error("Не удалось загрузить видео комнаты",
+			"room_id", roomID,
+			"ошибка", err,
+		)
+	}
+}
+
+// next переключает комнату с видео, показанного fromSeq-м, на первое из очереди
+// и рассылает участникам ссылку на него с общим моментом старта.
+func (h *Hub) next(ctx context.Context, roomID, fromSeq int) (*database.Room, error) {
+	room, err := h.queueStorage.AdvanceQueue(ctx, roomID, fromSeq)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Комната переключилась на следующее видео",
+		"room_id", roomID,
+		"video_id", room.VideoID,
+	)
+
+	s := h.session(roomID)
+	if s == nil {
+		return room, nil
+	}
+	video, err := h.videoStorage.GetVideoByID(ctx, room.VideoID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить следующее видео комнаты: %w", err)
+	}
+	queue, err := h.queueStorage.GetQueue(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить очередь комнаты: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.video = video
+	s.videoSeq = room.VideoSeq
+	startAt := time.Now().Add(nextVideoDelay)
+	state := s.clock.Restart(startAt)
+	link := h.signer.Link(video.ID, video.FileName, roomID)
+	s.sendAll(Event{
+		Type:     EventNext,
+		VideoID:  video.ID,
+		Playback: &link,
+		StartAt:  startAt.UnixMilli(),
+		State:    &state,
+	})
+	s.sendAll(Event{Type: EventQueue, Queue: queue})
+	s.scheduleEnd()
+	return room, nil
+}
+
+// videoEnded переключает комнату на следующее видео, когда досмотрено видео
+// с номером переключения seq. Если очередь пуста, воспроизведение останавливается в конце видео.
+func (h *Hub) videoEnded(roomID, seq int) {
+	_, err := h.next(context.Background(), roomID, seq)
+	switch {
+	case errors.Is(err, database.ErrQueueEmpty):
+		if s := h.session(roomID); s != nil {
+			s.finish(seq)
+		}
+	case errors.Is(err, database.ErrStale):
+		// Комнату уже переключили: пропуском или окончанием, о котором сообщил другой клиент
+	case err != nil:
+		slog.Error("Не удалось переключить комнату на следующее видео",
+			"room_id", roomID,
+			"video_seq", seq,
+			"ошибка", err,
+		)
+	}
+}
+
+// attach регистрирует клиента в комнате, создавая сессию при необходимости.
+func (h *Hub) attach(roomID int, c *client) *session {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.rooms[roomID]
+	if !ok {
+		s = &session{
+			clock:   NewClock(),
+			onEnd:   func(seq int) { h.videoEnded(roomID, seq) },
+			clients: make(map[*client]struct{}),
+			limits:  make(map[int]*limiter),
+		}
+		h.rooms[roomID] = s
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) == 0 {
+		s.stopIdle()
+		s.stop = make(chan struct{})
+		go s.heartbeat(s.stop)
+	}
+	s.clients[c] = struct{}{}
+	return s
+}
+
+// detach удаляет клиента из комнаты. Если комната опустела, рассылка состояния
+// останавливается, а через sessionIdleTimeout сессия забывается.
+func (h *Hub) detach(roomID int, c *client) {
+	h.mu.Lock()
+	s, ok := h.rooms[roomID]
+	h.mu.Unlock()
+	if ok {
+		s.mu.Lock()
+		// Исключенного участника kick уже удалил из комнаты
+		if _, attached := s.clients[c]; attached {
+			delete(s.clients, c)
+			if len(s.clients) == 0 {
+				s.stopHeartbeat()
+				s.idle = time.AfterFunc(sessionIdleTimeout, func() { h.evict(roomID, s) })
+			}
+		}
+		s.mu.Unlock()
+	}
+	c.close()
+}
+
+// evict забывает сессию s комнаты roomID, если к ней так никто и не подключился.
+func (h *Hub) evict(roomID int, s *session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h.rooms[roomID] != s || len(s.clients) > 0 {
+		return
+	}
+	delete(h.rooms, roomID)
+	s.stopEndTimer()
+	slog.Info("Сессия опустевшей комнаты забыта", "room_id", roomID)
+}
+
+// stopHeartbeat останавливает рассылку состояния. Вызывается под s.mu.
+func (s *session) stopHeartbeat() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// stopIdle отменяет забывание опустевшей сессии. Вызывается под s.mu.
+func (s *session) stopIdle() {
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+	}
+}
+
+// heartbeat периодически рассылает авторитетное состояние, пока не закрыт stop.
+func (s *session) heartbeat(stop <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			state := s.clock.Snapshot()
+			s.sendAll(Event{Type: EventState, State: &state})
+			s.mu.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// apply применяет событие к часам комнаты и рассылает его вместе с новым состоянием.
+// Применение и рассылка идут под одной блокировкой, чтобы все клиенты
+// получали изменения в том же порядке, в котором их применил сервер.
+func (s *session) apply(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.clock.Apply(e)
+	e.State = &state
+	s.sendAll(e)
+	s.scheduleEnd()
+}
+
+// clientEnded обрабатывает сообщение клиента о том, что видео videoID досмотрено.
+// Если длительность видео известна, конец отслеживает таймер сервера
+// и сообщения клиентов не нужны: иначе один клиент мог бы пропускать видео за всех.
+func (s *session) clientEnded(videoID int) {
+	s.mu.Lock()
+	// Сообщение о прошлом видео или о видео, которое сессия не смогла загрузить, не учитывается
+	current := s.video != nil && s.video.ID == videoID
+	known := current && s.video.Duration > 0
+	seq := s.videoSeq
+	s.mu.Unlock()
+	if current && !known {
+		s.onEnd(seq)
+	}
+}
+
+// scheduleEnd перезапускает таймер окончания текущего видео по часам комнаты.
+// На паузе и для видео неизвестной длительности таймер не запускается.
+// Вызывается под s.mu.
+func (s *session) scheduleEnd() {
+	s.stopEndTimer()
+	if s.video == nil || s.video.Duration <= 0 {
+		return
+	}
+	endsAt, ok := s.clock.EndsAt(s.video.Duration)
+	if !ok {
+		return
+	}
+
+	seq := s.videoSeq
+	gen := s.endGen
+	s.endTimer = time.AfterFunc(time.Until(endsAt), func() {
+		s.mu.Lock()
+		current := s.endGen == gen
+		s.mu.Unlock()
+		if current {
+			s.onEnd(seq)
+		}
+	})
+}
+
+// stopEndTimer останавливает таймер окончания видео. Вызывается под s.mu.
+func (s *session) stopEndTimer() {
+	s.endGen++
+	if s.endTimer != nil {
+		s.endTimer.Stop()
+		s.endTimer = nil
+	}
+}
+
+// finish останавливает воспроизведение в конце видео с номером переключения seq,
+// когда очередь закончилась.
+func (s *session) finish(seq int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.video == nil || s.videoSeq != seq {
+		return
+	}
+	videoID := s.video.ID
+	current := s.clock.Snapshot()
+	if current.Paused {
+		return // Уже остановлено: о конце сообщили несколько клиентов
+	}
+
+	position := s.video.Duration
+	if position <= 0 {
+		position = current.Position // Длительность неизвестна: останавливаемся там, где сейчас
+	}
+	state := s.clock.Apply(Event{Type: EventPause, Position: position})
+	s.sendAll(Event{Type: EventEnded, VideoID: videoID, State: &state})
+	s.stopEndTimer()
+}
+
+// broadcast рассылает событие всем клиентам сессии.
+func (s *session) broadcast(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendAll(e)
+}
+
+// sendAll проставляет время сервера и ставит событие в очередь каждому клиенту.
+// Вызывается под s.mu.
+func (s *session) sendAll(e Event) {
+	e.ServerTime = serverTime()
+	for c := range s.clients {
+		c.send(e)
+	}
+}
@@ -0,0 +1,411 @@
+package rooms_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"video/database"
+	"video/database/dbtest"
+	"video/playback"
+	"video/rooms"
+	"video/rooms/roomstest"
+
+	"github.com/gorilla/websocket"
+)
+
+// hubServer поднимает Hub с политикой policy и комнатой, в которой идет видео неизвестной
+// длительности и есть еще одно в очереди. В комнате хозяин, модератор и зритель с токенами
+// "host-token", "moderator-token" и "viewer-token", имена участников совпадают с токенами.
+// Подключение по адресу /?token=... обслуживает участника с этим токеном.
+// configure вызываются для Hub до начала работы сервера.
+func hubServer(t *testing.T, policy rooms.Policy, configure ...func(*rooms.Hub)) (*httptest.Server, *database.DB, *database.Room) {
+	t.Helper()
+	ctx := context.Background()
+
+	db := dbtest.Open(t)
+	current, err := db.InsertVideo(ctx, "Первое", "first.mp4", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := db.InsertVideo(ctx, "Второе", "second.mp4", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	room, err := db.InsertRoom(ctx, "HUB001", current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddToQueue(ctx, room.ID, next); err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"host-token", "moderator-token", "viewer-token"} {
+		if _, err := db.InsertMember(ctx, room.ID, token, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.SetMemberRole(ctx, room.ID, member(t, db, "moderator-token").ID, database.RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+
+	hub := rooms.NewHub(db, db, db, db, db, playback.NewSigner([]byte("secret"), time.Hour), policy)
+	for _, f := range configure {
+		f(hub)
+	}
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		member, err := db.GetMemberByToken(r.Context(), r.URL.Query().Get("token"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Serve(conn, *member)
+	}))
+	t.Cleanup(func() {
+		hub.Close(room.ID)
+		srv.Close()
+	})
+	return srv, db, room
+}
+
+// connect подключает участника с токеном token и дожидается начального состояния.
+func connect(t *testing.T, srv *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+	conn, _ := connectState(t, srv, token)
+	return conn
+}
+
+// connectState подключает участника с токеном token и возвращает начальное состояние комнаты.
+func connectState(t *testing.T, srv *httptest.Server, token string) (*websocket.Conn, rooms.State) {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/?token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	e := roomstest.ReadEvent(t, conn, rooms.EventState, time.Second)
+	return conn, *e.State
+}
+
+// member возвращает участника с токеном token из базы.
+func member(t *testing.T, db *database.DB, token string) *database.Member {
+	t.Helper()
+	m, err := db.GetMemberByToken(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// send отправляет событие от клиента conn.
+func send(t *testing.T, conn *websocket.Conn, e rooms.Event) {
+	t.Helper()
+	if err := conn.WriteJSON(e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// flush дожидается, пока сервер обработает все события, отправленные клиентом conn,
+// и возвращает все, что пришло клиенту до ответа. События клиента обрабатываются
+// по порядку, поэтому к приходу pong обработаны и предыдущие.
+func flush(t *testing.T, conn *websocket.Conn) []rooms.Event {
+	t.Helper()
+	send(t, conn, rooms.Event{Type: rooms.EventPing, ClientTime: time.Now().UnixMilli()})
+	return roomstest.ReadEvents(t, conn, rooms.EventPong, time.Second)
+}
+
+// expectReject проверяет, что клиенту conn пришел отказ с текстом, содержащим reason.
+func expectReject(t *testing.T, conn *websocket.Conn, reason string) {
+	t.Helper()
+	if e := roomstest.ReadEvent(t, conn, rooms.EventError, time.Second); !strings.Contains(e.Error, reason) {
+		t.Errorf("отказ %q, ожидалась причина %q", e.Error, reason)
+	}
+}
+
+func TestViewerEndedIgnored(t *testing.T) {
+	srv, db, room := hubServer(t, rooms.DefaultPolicy)
+	host := connect(t, srv, "host-token")
+	viewer := connect(t, srv, "viewer-token")
+
+	// События клиента обрабатываются по порядку, поэтому после pong ended уже обработан
+	if err := viewer.WriteJSON(rooms.Event{Type: rooms.EventEnded, VideoID: room.VideoID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := viewer.WriteJSON(rooms.Event{Type: rooms.EventPing, ClientTime: time.Now().UnixMilli()}); err != nil {
+		t.Fatal(err)
+	}
+	if e := roomstest.ReadEvent(t, viewer, rooms.EventPong, time.Second); e.Error != "" {
+		t.Fatalf("pong с ошибкой: %q", e.Error)
+	}
+	got, err := db.GetRoomByID(context.Background(), room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.VideoID != room.VideoID || got.VideoSeq != room.VideoSeq {
+		t.Fatalf("ended от зрителя переключил комнату: %+v", *got)
+	}
+
+	// От хозяина то же событие переключает комнату на следующее видео
+	if err := host.WriteJSON(rooms.Event{Type: rooms.EventEnded, VideoID: room.VideoID}); err != nil {
+		t.Fatal(err)
+	}
+	next := roomstest.ReadEvent(t, viewer, rooms.EventNext, time.Second)
+	if next.VideoID == room.VideoID {
+		t.Errorf("после ended от хозяина комната осталась на видео %d", next.VideoID)
+	}
+}
+
+func TestViewerPlaybackRejected(t *testing.T) {
+	srv, _, _ := hubServer(t, rooms.DefaultPolicy)
+	host := connect(t, srv, "host-token")
+	viewer := connect(t, srv, "viewer-token")
+
+	for _, e := range []rooms.Event{
+		{Type: rooms.EventPlay, Position: 10},
+		{Type: rooms.EventPause, Position: 20},
+		{Type: rooms.EventSeek, Position: 30},
+		{Type: rooms.EventRate, Rate: 2},
+	} {
+		send(t, viewer, e)
+		expectReject(t, viewer, "not allowed to "+e.Type)
+	}
+	flush(t, viewer)
+
+	// Отклоненные события никому не рассылаются и не меняют часы комнаты
+	for _, e := range flush(t, host) {
+		switch e.Type {
+		case rooms.EventPlay, rooms.EventPause, rooms.EventSeek, rooms.EventRate:
+			t.Errorf("хозяину разослано событие %q от зрителя", e.Type)
+		}
+	}
+	if _, state := connectState(t, srv, "moderator-token"); !state.Paused || state.Position != 0 || state.Rate != 1 {
+		t.Errorf("состояние после отказов = %+v, ожидалась пауза в начале на обычной скорости", state)
+	}
+
+	// Модератору управление воспроизведением по умолчанию разрешено
+	moderator := connect(t, srv, "moderator-token")
+	send(t, moderator, rooms.Event{Type: rooms.EventSeek, Position: 30})
+	if e := roomstest.ReadEvent(t, viewer, rooms.EventSeek, time.Second); e.State.Position != 30 {
+		t.Errorf("перемотка модератора: состояние = %+v", *e.State)
+	}
+}
+
+func TestQueueControlByRole(t *testing.T) {
+	// Политика, в которой очередью управляет только хозяин
+	hostOnly := rooms.Policy{}
+	for action, role := range rooms.DefaultPolicy {
+		if action != rooms.ActionQueue {
+			hostOnly[action] = role
+		}
+	}
+
+	tests := []struct {
+		name     string
+		policy   rooms.Policy
+		token    string
+		switched bool
+	}{
+		{"модератор по умолчанию", rooms.DefaultPolicy, "moderator-token", true},
+		{"хозяин по умолчанию", rooms.DefaultPolicy, "host-token", true},
+		{"модератор, очередь только хозяину", hostOnly, "moderator-token", false},
+		{"хозяин, очередь только хозяину", hostOnly, "host-token", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, db, room := hubServer(t, tt.policy)
+			author := connect(t, srv, tt.token)
+
+			send(t, author, rooms.Event{Type: rooms.EventEnded, VideoID: room.VideoID})
+			events := flush(t, author)
+			got, err := db.GetRoomByID(context.Background(), room.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if switched := got.VideoSeq != room.VideoSeq; switched != tt.switched {
+				t.Errorf("комната переключилась: %v, ожидалось %v", switched, tt.switched)
+			}
+			gotNext := slices.ContainsFunc(events, func(e rooms.Event) bool { return e.Type == rooms.EventNext })
+			if gotNext != tt.switched {
+				t.Errorf("автору пришло next: %v, ожидалось %v", gotNext, tt.switched)
+			}
+		})
+	}
+}
+
+func TestKick(t *testing.T) {
+	srv, db, _ := hubServer(t, rooms.DefaultPolicy)
+	host := connect(t, srv, "host-token")
+	moderator := connect(t, srv, "moderator-token")
+	viewer := connect(t, srv, "viewer-token")
+	hostID, moderatorID, viewerID := member(t, db, "host-token").ID, member(t, db, "moderator-token").ID, member(t, db, "viewer-token").ID
+
+	// Зрителю исключать нельзя, модератору - только младших
+	send(t, viewer, rooms.Event{Type: rooms.EventKick, TargetID: moderatorID})
+	expectReject(t, viewer, "not allowed to kick")
+	send(t, moderator, rooms.Event{Type: rooms.EventKick, TargetID: hostID})
+	expectReject(t, moderator, "lower role")
+	send(t, moderator, rooms.Event{Type: rooms.EventKick, TargetID: 999})
+	expectReject(t, moderator, "Member not found")
+
+	send(t, moderator, rooms.Event{Type: rooms.EventKick, TargetID: viewerID})
+	if e := roomstest.ReadEvent(t, host, rooms.EventKick, time.Second); e.TargetID != viewerID || e.MemberID != moderatorID {
+		t.Errorf("хозяину: kick = %+v, ожидалось исключение зрителя модератором", e)
+	}
+	// Исключенный получает событие последним, после чего сервер закрывает соединение
+	if e := roomstest.ReadEvent(t, viewer, rooms.EventKick, time.Second); e.TargetID != viewerID {
+		t.Errorf("исключенному: kick = %+v", e)
+	}
+	var e rooms.Event
+	if err := viewer.ReadJSON(&e); err == nil {
+		t.Errorf("соединение исключенного открыто, пришло %+v", e)
+	}
+	if _, err := db.GetMemberByToken(context.Background(), "viewer-token"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("исключенный остался в комнате: %v", err)
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	ctx := context.Background()
+	srv, db, room := hubServer(t, rooms.DefaultPolicy)
+	host := connect(t, srv, "host-token")
+	moderator := connect(t, srv, "moderator-token")
+	viewer := connect(t, srv, "viewer-token")
+	hostID, moderatorID, viewerID := member(t, db, "host-token").ID, member(t, db, "moderator-token").ID, member(t, db, "viewer-token").ID
+
+	send(t, moderator, rooms.Event{Type: rooms.EventRole, TargetID: viewerID, Role: database.RoleModerator})
+	expectReject(t, moderator, "Only the host")
+	send(t, host, rooms.Event{Type: rooms.EventRole, TargetID: hostID, Role: database.RoleViewer})
+	expectReject(t, host, "your own role")
+
+	// Новая роль действует сразу, без переподключения
+	send(t, host, rooms.Event{Type: rooms.EventRole, TargetID: viewerID, Role: database.RoleModerator})
+	if e := roomstest.ReadEvent(t, viewer, rooms.EventRole, time.Second); e.TargetID != viewerID || e.Role != database.RoleModerator {
+		t.Errorf("role = %+v, ожидалось повышение зрителя до модератора", e)
+	}
+	send(t, viewer, rooms.Event{Type: rooms.EventPlay, Position: 5})
+	if e := roomstest.ReadEvent(t, host, rooms.EventPlay, time.Second); e.MemberID != viewerID {
+		t.Errorf("play от повышенного зрителя = %+v", e)
+	}
+
+	// Назначив хозяином другого, хозяин становится модератором
+	send(t, host, rooms.Event{Type: rooms.EventRole, TargetID: moderatorID, Role: database.RoleHost})
+	roles := make(map[int]string)
+	for len(roles) < 2 {
+		e := roomstest.ReadEvent(t, viewer, rooms.EventRole, time.Second)
+		roles[e.TargetID] = e.Role
+	}
+	if roles[hostID] != database.RoleModerator || roles[moderatorID] != database.RoleHost {
+		t.Errorf("роли после передачи = %v", roles)
+	}
+	members, err := db.GetMembers(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range members {
+		want := map[int]string{hostID: database.RoleModerator, moderatorID: database.RoleHost, viewerID: database.RoleModerator}[m.ID]
+		if m.Role != want {
+			t.Errorf("роль %s в базе = %q, ожидалась %q", m.Name, m.Role, want)
+		}
+	}
+	send(t, host, rooms.Event{Type: rooms.EventRole, TargetID: viewerID, Role: database.RoleViewer})
+	expectReject(t, host, "Only the host")
+}
+
+func TestHostHandOver(t *testing.T) {
+	const grace = 100 * time.Millisecond
+	shortGrace := func(h *rooms.Hub) { h.SetHostGracePeriod(grace) }
+
+	t.Run("после отключения", func(t *testing.T) {
+		srv, db, _ := hubServer(t, rooms.DefaultPolicy, shortGrace)
+		host := connect(t, srv, "host-token")
+		viewer := connect(t, srv, "viewer-token")
+		moderator := connect(t, srv, "moderator-token")
+		hostID, moderatorID := member(t, db, "host-token").ID, member(t, db, "moderator-token").ID
+
+		host.Close()
+		roomstest.ReadEvent(t, viewer, rooms.EventLeft, time.Second)
+		// Модератор старше зрителя, поэтому роль хозяина переходит к нему
+		roles := make(map[int]string)
+		for len(roles) < 2 {
+			e := roomstest.ReadEvent(t, viewer, rooms.EventRole, time.Second)
+			roles[e.TargetID] = e.Role
+		}
+		if roles[hostID] != database.RoleModerator || roles[moderatorID] != database.RoleHost {
+			t.Errorf("роли после передачи = %v", roles)
+		}
+		if m := member(t, db, "moderator-token"); m.Role != database.RoleHost {
+			t.Errorf("роль модератора в базе = %q", m.Role)
+		}
+		if m := member(t, db, "host-token"); m.Role != database.RoleModerator {
+			t.Errorf("роль прежнего хозяина в базе = %q", m.Role)
+		}
+		// Новая роль уже действует в открытом соединении
+		send(t, moderator, rooms.Event{Type: rooms.EventRole, TargetID: member(t, db, "viewer-token").ID, Role: database.RoleModerator})
+		roomstest.ReadEvent(t, viewer, rooms.EventRole, time.Second)
+	})
+
+	t.Run("хозяин вернулся", func(t *testing.T) {
+		srv, db, _ := hubServer(t, rooms.DefaultPolicy, shortGrace)
+		host := connect(t, srv, "host-token")
+		connect(t, srv, "moderator-token")
+
+		host.Close()
+		connect(t, srv, "host-token")
+		time.Sleep(3 * grace)
+		if m := member(t, db, "host-token"); m.Role != database.RoleHost {
+			t.Errorf("роль переподключившегося хозяина = %q, ожидалась host", m.Role)
+		}
+	})
+}
+
+func TestSingleHostConcurrentJoins(t *testing.T) {
+	ctx := context.Background()
+	_, db, room := hubServer(t, rooms.DefaultPolicy)
+	empty, err := db.InsertRoom(ctx, "HUB002", room.VideoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const joins = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, joins)
+	for i := 0; i < joins; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token := fmt.Sprintf("join-%d", i)
+			if _, err := db.InsertMember(ctx, empty.ID, token, token); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("вход в комнату: %v", err)
+	}
+
+	members, err := db.GetMembers(ctx, empty.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := 0
+	for _, m := range members {
+		if m.Role == database.RoleHost {
+			hosts++
+		}
+	}
+	if len(members) != joins || hosts != 1 {
+		t.Errorf("участников %d, хозяев %d; ожидалось %d участников и один хозяин", len(members), hosts, joins)
+	}
+}
@@ -0,0 +1,71 @@
+package rooms
+
+import (
+	"fmt"
+	"maps"
+	"video/database"
+)
+
+// Действия участников, права на которые задает Policy.
+// Для управления воспроизведением действие совпадает с типом события.
+const (
+	ActionPlay  = EventPlay  // Запуск воспроизведения
+	ActionPause = EventPause // Пауза
+	ActionSeek  = EventSeek  // Перемотка
+	ActionRate  = EventRate  // Изменение скорости
+	ActionQueue = "queue"    // Добавление, удаление, порядок и пропуск видео в очереди
+	ActionKick  = "kick"     // Исключение участника с младшей ролью
+	ActionChat  = EventChat  // Сообщения в чат
+	ActionReact = EventReact // Реакции
+)
+
+// Policy задает для каждого действия младшую роль, которой оно разрешено.
+// Роли назначает только хозяин, и хозяину разрешено все.
+type Policy map[string]string
+
+// DefaultPolicy - политика по умолчанию: комнатой управляют хозяин и модераторы,
+// зрители смотрят, пишут в чат и ставят реакции.
+var DefaultPolicy = Policy{
+	ActionPlay:  database.RoleModerator,
+	ActionPause: database.RoleModerator,
+	ActionSeek:  database.RoleModerator,
+	ActionRate:  database.RoleModerator,
+	ActionQueue: database.RoleModerator,
+	ActionKick:  database.RoleModerator,
+	ActionChat:  database.RoleViewer,
+	ActionReact: database.RoleViewer,
+}
+
+// NewPolicy строит политику из настройки room_policy: действия, которых в ней нет,
+// берутся из DefaultPolicy. Неизвестное действие или роль - ошибка.
+func NewPolicy(overrides map[string]string) (Policy, error) {
+	policy := maps.Clone(DefaultPolicy)
+	maps.Copy(policy, overrides)
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Allows сообщает, разрешено ли действие action участнику с ролью role.
+// Действия, которых нет в политике, разрешены только хозяину.
+func (p Policy) Allows(role, action string) bool {
+	if role == database.RoleHost {
+		return true
+	}
+	minRole, ok := p[action]
+	return ok && database.RoleRank(role) >= database.RoleRank(minRole)
+}
+
+// Validate проверяет, что в политике только известные действия и роли.
+func (p Policy) Validate() error {
+	for action, role := range p {
+		if _, ok := DefaultPolicy[action]; !ok {
+			return fmt.Errorf("неизвестное действие %q", action)
+		}
+		if database.RoleRank(role) == 0 {
+			return fmt.Errorf("действие %q: неизвестная роль %q", action, role)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package rooms
+
+import (
+	"maps"
+	"strings"
+	"testing"
+	"video/database"
+)
+
+func TestNewPolicy(t *testing.T) {
+	policy, err := NewPolicy(map[string]string{ActionChat: database.RoleModerator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy[ActionChat] != database.RoleModerator {
+		t.Errorf("chat = %q, ожидался moderator из настройки", policy[ActionChat])
+	}
+	if len(policy) != len(DefaultPolicy) || policy[ActionQueue] != DefaultPolicy[ActionQueue] {
+		t.Errorf("политика %v не дополнена умолчаниями", policy)
+	}
+	if DefaultPolicy[ActionChat] != database.RoleViewer {
+		t.Error("NewPolicy изменил политику по умолчанию")
+	}
+
+	if policy, err := NewPolicy(nil); err != nil || !maps.Equal(policy, DefaultPolicy) {
+		t.Errorf("NewPolicy(nil) = %v, %v; ожидалась политика по умолчанию", policy, err)
+	}
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      string
+	}{
+		{"неизвестное действие", map[string]string{"dance": database.RoleViewer}, "dance"},
+		{"неизвестная роль", map[string]string{ActionChat: "guest"}, "guest"},
+	}
+	for _, tt := range tests {
+		if _, err := NewPolicy(tt.overrides); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ошибка = %v, ожидалась с %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package rooms
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"unicode"
+	"unicode/utf8"
+	"video/database"
+)
+
+// Ограничение частоты реакций одного участника: в среднем reactionRate в секунду,
+// но подряд можно поставить до reactionBurst, чтобы взрыв смеха не обрезался.
+const (
+	reactionRate  = 2.0
+	reactionBurst = 10
+)
+
+// maxEmojiLength - сколько символов может занимать эмодзи реакции.
+// Составные эмодзи вроде семьи или флага занимают несколько символов.
+const maxEmojiLength = 10
+
+// limiter - ограничитель частоты по алгоритму token bucket.
+type limiter struct {
+	tokens  float64
+	last    time.Time
+	limited bool // Сообщили ли участнику, что он упёрся в ограничение; сбрасывается, когда запас восстановится
+}
+
+// newLimiter создает ограничитель с полным запасом.
+func newLimiter(now time.Time) *limiter {
+	return &limiter{tokens: reactionBurst, last: now}
+}
+
+// allow списывает одну реакцию, если запас еще есть.
+// Запас пополняется со скоростью reactionRate, но не больше reactionBurst.
+func (l *limiter) allow(now time.Time) bool {
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*reactionRate, reactionBurst)
+	l.last = now
+	if l.tokens >= reactionBurst {
+		l.limited = false
+	}
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// react рассылает реакцию клиента c с текущей позицией воспроизведения и сохраняет ее.
+// Реакции сверх ограничения частоты отбрасываются, автору об этом сообщается один раз.
+func (h *Hub) react(s *session, c *client, emoji string) {
+	author := s.member(c)
+	if !h.policy.Allows(author.Role, ActionReact) {
+		c.reject("Your role is not allowed to react")
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	// Отклоненная по другой причине реакция не должна тратить запас
+	if s.video == nil {
+		s.mu.Unlock()
+		c.reject("Room video is not loaded yet")
+		return
+	}
+	l, ok := s.limits[author.ID]
+	if !ok {
+		l = newLimiter(now)
+		s.limits[author.ID] = l
+	}
+	if !l.allow(now) {
+		notify := !l.limited
+		l.limited = true
+		s.mu.Unlock()
+		if notify {
+			c.reject("Too many reactions, slow down")
+		}
+		return
+	}
+	reaction := database.Reaction{
+		VideoID:  s.video.ID,
+		RoomID:   author.RoomID,
+		MemberID: author.ID,
+		Emoji:    emoji,
+		Position: s.clock.Snapshot().Position,
+	}
+	s.sendAll(Event{
+		Type:       EventReact,
+		MemberID:   author.ID,
+		MemberName: author.Name,
+		Emoji:      emoji,
+		Position:   reaction.Position,
+		VideoID:    reaction.VideoID,
+	})
+	s.mu.Unlock()
+
+	// Реакция уже показана всем, поэтому ошибка сохранения только портит статистику
+	if err := h.reactionStorage.InsertReaction(context.Background(), reaction); err != nil {
+		slog.Error("Не удалось сохранить реакцию",
+			"room_id", author.RoomID,
+			"member_id", author.ID,
+			"ошибка", err,
+		)
+	}
+}
+
+// isEmoji сообщает, является ли строка ровно одним эмодзи (одной графемой).
+// Эмодзи - это флаг страны из двух букв-регионов, кейкап вроде 1️⃣ (цифра, # или *,
+// затем U+FE0F и U+20E3) или цепочка пиктограмм, соединенных ZWJ, как в эмодзи семьи.
+// У каждой пиктограммы в цепочке может быть вариант эмодзи U+FE0F или цвет кожи,
+// а у флага региона вроде Шотландии - теги. Несколько эмодзи подряд без ZWJ не проходят.
+func isEmoji(s string) bool {
+	if s == "" || utf8.RuneCountInString(s) > maxEmojiLength {
+		return false
+	}
+	if isKeycap(s) {
+		return true
+	}
+	runes := []rune(s)
+	if len(runes) == 2 && isRegionalIndicator(runes[0]) && isRegionalIndicator(runes[1]) {
+		return true
+	}
+	for i := 0; ; i++ {
+		if i == len(runes) || !unicode.Is(extendedPictographic, runes[i]) {
+			return false
+		}
+		i++
+		if i < len(runes) && (runes[i] == '\ufe0f' || isSkinTone(runes[i])) {
+			i++
+		}
+		if i < len(runes) && isTag(runes[i]) {
+			for i < len(runes) && isTag(runes[i]) {
+				i++
+			}
+			if i == len(runes) || runes[i] != '\U000e007f' {
+				return false
+			}
+			i++
+		}
+		if i == len(runes) {
+			return true
+		}
+		// Следующая пиктограмма присоединяется только через ZWJ
+		if runes[i] != '\u200d' {
+			return false
+		}
+	}
+}
+
+// isKeycap сообщает, является ли строка кейкапом. U+FE0F в середине
+// необязателен: его иногда теряют клавиатуры.
+func isKeycap(s string) bool {
+	runes := []rune(s)
+	if len(runes) == 3 && runes[1] == '\ufe0f' {
+		runes = []rune{runes[0], runes[2]}
+	}
+	if len(runes) != 2 || runes[1] != '\u20e3' {
+		return false
+	}
+	return runes[0] >= '0' && runes[0] <= '9' || runes[0] == '#' || runes[0] == '*'
+}
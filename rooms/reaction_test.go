@@ -0,0 +1,96 @@
+package rooms
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	start := time.Now()
+	l := newLimiter(start)
+
+	for i := 0; i < reactionBurst; i++ {
+		if !l.allow(start) {
+			t.Fatalf("реакция %d из запаса отклонена", i+1)
+		}
+	}
+	if l.allow(start) {
+		t.Fatal("реакция сверх запаса принята")
+	}
+	l.limited = true // Так react отмечает, что участнику уже сообщили
+
+	// За полсекунды запас пополняется на одну реакцию
+	now := start.Add(time.Second / reactionRate)
+	if !l.allow(now) {
+		t.Fatal("реакция после пополнения запаса отклонена")
+	}
+	if l.allow(now) {
+		t.Fatal("пополнение дало больше одной реакции")
+	}
+	if !l.limited {
+		t.Fatal("отметка об ограничении сброшена до восстановления запаса")
+	}
+
+	// Запас восстанавливается полностью, но не больше reactionBurst
+	now = now.Add(time.Hour)
+	for i := 0; i < reactionBurst; i++ {
+		if !l.allow(now) {
+			t.Fatalf("реакция %d после восстановления запаса отклонена", i+1)
+		}
+	}
+	if l.allow(now) {
+		t.Fatal("запас вырос больше reactionBurst")
+	}
+	if l.limited {
+		t.Fatal("отметка об ограничении не сброшена после восстановления запаса")
+	}
+}
+
+func TestIsEmoji(t *testing.T) {
+	tests := []struct {
+		name  string
+		emoji string
+		want  bool
+	}{
+		{"простой", "\U0001F602", true},
+		{"с вариантом эмодзи", "\u2764\uFE0F", true},
+		{"цвет кожи", "\U0001F44D\U0001F3FD", true},
+		{"семья через ZWJ", "\U0001F468\u200D\U0001F469\u200D\U0001F467", true},
+		{"флаг", "\U0001F1F7\U0001F1FA", true},
+		{"флаг региона", "\U0001F3F4\U000E0067\U000E0062\U000E0073\U000E0063\U000E0074\U000E007F", true},
+		{"кейкап", "1\uFE0F\u20E3", true},
+		{"кейкап решетка", "#\uFE0F\u20E3", true},
+		{"кейкап без варианта", "9\u20E3", true},
+		{"пусто", "", false},
+		{"текст", "hi", false},
+		{"цифра", "1", false},
+		{"кейкап на букве", "a\uFE0F\u20E3", false},
+		{"кейкап на эмодзи", "\U0001F525\u20E3", false},
+		{"эмодзи с текстом", "\U0001F602 lol", false},
+		{"только ZWJ", "\u200D", false},
+		{"без варианта эмодзи", "\u2764", true},
+		{"копирайт", "\u00A9\uFE0F", true},
+		{"поцелуй с цветами кожи", "\U0001F469\U0001F3FD\u200D\u2764\uFE0F\u200D\U0001F48B\u200D\U0001F468\U0001F3FF", true},
+		{"градус", "\u00B0", false},
+		{"псевдографика", "\u2591", false},
+		{"номер", "\u2116", false},
+		{"градус Цельсия", "\u2103", false},
+		{"три эмодзи подряд", "\U0001F600\U0001F600\U0001F600", false},
+		{"два эмодзи подряд", "\u2764\uFE0F\U0001F525", false},
+		{"два флага", "\U0001F1F7\U0001F1FA\U0001F1FA\U0001F1F8", false},
+		{"одна буква флага", "\U0001F1F7", false},
+		{"три буквы флага", "\U0001F1F7\U0001F1FA\U0001F1F8", false},
+		{"только цвет кожи", "\U0001F3FD", false},
+		{"два цвета кожи", "\U0001F44D\U0001F3FD\U0001F3FD", false},
+		{"ZWJ в конце", "\U0001F468\u200D", false},
+		{"ZWJ в начале", "\u200D\U0001F468", false},
+		{"теги без завершения", "\U0001F3F4\U000E0067\U000E0062", false},
+		{"ZWJ перед текстом", "\U0001F468\u200Da", false},
+		{"слишком длинный", "\U0001F602\U0001F602\U0001F602\U0001F602\U0001F602\U0001F602\U0001F602\U0001F602\U0001F602\U0001F602\U0001F602", false},
+	}
+	for _, tt := range tests {
+		if got := isEmoji(tt.emoji); got != tt.want {
+			t.Errorf("%s: isEmoji(%q) = %v, ожидалось %v", tt.name, tt.emoji, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+	"video/database"
+)
+
+// hostGracePeriod - сколько ждем переподключения хозяина, прежде чем передать его роль.
+const hostGracePeriod = 15 * time.Second
+
+// Allows сообщает, разрешено ли участнику member действие action по политике комнат.
+// Роль берется из member, поэтому его нужно прочитать из базы перед проверкой.
+func (h *Hub) Allows(member *database.Member, action string) bool {
+	return h.policy.Allows(member.Role, action)
+}
+
+// HandOverHost передает роль хозяина, если комнату покидает ее хозяин fromID.
+// Новым хозяином становится подключенный участник, а если таких нет - любой оставшийся.
+func (h *Hub) HandOverHost(ctx context.Context, roomID, fromID int) {
+	h.handOverHost(ctx, roomID, fromID, false)
+}
+
+// handOverHost передает роль хозяина комнаты от fromID преемнику.
+// connectedOnly - передавать только подключенному участнику и только если
+// сам fromID так и не переподключился: так роль переходит после обрыва связи.
+func (h *Hub) handOverHost(ctx context.Context, roomID, fromID int, connectedOnly bool) {
+	connected := make(map[int]bool)
+	s := h.session(roomID)
+	if s != nil {
+		s.mu.Lock()
+		for c := range s.clients {
+			connected[c.member.ID] = true
+		}
+		s.mu.Unlock()
+	}
+	if connectedOnly && connected[fromID] {
+		return // Хозяин вернулся
+	}
+
+	members, err := h.roomStorage.GetMembers(ctx, roomID)
+	if err != nil {
+		slog.Error("Не удалось получить участников для передачи роли хозяина",
+			"room_id", roomID,
+			"ошибка", err,
+		)
+		return
+	}
+	next, ok := successor(members, connected, fromID, connectedOnly)
+	if !ok {
+		return
+	}
+
+	err = h.roomStorage.TransferHost(ctx, roomID, fromID, next.ID)
+	if errors.Is(err, database.ErrStale) {
+		return // fromID уже не хозяин: роль передали раньше
+	}
+	if err != nil {
+		slog.Error("Не удалось передать роль хозяина",
+			"room_id", roomID,
+			"from_member_id", fromID,
+			"to_member_id", next.ID,
+			"ошибка", err,
+		)
+		return
+	}
+	slog.Info("Роль хозяина передана",
+		"room_id", roomID,
+		"from_member_id", fromID,
+		"to_member_id", next.ID,
+	)
+
+	if s != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.changeRole(0, fromID, database.RoleModerator)
+		s.changeRole(0, next.ID, database.RoleHost)
+	}
+}
+
+// successor выбирает нового хозяина вместо fromID: сначала подключенные,
+// среди них модераторы раньше зрителей, при равенстве - кто раньше вошел.
+func successor(members []database.Member, connected map[int]bool, fromID int, connectedOnly bool) (database.Member, bool) {
+	candidates := slices.DeleteFunc(members, func(m database.Member) bool {
+		return m.ID == fromID || (connectedOnly && !connected[m.ID])
+	})
+	if len(candidates) == 0 {
+		return database.Member{}, false
+	}
+	slices.SortStableFunc(candidates, func(a, b database.Member) int {
+		if connected[a.ID] != connected[b.ID] {
+			if connected[a.ID] {
+				return -1
+			}
+			return 1
+		}
+		if rankA, rankB := database.RoleRank(a.Role), database.RoleRank(b.Role); rankA != rankB {
+			return rankB - rankA
+		}
+		return a.ID - b.ID
+	})
+	return candidates[0], true
+}
+
+// kick исключает из комнаты участника targetID по просьбе клиента c.
+// Исключить можно только участника с младшей ролью.
+func (h *Hub) kick(s *session, c *client, targetID int) {
+	author := s.member(c)
+	if !h.policy.Allows(author.Role, ActionKick) {
+		c.reject("Your role is not allowed to kick members")
+		return
+	}
+	target, ok := h.findMember(c, author.RoomID, targetID)
+	if !ok {
+		return
+	}
+	if database.RoleRank(target.Role) >= database.RoleRank(author.Role) {
+		c.reject("You can only kick members with a lower role")
+		return
+	}
+
+	if err := h.roomStorage.DeleteMember(context.Background(), author.RoomID, target.ID); err != nil {
+		slog.Error("Не удалось исключить участника",
+			"room_id", author.RoomID,
+			"member_id", target.ID,
+			"ошибка", err,
+		)
+		c.reject("Failed to kick member")
+		return
+	}
+	slog.Info("Участник исключен из комнаты",
+		"room_id", author.RoomID,
+		"member_id", target.ID,
+		"by_member_id", author.ID,
+	)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendAll(Event{Type: EventKick, MemberID: author.ID, MemberName: author.Name, TargetID: target.ID})
+	for other := range s.clients {
+		if other.member.ID == target.ID {
+			// Закрытая очередь отправит исключенному это событие последним и закроет соединение.
+			// Из сессии клиент убирается сразу, чтобы ему больше ничего не отправлялось.
+			delete(s.clients, other)
+			other.close()
+		}
+	}
+}
+
+// setRole назначает участнику targetID роль role по просьбе клиента c.
+// Роли назначает только хозяин. Назначив хозяином другого, он сам становится модератором.
+func (h *Hub) setRole(s *session, c *client, targetID int, role string) {
+	author := s.member(c)
+	if author.Role != database.RoleHost {
+		c.reject("Only the host can change roles")
+		return
+	}
+	if targetID == author.ID {
+		c.reject("You cannot change your own role")
+		return
+	}
+	target, ok := h.findMember(c, author.RoomID, targetID)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	var err error
+	if role == database.RoleHost {
+		err = h.roomStorage.TransferHost(ctx, author.RoomID, author.ID, target.ID)
+	} else {
+		err = h.roomStorage.SetMemberRole(ctx, author.RoomID, target.ID, role)
+	}
+	if err != nil {
+		slog.Error("Не удалось сменить роль участника",
+			"room_id", author.RoomID,
+			"member_id", target.ID,
+			"role", role,
+			"ошибка", err,
+		)
+		c.reject("Failed to change role")
+		return
+	}
+	slog.Info("Роль участника изменена",
+		"room_id", author.RoomID,
+		"member_id", target.ID,
+		"role", role,
+		"by_member_id", author.ID,
+	)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if role == database.RoleHost {
+		s.changeRole(author.ID, author.ID, database.RoleModerator)
+	}
+	s.changeRole(author.ID, target.ID, role)
+}
+
+// findMember находит участника комнаты roomID. Если его нет, сообщает об этом клиенту c.
+func (h *Hub) findMember(c *client, roomID, memberID int) (database.Member, bool) {
+	members, err := h.roomStorage.GetMembers(context.Background(), roomID)
+	if err != nil {
+		slog.Error("Не удалось получить участников комнаты",
+			"room_id", roomID,
+			"ошибка", err,
+		)
+		c.reject("Failed to get members")
+		return database.Member{}, false
+	}
+	for _, m := range members {
+		if m.ID == memberID {
+			return m, true
+		}
+	}
+	c.reject("Member not found")
+	return database.Member{}, false
+}
+
+// member возвращает участника, которому принадлежит клиент c, с его текущей ролью.
+func (s *session) member(c *client) database.Member {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return c.member
+}
+
+// changeRole обновляет роль memberID у его подключенных клиентов и рассылает ее всем.
+// authorID - кто сменил роль, 0 - сервер. Вызывается под s.mu.
+func (s *session) changeRole(authorID, memberID int, role string) {
+	for c := range s.clients {
+		if c.member.ID == memberID {
+			c.member.Role = role
+		}
+	}
+	s.sendAll(Event{Type: EventRole, MemberID: authorID, TargetID: memberID, Role: role})
+}
@@ -0,0 +1,36 @@
+// Package roomstest помогает тестам работать с каналом синхронизации комнаты.
+package roomstest
+
+import (
+	"testing"
+	"time"
+	"video/rooms"
+
+	"github.com/gorilla/websocket"
+)
+
+// ReadEvent читает события, пока не придет событие типа eventType.
+// Если оно не пришло за timeout, тест завершается с ошибкой.
+func ReadEvent(t testing.TB, conn *websocket.Conn, eventType string, timeout time.Duration) rooms.Event {
+	t.Helper()
+	events := ReadEvents(t, conn, eventType, timeout)
+	return events[len(events)-1]
+}
+
+// ReadEvents читает события, пока не придет событие типа eventType, и возвращает
+// все прочитанные, включая его. Если оно не пришло за timeout, тест завершается с ошибкой.
+func ReadEvents(t testing.TB, conn *websocket.Conn, eventType string, timeout time.Duration) []rooms.Event {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	var events []rooms.Event
+	for {
+		var e rooms.Event
+		if err := conn.ReadJSON(&e); err != nil {
+			t.Fatalf("не дождались события %q: %v", eventType, err)
+		}
+		events = append(events, e)
+		if e.Type == eventType {
+			return events
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Package storage хранит файлы видео: исходники для стриминга и готовые HLS-потоки.
+// Хранилище может быть локальной папкой или S3-совместимым бакетом,
+// поэтому обработчики работают с ним только через интерфейс Backend.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"video/config"
+)
+
+// ErrNotExist возвращается, когда объекта с таким ключом нет в хранилище.
+var ErrNotExist = errors.New("объект не найден в хранилище")
+
+// Backend - хранилище объектов. Ключ - путь через "/" относительно корня
+// хранилища, например "ABC123/main.m3u8".
+type Backend interface {
+	// Put сохраняет объект из r. size - длина данных или -1, если она неизвестна.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get открывает length байт объекта начиная с offset; length < 0 - до конца объекта.
+	Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Stat возвращает сведения об объекте или ErrNotExist.
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// List возвращает все объекты, ключи которых начинаются с prefix.
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Delete удаляет объект. Удаление отсутствующего объекта не ошибка.
+	Delete(ctx context.Context, key string) error
+}
+
+// Dir - необязательный интерфейс хранилища на локальном диске.
+// В такое хранилище ffmpeg пишет HLS напрямую, без промежуточной папки,
+// и видео можно смотреть еще во время кодирования.
+type Dir interface {
+	Root() string // Папка, в которой лежат файлы объектов
+}
+
+// ObjectInfo - сведения об объекте хранилища.
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+	ETag    string // Может быть пустым
+}
+
+// Open создает хранилище по настройкам cfg.Storage.
+// Локальное хранилище живет в cfg.UploadDir.
+func Open(ctx context.Context, cfg *config.Config) (Backend, error) {
+	switch cfg.Storage.Type {
+	case config.StorageLocal:
+		return NewLocal(cfg.UploadDir)
+	case config.StorageS3:
+		return NewS3(ctx, cfg.Storage.S3)
+	default:
+		return nil, fmt.Errorf("неизвестный тип хранилища: %q", cfg.Storage.Type)
+	}
+}
+
+// DeletePrefix удаляет все объекты с ключами, начинающимися с prefix,
+// и возвращает их количество.
+func DeletePrefix(ctx context.Context, b Backend, prefix string) (int, error) {
+	objects, err := b.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	for i, obj := range objects {
+		if err := b.Delete(ctx, obj.Key); err != nil {
+			return i, err
+		}
+	}
+	return len(objects), nil
+}
+
+// PutDir загружает все файлы локальной папки dir в хранилище
+// под ключами prefix/<относительный путь>.
+func PutDir(ctx context.Context, b Backend, dir, prefix string) error {
+	return filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("не удалось открыть файл %q: %w", name, err)
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("не удалось получить информацию о файле %q: %w", name, err)
+		}
+
+		key := path.Join(prefix, filepath.ToSlash(rel))
+		if err := b.Put(ctx, key, file, info.Size(), ContentType(key)); err != nil {
+			return fmt.Errorf("не удалось загрузить %q в хранилище: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// ContentType возвращает MIME-тип объекта по расширению ключа.
+func ContentType(key string) string {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".m3u8":
+		return "application/x-mpegURL"
+	case ".fmp4", ".m4s":
+		return "video/iso.segment"
+	case ".mp4":
+		return "video/mp4"
+	case ".vtt":
+		return "text/vtt; charset=utf-8"
+	}
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// cleanKey проверяет, что ключ не выходит за корень хранилища.
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if !fs.ValidPath(key) || key == "." {
+		return "", fmt.Errorf("некорректный ключ объекта: %q", key)
+	}
+	return key, nil
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"video/config"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+// S3-хранилище по умолчанию проверяется на S3 в памяти процесса (gofakes3).
+// Настоящий MinIO подключается, если задан его адрес, например
+// VIDEO_TEST_S3_ENDPOINT=localhost:9000 go test ./storage/
+// Ключи берутся из VIDEO_TEST_S3_ACCESS_KEY и VIDEO_TEST_S3_SECRET_KEY, по умолчанию minioadmin.
+
+func TestLocal(t *testing.T) {
+	local, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBackend(t, local)
+}
+
+func TestS3(t *testing.T) {
+	// Без префикса ключи лежат в корне бакета, поэтому на общем MinIO проверяется только с префиксом
+	prefixes := []string{"", "videos/test"}
+	endpoint := os.Getenv("VIDEO_TEST_S3_ENDPOINT")
+	if endpoint != "" {
+		prefixes = []string{fmt.Sprintf("test-%d", time.Now().UnixNano())} // Прогоны не мешают друг другу
+	}
+
+	for _, prefix := range prefixes {
+		t.Run("prefix="+prefix, func(t *testing.T) {
+			cfg := config.S3Config{
+				Endpoint:  endpoint,
+				Bucket:    "video-test",
+				Prefix:    prefix,
+				AccessKey: envOr("VIDEO_TEST_S3_ACCESS_KEY", "minioadmin"),
+				SecretKey: envOr("VIDEO_TEST_S3_SECRET_KEY", "minioadmin"),
+			}
+			if cfg.Endpoint == "" {
+				srv := httptest.NewServer(gofakes3.New(s3mem.New()).Server())
+				t.Cleanup(srv.Close)
+				cfg.Endpoint = strings.TrimPrefix(srv.URL, "http://")
+			}
+			s3, err := NewS3(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testBackend(t, s3)
+		})
+	}
+}
+
+// testBackend проверяет контракт Backend, общий для всех хранилищ.
+func testBackend(t *testing.T, b Backend) {
+	ctx := context.Background()
+	const data = "0123456789abcdef"
+	t.Cleanup(func() { DeletePrefix(ctx, b, "") })
+
+	put := func(key, data string) {
+		t.Helper()
+		if err := b.Put(ctx, key, strings.NewReader(data), int64(len(data)), ContentType(key)); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+	put("ABC/main.m3u8", data)
+	put("ABC/720p/seg0.m4s", "segment")
+	put("ABD/main.m3u8", "other")
+
+	t.Run("Get", func(t *testing.T) {
+		tests := []struct {
+			offset, length int64
+			want           string
+		}{
+			{0, -1, data},
+			{4, -1, data[4:]},
+			{4, 6, data[4:10]},
+			{0, 0, ""},
+		}
+		for _, tt := range tests {
+			r, err := b.Get(ctx, "ABC/main.m3u8", tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("Get(%d, %d): %v", tt.offset, tt.length, err)
+			}
+			got, err := io.ReadAll(r)
+			r.Close()
+			if err != nil || string(got) != tt.want {
+				t.Errorf("Get(%d, %d) = %q, %v; ожидалось %q", tt.offset, tt.length, got, err, tt.want)
+			}
+		}
+		if _, err := b.Get(ctx, "ABC/missing", 0, -1); !errors.Is(err, ErrNotExist) {
+			t.Errorf("Get отсутствующего объекта: %v, ожидалась ErrNotExist", err)
+		}
+	})
+
+	t.Run("NewReader", func(t *testing.T) {
+		// Хранилище без Opener, чтобы проверить чтение запросами Get с диапазоном
+		r, err := NewReader(ctx, struct{ Backend }{b}, "ABC/main.m3u8", int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		if _, err := r.Seek(-6, io.SeekEnd); err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil || string(got) != data[10:] {
+			t.Errorf("чтение после Seek = %q, %v; ожидалось %q", got, err, data[10:])
+		}
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		info, err := b.Stat(ctx, "ABC/main.m3u8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Key != "ABC/main.m3u8" || info.Size != int64(len(data)) || info.ModTime.IsZero() {
+			t.Errorf("Stat = %+v", *info)
+		}
+		for _, key := range []string{"ABC/missing", "ABC"} {
+			if _, err := b.Stat(ctx, key); !errors.Is(err, ErrNotExist) {
+				t.Errorf("Stat(%q): %v, ожидалась ErrNotExist", key, err)
+			}
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		tests := []struct {
+			prefix string
+			want   []string
+		}{
+			{"ABC/", []string{"ABC/720p/seg0.m4s", "ABC/main.m3u8"}},
+			{"AB", []string{"ABC/720p/seg0.m4s", "ABC/main.m3u8", "ABD/main.m3u8"}},
+			{"ABC/main", []string{"ABC/main.m3u8"}},
+			{"XYZ/", nil},
+		}
+		for _, tt := range tests {
+			objects, err := b.List(ctx, tt.prefix)
+			if err != nil {
+				t.Fatalf("List(%q): %v", tt.prefix, err)
+			}
+			var keys []string
+			for _, obj := range objects {
+				keys = append(keys, obj.Key)
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, tt.want) {
+				t.Errorf("List(%q) = %v, ожидалось %v", tt.prefix, keys, tt.want)
+			}
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := b.Delete(ctx, "ABD/main.m3u8"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.Stat(ctx, "ABD/main.m3u8"); !errors.Is(err, ErrNotExist) {
+			t.Errorf("объект остался после Delete: %v", err)
+		}
+		if err := b.Delete(ctx, "ABD/main.m3u8"); err != nil {
+			t.Errorf("повторный Delete: %v", err)
+		}
+		n, err := DeletePrefix(ctx, b, "ABC/")
+		if err != nil || n != 2 {
+			t.Errorf("DeletePrefix = %d, %v; ожидалось 2 объекта", n, err)
+		}
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		for _, key := range []string{"../escape", "ABC/../../escape", "ABC/./main.m3u8", "", "/"} {
+			if err := b.Put(ctx, key, strings.NewReader("x"), 1, ""); err == nil {
+				t.Errorf("Put(%q) принял ключ за пределами хранилища", key)
+			}
+			if _, err := b.Get(ctx, key, 0, -1); err == nil || errors.Is(err, ErrNotExist) {
+				t.Errorf("Get(%q): %v, ожидалась ошибка ключа", key, err)
+			}
+			if err := b.Delete(ctx, key); err == nil {
+				t.Errorf("Delete(%q) принял ключ за пределами хранилища", key)
+			}
+		}
+	})
+}
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"ABC/main.m3u8", "ABC/main.m3u8", true},
+		{"/ABC/main.m3u8", "ABC/main.m3u8", true},
+		{"../main.m3u8", "", false},
+		{"ABC/../main.m3u8", "", false},
+		{"ABC/../../main.m3u8", "", false},
+		{"ABC//main.m3u8", "", false},
+		{"ABC/", "", false},
+		{"", "", false},
+		{".", "", false},
+	}
+	for _, tt := range tests {
+		got, err := cleanKey(tt.key)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("cleanKey(%q) = %q, %v; ожидалось %q, ok=%v", tt.key, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Local хранит объекты файлами в папке на диске.
+type Local struct {
+	root string
+}
+
+// NewLocal создает хранилище в папке root, создавая ее при необходимости.
+func NewLocal(root string) (*Local, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать папку хранилища %s: %w", root, err)
+	}
+	return &Local{root: root}, nil
+}
+
+// Root возвращает папку хранилища.
+func (l *Local) Root() string {
+	return l.root
+}
+
+// Path возвращает путь к файлу объекта key.
+func (l *Local) Path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// Put записывает объект во временный файл и переименовывает его,
+// чтобы читатели не видели недописанных файлов.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := l.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("не удалось создать папку для %q: %w", key, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".put-*")
+	if err != nil {
+		return fmt.Errorf("не удалось создать файл для %q: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка записи %q: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("не удалось сохранить %q: %w", key, err)
+	}
+	return nil
+}
+
+// Get открывает файл объекта на нужном смещении.
+func (l *Local) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	file, err := l.open(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("не удалось перейти к позиции %d в %q: %w", offset, key, err)
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+// Open открывает файл объекта целиком.
+func (l *Local) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	return l.open(key)
+}
+
+// Stat возвращает сведения о файле объекта. Папки объектами не считаются.
+func (l *Local) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	name, err := l.Path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при доступе к %q: %w", key, err)
+	}
+	return &ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// List обходит папку, в которой лежат ключи с префиксом prefix.
+func (l *Local) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	prefix = strings.TrimPrefix(prefix, "/")
+	// Обходим только папку префикса: "ABC/main" ищется в папке "ABC"
+	dir := l.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		var err error
+		if dir, err = l.Path(prefix[:i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []ObjectInfo
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(l.root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) || strings.HasPrefix(path.Base(key), ".put-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обхода папки хранилища для %q: %w", prefix, err)
+	}
+	return objects, nil
+}
+
+// Delete удаляет файл объекта и ставшие пустыми папки над ним.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("не удалось удалить %q: %w", key, err)
+	}
+	// os.Remove не удаляет непустые папки, поэтому останавливаемся на первой такой
+	for dir := filepath.Dir(name); dir != filepath.Clean(l.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// open открывает файл объекта для чтения.
+func (l *Local) open(key string) (*os.File, error) {
+	name, err := l.Path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть %q: %w", key, err)
+	}
+	return file, nil
+}
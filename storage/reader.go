@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Opener - необязательный интерфейс хранилища, которое само умеет открывать
+// объект для произвольного доступа. Локальное хранилище отдает *os.File,
+// и net/http передает его клиенту через sendfile без копирования в память.
+type Opener interface {
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+}
+
+// NewReader открывает объект размером size для чтения с перемоткой.
+// Если хранилище не реализует Opener, каждое чтение после перемотки
+// открывает объект заново с нужного смещения через Get.
+func NewReader(ctx context.Context, b Backend, key string, size int64) (io.ReadSeekCloser, error) {
+	if opener, ok := b.(Opener); ok {
+		return opener.Open(ctx, key)
+	}
+	return &rangeReader{ctx: ctx, backend: b, key: key, size: size}, nil
+}
+
+// rangeReader читает объект запросами Get с диапазоном.
+// Соединение открывается лениво: http.ServeContent перематывает файл
+// несколько раз перед чтением, и лишние запросы к хранилищу не нужны.
+type rangeReader struct {
+	ctx     context.Context
+	backend Backend
+	key     string
+	size    int64
+	offset  int64
+	body    io.ReadCloser // Открытый поток с позиции offset или nil
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.backend.Get(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("некорректный whence")
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("отрицательная позиция: %d", offset)
+	}
+	if offset != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"video/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 хранит объекты в бакете S3-совместимого хранилища: AWS S3, MinIO и т.п.
+type S3 struct {
+	client *minio.Client
+	bucket string
+	prefix string // Префикс ключей внутри бакета, без "/" на конце
+}
+
+// NewS3 подключается к хранилищу и создает бакет, если его еще нет.
+func NewS3(ctx context.Context, cfg config.S3Config) (*S3, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать клиент S3 для %s: %w", cfg.Endpoint, err)
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить бакет %q: %w", cfg.Bucket, err)
+	}
+	if !exists {
+		err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region})
+		if err != nil {
+			return nil, fmt.Errorf("не удалось создать бакет %q: %w", cfg.Bucket, err)
+		}
+		fmt.Printf("Бакет '%s' создан.\n", cfg.Bucket)
+	}
+	return &S3{client: client, bucket: cfg.Bucket, prefix: strings.Trim(cfg.Prefix, "/")}, nil
+}
+
+// objectName возвращает имя объекта в бакете для ключа key.
+func (s *S3) objectName(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(s.prefix, key), nil
+}
+
+// Put загружает объект в бакет.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := s.objectName(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, s.bucket, name, r, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки %q в S3: %w", key, err)
+	}
+	return nil
+}
+
+// Get читает объект запросом с заголовком Range.
+func (s *S3) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	name, err := s.objectName(key)
+	if err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	var opts minio.GetObjectOptions
+	switch {
+	case length > 0:
+		err = opts.SetRange(offset, offset+length-1)
+	case offset > 0:
+		err = opts.SetRange(offset, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("некорректный диапазон для %q: %w", key, err)
+	}
+
+	// Client.GetObject ленивый, а его Stat стирает Range из запроса,
+	// поэтому запрос с диапазоном отправляется сразу через Core
+	body, _, _, err := minio.Core{Client: s.client}.GetObject(ctx, s.bucket, name, opts)
+	if err != nil {
+		return nil, s.wrapError(key, err)
+	}
+	return body, nil
+}
+
+// Stat запрашивает сведения об объекте запросом HEAD.
+func (s *S3) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	name, err := s.objectName(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.client.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s.wrapError(key, err)
+	}
+	return &ObjectInfo{Key: key, Size: info.Size, ModTime: info.LastModified, ETag: info.ETag}, nil
+}
+
+// List перечисляет объекты с префиксом prefix.
+func (s *S3) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	namePrefix := strings.TrimPrefix(prefix, "/")
+	if s.prefix != "" {
+		namePrefix = s.prefix + "/" + namePrefix
+	}
+
+	var objects []ObjectInfo
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: namePrefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("ошибка получения списка объектов %q из S3: %w", prefix, obj.Err)
+		}
+		key := obj.Key
+		if s.prefix != "" {
+			key = strings.TrimPrefix(key, s.prefix+"/")
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: obj.Size, ModTime: obj.LastModified, ETag: obj.ETag})
+	}
+	return objects, nil
+}
+
+// Delete удаляет объект из бакета.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	name, err := s.objectName(key)
+	if err != nil {
+		return err
+	}
+	if err := s.client.RemoveObject(ctx, s.bucket, name, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("не удалось удалить %q из S3: %w", key, err)
+	}
+	return nil
+}
+
+// wrapError превращает ответ 404 хранилища в ErrNotExist.
+func (s *S3) wrapError(key string, err error) error {
+	resp := minio.ToErrorResponse(err)
+	if resp.StatusCode == http.StatusNotFound || resp.Code == "NoSuchKey" {
+		return fmt.Errorf("%w: %s", ErrNotExist, key)
+	}
+	return fmt.Errorf("ошибка доступа к %q в S3: %w", key, err)
+}
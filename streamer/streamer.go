@@ -1,73 +1,56 @@
 package streamer
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"os"
-	"path"
-	"video/config"
-	"video/database"
+	"time"
+	"video/storage"
 )
 
+// Streamer открывает файлы хранилища для потоковой отдачи.
 type Streamer interface {
-	Seek(video *database.Video, start, end int64) ([]byte, error)
+	Open(ctx context.Context, key string) (*Stream, error)
 }
 
-type FileStreamer struct{}
+// Stream - открытый файл хранилища, например сегмент HLS.
+// Данные читаются по мере отдачи клиенту, поэтому в памяти не держится ни весь файл, ни большие его куски.
+type Stream struct {
+	io.ReadSeekCloser
+	Name    string    // Ключ файла в хранилище
+	Size    int64     // Размер в байтах
+	ModTime time.Time // Время последнего изменения
+	ETag    string    // Сильный ETag в кавычках
+}
 
-// Seek возвращает фрагмент файла [start, end] (включительно)
-func (f *FileStreamer) Seek(video *database.Video, start, end int64) ([]byte, error) {
-	if start < 0 || end < 0 {
-		return nil, fmt.Errorf("некорректные позиции: start=%d, end=%d (должны быть неотрицательными)", start, end)
-	}
-	if start > end {
-		return nil, fmt.Errorf("некорректные позиции: start=%d, end=%d (start не может быть больше end)", start, end)
-	}
+// FileStreamer читает файлы из хранилища Storage.
+type FileStreamer struct {
+	Storage storage.Backend
+}
 
-	fullPath := path.Join(config.UploadDir, video.FileName)
-	file, err := os.Open(fullPath)
+// Open открывает файл с ключом key. Чтение идет в контексте ctx:
+// когда клиент отключается, запросы к хранилищу прерываются.
+// Если файла нет, ошибка оборачивает storage.ErrNotExist.
+func (f *FileStreamer) Open(ctx context.Context, key string) (*Stream, error) {
+	info, err := f.Storage.Stat(ctx, key)
 	if err != nil {
-		return nil, fmt.Errorf("не удалось открыть файл %q: %w", fullPath, err)
+		return nil, fmt.Errorf("не удалось получить информацию о файле %q: %w", key, err)
 	}
-	defer file.Close()
-
-	fileInfo, err := file.Stat()
+	file, err := storage.NewReader(ctx, f.Storage, key, info.Size)
 	if err != nil {
-		return nil, fmt.Errorf("не удалось получить информацию о файле %q: %w", fullPath, err)
-	}
-	fileSize := fileInfo.Size()
-
-	// Если start за пределами файла — ошибка (416 Range Not Satisfiable)
-	if start >= fileSize {
-		return nil, fmt.Errorf("начальная позиция (%d) за пределами файла (размер: %d)", start, fileSize)
-	}
-
-	// Обрезаем end по размеру файла
-	if end >= fileSize {
-		end = fileSize - 1
-	}
-
-	// Вычисляем количество байт (осторожно с переполнением)
-	count := end - start + 1
-	if count <= 0 {
-		return []byte{}, nil
-	}
-
-	// Защита от слишком больших запросов (опционально)
-	const maxChunkSize = 100 * 1024 * 1024 // 100 MB
-	if count > maxChunkSize {
-		return nil, fmt.Errorf("запрашиваемый фрагмент слишком большой: %d байт (максимум %d)", count, maxChunkSize)
+		return nil, fmt.Errorf("не удалось открыть файл %q: %w", key, err)
 	}
 
-	buf := make([]byte, count)
-	n, err := file.ReadAt(buf, start)
-	if err != nil && err != io.EOF {
-		return nil, fmt.Errorf("ошибка чтения файла %q: %w", fullPath, err)
-	}
-	if n != len(buf) {
-		// Файл был изменён во время чтения — возвращаем то, что прочитали
-		return buf[:n], nil
+	etag := info.ETag
+	if etag == "" {
+		// Локальные файлы не меняются после записи, поэтому размера и времени изменения достаточно
+		etag = fmt.Sprintf("%x-%x", info.Size, info.ModTime.UnixNano())
 	}
-
-	return buf, nil
-}
\ No newline at end of file
+	return &Stream{
+		ReadSeekCloser: file,
+		Name:           key,
+		Size:           info.Size,
+		ModTime:        info.ModTime,
+		ETag:           `"` + etag + `"`,
+	}, nil
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// AudioGroup - GROUP-ID аудиодорожек в мастер-плейлисте.
+const AudioGroup = "audio"
+
+// AudioRendition - аудиодорожка HLS. Она общая для всех качеств видео,
+// и зритель выбирает ее в плеере независимо от качества: дубляж или оригинал.
+type AudioRendition struct {
+	BaseName string // Имя плейлиста без расширения, например "audio_0"
+	Stream   int    // Номер аудиодорожки исходника
+	Name     string // Название в меню плеера
+	Language string // Код языка из тегов исходника, пустой - не указан
+	Channels int    // Каналов после сведения: 1 или 2
+	Bitrate  string // Например, "192k"
+	Default  bool   // Включается по умолчанию
+}
+
+// BuildAudioRenditions создает по аудиодорожке HLS на каждую аудиодорожку исходника.
+// По умолчанию включается дорожка, помеченная основной в исходнике, иначе первая.
+func BuildAudioRenditions(info *MediaInfo, bitrate string) []AudioRendition {
+	renditions := make([]AudioRendition, 0, len(info.Audio))
+	defaultIndex := 0
+	for i, stream := range info.Audio {
+		if stream.Default {
+			defaultIndex = i
+			break
+		}
+	}
+
+	for i, stream := range info.Audio {
+		language := stream.Language
+		if language == "und" {
+			language = ""
+		}
+		name := stream.Title
+		if name == "" {
+			name = language
+		}
+		if name == "" {
+			name = fmt.Sprintf("Audio %d", i+1)
+		}
+		renditions = append(renditions, AudioRendition{
+			BaseName: fmt.Sprintf("audio_%d", stream.Index),
+			Stream:   stream.Index,
+			Name:     name,
+			Language: language,
+			Channels: max(min(stream.Channels, 2), 1), // Моно остается моно, остальное сводим в стерео
+			Bitrate:  bitrate,
+			Default:  i == defaultIndex,
+		})
+	}
+	return renditions
+}
+
+// mediaTag возвращает строку #EXT-X-MEDIA для мастер-плейлиста.
+func (a AudioRendition) mediaTag() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="%s",NAME="%s"`, AudioGroup, QuotedString(a.Name))
+	if a.Language != "" {
+		fmt.Fprintf(&b, `,LANGUAGE="%s"`, QuotedString(a.Language))
+	}
+	fmt.Fprintf(&b, `,DEFAULT=%s,AUTOSELECT=YES,CHANNELS="%d",URI="%s.m3u8"`, YesNo(a.Default), a.Channels, a.BaseName)
+	return b.String()
+}
+
+// generateAudioRendition кодирует аудиодорожку a в отдельный HLS-плейлист без видео.
+func generateAudioRendition(
+	inputPath string,
+	outputPathDir string,
+	profile HLSProfile,
+	playlistType string,
+	a AudioRendition,
+	info *MediaInfo,
+	onProgress func(outTime, speed, percent float64),
+) error {
+	if err := os.MkdirAll(outputPathDir, 0755); err != nil {
+		return fmt.Errorf("не удалось создать выходную директорию: %w", err)
+	}
+
+	args := []string{
+		"-progress", "pipe:1",
+		"-nostats",
+		"-i", inputPath,
+		"-map", fmt.Sprintf("0:a:%d", a.Stream),
+		"-c:a", "aac",
+		"-b:a", a.Bitrate,
+		"-ar", "48000",
+		"-ac", strconv.Itoa(a.Channels),
+		"-f", "hls",
+		"-hls_list_size", "0",
+		"-hls_flags", "temp_file",
+		"-hls_time", strconv.Itoa(profile.SegmentDuration),
+		"-hls_playlist_type", playlistType,
+		"-hls_segment_type", "fmp4",
+		"-hls_fmp4_init_filename", fmt.Sprintf("%s_init.mp4", a.BaseName),
+		"-hls_segment_filename", filepath.Join(outputPathDir, fmt.Sprintf("%s_%%03d.fmp4", a.BaseName)),
+		filepath.Join(outputPathDir, fmt.Sprintf("%s.m3u8", a.BaseName)),
+	}
+	if err := runWithProgress(args, info.Duration, onProgress); err != nil {
+		return fmt.Errorf("ошибка кодирования аудиодорожки %s: %w", a.BaseName, err)
+	}
+	return nil
+}
+
+// QuotedString убирает из значения символы, недопустимые в quoted-string HLS.
+func QuotedString(s string) string {
+	return strings.NewReplacer(`"`, "'", "\r", " ", "\n", " ").Replace(s)
+}
+
+// YesNo форматирует флаг для атрибутов HLS вроде DEFAULT=YES.
+func YesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
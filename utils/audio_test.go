@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildAudioRenditions(t *testing.T) {
+	info := &MediaInfo{Audio: []AudioStream{
+		{Index: 0, Channels: 6, Language: "eng", Title: "Original"},
+		{Index: 1, Channels: 1, Language: "rus", Default: true},
+		{Index: 2, Channels: 2, Language: "und"},
+		{Index: 3, Channels: 0, Language: "ger", Default: true},
+	}}
+	want := []AudioRendition{
+		{BaseName: "audio_0", Stream: 0, Name: "Original", Language: "eng", Channels: 2, Bitrate: "192k"},
+		{BaseName: "audio_1", Stream: 1, Name: "rus", Language: "rus", Channels: 1, Bitrate: "192k", Default: true},
+		{BaseName: "audio_2", Stream: 2, Name: "Audio 3", Language: "", Channels: 2, Bitrate: "192k"},
+		{BaseName: "audio_3", Stream: 3, Name: "ger", Language: "ger", Channels: 1, Bitrate: "192k"},
+	}
+	if got := BuildAudioRenditions(info, "192k"); !slices.Equal(got, want) {
+		t.Errorf("BuildAudioRenditions =\n%+v\nожидалось\n%+v", got, want)
+	}
+}
+
+func TestBuildAudioRenditionsDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults []bool
+		want     int // Номер дорожки по умолчанию, -1 - дорожек нет
+	}{
+		{"без дорожек", nil, -1},
+		{"одна дорожка без пометки", []bool{false}, 0},
+		{"нет пометок - первая", []bool{false, false, false}, 0},
+		{"помеченная дорожка", []bool{false, false, true}, 2},
+		{"несколько пометок - первая помеченная", []bool{false, true, true}, 1},
+	}
+	for _, tt := range tests {
+		info := &MediaInfo{}
+		for i, d := range tt.defaults {
+			info.Audio = append(info.Audio, AudioStream{Index: i, Channels: 2, Default: d})
+		}
+		got := -1
+		for i, a := range BuildAudioRenditions(info, "128k") {
+			if a.Default {
+				if got != -1 {
+					t.Errorf("%s: по умолчанию включены дорожки %d и %d", tt.name, got, i)
+				}
+				got = i
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: по умолчанию дорожка %d, ожидалась %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Пометка основной дорожки доходит из disposition ffprobe до мастер-плейлиста.
+func TestBuildAudioRenditionsFromProbe(t *testing.T) {
+	fakeProbe(t, `{
+		"streams": [
+			{"codec_type": "video", "width": 1920, "height": 1080, "avg_frame_rate": "25/1"},
+			{"codec_type": "audio", "channels": 2, "tags": {"language": "eng"}, "disposition": {"default": 0}},
+			{"codec_type": "audio", "channels": 6, "tags": {"language": "rus", "title": "Дубляж"}, "disposition": {"default": 1}}
+		],
+		"format": {"duration": "60"}
+	}`)
+	info, err := Probe("input.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	renditions := BuildAudioRenditions(info, "192k")
+	if len(renditions) != 2 || renditions[0].Default || !renditions[1].Default {
+		t.Fatalf("дорожки = %+v, ожидалась основной вторая", renditions)
+	}
+	want := `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="Дубляж",LANGUAGE="rus",DEFAULT=YES,AUTOSELECT=YES,CHANNELS="2",URI="audio_1.m3u8"`
+	if got := renditions[1].mediaTag(); got != want {
+		t.Errorf("mediaTag =\n%s\nожидалось\n%s", got, want)
+	}
+}
+
+func TestMediaTag(t *testing.T) {
+	tests := []struct {
+		name string
+		a    AudioRendition
+		want string
+	}{
+		{
+			name: "с языком",
+			a:    AudioRendition{BaseName: "audio_0", Name: "English", Language: "eng", Channels: 2, Default: true},
+			want: `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="English",LANGUAGE="eng",DEFAULT=YES,AUTOSELECT=YES,CHANNELS="2",URI="audio_0.m3u8"`,
+		},
+		{
+			name: "без языка",
+			a:    AudioRendition{BaseName: "audio_1", Name: "Audio 2", Channels: 1},
+			want: `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="Audio 2",DEFAULT=NO,AUTOSELECT=YES,CHANNELS="1",URI="audio_1.m3u8"`,
+		},
+		{
+			name: "кавычки и переводы строк экранируются",
+			a:    AudioRendition{BaseName: "audio_2", Name: "Режиссер \"Вася\"\r\nкомментарий", Language: "r\"u", Channels: 2},
+			want: `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="Режиссер 'Вася'  комментарий",LANGUAGE="r'u",DEFAULT=NO,AUTOSELECT=YES,CHANNELS="2",URI="audio_2.m3u8"`,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.a.mediaTag(); got != tt.want {
+			t.Errorf("%s:\n%s\nожидалось\n%s", tt.name, got, tt.want)
+		}
+	}
+}
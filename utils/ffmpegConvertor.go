@@ -20,7 +20,7 @@ func ConvertToMP4(inputPath string, outputPath string) error {
 		return err
 	}
 
-	if _, err := exec.LookPath("ffmpeg"); err != nil {
+	if _, err := exec.LookPath(FFmpegPath); err != nil {
 		return fmt.Errorf("ffmpeg не установлен или не в PATH: %v", err)
 	}
 
@@ -42,7 +42,7 @@ func ConvertToMP4(inputPath string, outputPath string) error {
 
 	slog.Info("Запуск ffmpeg", "args", args)
 
-	cmd := exec.Command("ffmpeg", args...)
+	cmd := exec.Command(FFmpegPath, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"fmt"
+	"log/slog"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,22 +13,24 @@ import (
 
 // Структура для определения настроек качества
 type HLSQuality struct {
-	Resolution   string // Например, "854x480"
-	VideoBitrate string // Например, "1M" (1 Mbps)
-	AudioBitrate string // Например, "96k" (96 kbps)
-	BaseName     string // Например, "480p"
-	Bandwidth    int    // Суммарный битрейт (видео + аудио) для #EXT-X-STREAM-INF, в bps
+	Height       int    `yaml:"height"`        // Ступень качества, например 480 - рамка 854x480
+	Resolution   string `yaml:"-"`             // Например, "854x480", вычисляется BuildLadder под исходник
+	VideoBitrate string `yaml:"video_bitrate"` // Например, "1M" (1 Mbps)
+	AudioBitrate string `yaml:"audio_bitrate"` // Например, "96k" (96 kbps)
+	BaseName     string `yaml:"name"`          // Например, "480p"
+	Bandwidth    int    `yaml:"-"`             // Суммарный битрейт (видео + аудио) для #EXT-X-STREAM-INF, в bps
+	Level        string `yaml:"-"`             // Уровень H.264, например "3.1", вычисляется BuildLadder
+	Codecs       string `yaml:"-"`             // Значение CODECS для мастер-плейлиста, вычисляется BuildLadder
 }
 
 func generateSingleQualityHLS(
 	inputPath string,
 	outputPathDir string,
-	segmentDuration int,
+	profile HLSProfile,
 	playlistType string,
-	resolution string,
-	videoBitrate string,
-	audioBitrate string,
-	outputBaseName string,
+	q HLSQuality,
+	info *MediaInfo,
+	onProgress func(outTime, speed, percent float64),
 ) error {
 	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
 		err := fmt.Errorf("входной файл не найден: %s", inputPath)
@@ -36,34 +40,77 @@ func generateSingleQualityHLS(
 		return fmt.Errorf("не удалось создать выходную директорию: %w", err)
 	}
 
-	outputPlaylistPath := filepath.Join(outputPathDir, fmt.Sprintf("%s.m3u8", outputBaseName))
+	outputPlaylistPath := filepath.Join(outputPathDir, fmt.Sprintf("%s.m3u8", q.BaseName))
+	width, height, _ := strings.Cut(q.Resolution, "x")
+
+	// Ключевой кадр в начале каждого сегмента: иначе сегменты получаются
+	// разной длины и клиентам сложнее держать синхронизацию
+	fps := info.FrameRate
+	if fps <= 0 {
+		fps = 30
+	}
+	gop := strconv.Itoa(int(math.Round(fps * float64(profile.SegmentDuration))))
 
 	args := []string{
+		"-progress", "pipe:1", // Прогресс в формате key=value в stdout
+		"-nostats",
 		"-i", inputPath,
+		"-map", "0:v:0",
 		"-c:v", "libx264",
-		"-b:v", videoBitrate,
-		"-preset", "ultrafast",
-		"-vf", fmt.Sprintf("scale=%s", resolution),
-		"-c:a", "aac",
-		"-b:a", audioBitrate,
+		"-preset", profile.Preset,
+		"-profile:v", "high",
+		"-level:v", q.Level,
+		"-pix_fmt", "yuv420p",
+		"-vf", fmt.Sprintf("scale=%s:%s", width, height),
+		"-g", gop,
+		"-keyint_min", gop,
+		"-sc_threshold", "0",
+	}
+	if profile.CRF > 0 {
+		// Постоянное качество, но не выше битрейта ступени
+		args = append(args,
+			"-crf", strconv.Itoa(profile.CRF),
+			"-maxrate", q.VideoBitrate,
+			"-bufsize", q.VideoBitrate,
+		)
+	} else {
+		args = append(args, "-b:v", q.VideoBitrate)
+	}
+	// Звук кодируется отдельно, в аудиодорожки generateAudioRendition
+	args = append(args,
 		"-f", "hls",
-		"-ar", "48000", // ← ОБЯЗАТЕЛЬНО: фиксированная частота
-		"-ac", "2", // ← ОБЯЗАТЕЛЬНО: стерео
 		"-hls_list_size", "0",
 		"-hls_flags", "temp_file",
-		"-hls_time", strconv.Itoa(segmentDuration),
+		"-hls_time", strconv.Itoa(profile.SegmentDuration),
 		"-hls_playlist_type", playlistType,
 		"-hls_segment_type", "fmp4",
-		"-hls_segment_filename", filepath.Join(outputPathDir, fmt.Sprintf("%s_%%03d.fmp4", outputBaseName)),
+		"-hls_fmp4_init_filename", fmt.Sprintf("%s_init.mp4", q.BaseName),
+		"-hls_segment_filename", filepath.Join(outputPathDir, fmt.Sprintf("%s_%%03d.fmp4", q.BaseName)),
 		outputPlaylistPath,
-	}
+	)
 
-	cmd := exec.Command("ffmpeg", args...)
+	if err := runWithProgress(args, info.Duration, onProgress); err != nil {
+		return fmt.Errorf("ошибка для HLS качества %s: %w", q.BaseName, err)
+	}
+	return nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ошибка при выполнении ffmpeg для HLS качества %s: %w", outputBaseName, err)
+// runWithProgress запускает ffmpeg с аргументами args, в которых задан
+// вывод прогресса "-progress pipe:1", и передает прогресс в onProgress.
+func runWithProgress(args []string, duration float64, onProgress func(outTime, speed, percent float64)) error {
+	cmd := exec.Command(FFmpegPath, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("не удалось получить вывод ffmpeg: %w", err)
 	}
 
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("ошибка при запуске ffmpeg: %w", err)
+	}
+	parseProgress(stdout, duration, onProgress)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ошибка при выполнении ffmpeg: %w", err)
+	}
 	return nil
 }
 
@@ -72,7 +119,9 @@ func generateSingleQualityHLS(
 // outputFoler - папка где будет храниться сгенерированный HLS плейлист
 // originalFileName - имя оригинального файла (например, "my_awesome_video.mp4").
 // HLS файлы будут сгенерированы в поддиректорию с именем, соответствующим originalFileName без расширения.
-func GenerateAdaptiveHLS(inputFolder, outputFoler, originalFileName string) error {
+// profile - настройки кодирования; из его ступеней кодируются только те, что не выше исходника.
+// onProgress получает прогресс кодирования, может быть nil.
+func GenerateAdaptiveHLS(inputFolder, outputFoler, originalFileName string, profile HLSProfile, onProgress ProgressFunc) error {
 	// Имя папки для HLS-файлов будет именем файла без расширения
 	videoFolderName := strings.TrimSuffix(originalFileName, filepath.Ext(originalFileName))
 
@@ -87,48 +136,107 @@ func GenerateAdaptiveHLS(inputFolder, outputFoler, originalFileName string) erro
 		err := fmt.Errorf("входной файл не найден: %s", inputPath)
 		return err
 	}
-	if _, err := exec.LookPath("ffmpeg"); err != nil {
+	if _, err := exec.LookPath(FFmpegPath); err != nil {
 		return fmt.Errorf("ffmpeg не установлен или не в PATH: %v", err)
 	}
-	if err := os.MkdirAll(outputPathDir, 0755); err != nil {
-		return fmt.Errorf("не удалось создать выходную директорию %s: %w", outputPathDir, err)
+	if err := profile.Validate(); err != nil {
+		return fmt.Errorf("некорректный профиль кодирования: %w", err)
+	}
+	if onProgress == nil {
+		onProgress = func(Progress) {}
+	}
+
+	// 2. Узнаем параметры исходника и выбираем подходящие ему качества
+	info, err := Probe(inputPath)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать параметры видео: %w", err)
+	}
+	qualities := BuildLadder(info, profile.Qualities)
+	if len(qualities) == 0 {
+		return fmt.Errorf("не задано ни одного качества для кодирования")
 	}
+	// Битрейт звука общий для всех качеств, берем его у лучшего (BuildLadder сортирует ступени)
+	audio := BuildAudioRenditions(info, qualities[len(qualities)-1].AudioBitrate)
+	slog.Info("Параметры исходного видео",
+		"path", inputPath,
+		"resolution", fmt.Sprintf("%dx%d", info.Width, info.Height),
+		"fps", info.FrameRate,
+		"audio_channels", info.AudioChannels,
+		"duration", info.Duration,
+		"qualities", len(qualities),
+		"audio_tracks", len(audio),
+	)
 
-	// 2. Определяем конфигурации для разных качеств (можно вынести в config)
-	qualities := []HLSQuality{
-		{Resolution: "854x480", VideoBitrate: "1M", AudioBitrate: "96k", BaseName: "480p", Bandwidth: 1100000},
-		//{Resolution: "1280x720", VideoBitrate: "2M", AudioBitrate: "128k", BaseName: "720p", Bandwidth: 2150000},
-		//{Resolution: "1920x1080", VideoBitrate: "4M", AudioBitrate: "192k", BaseName: "1080p", Bandwidth: 4250000},
+	if err := os.MkdirAll(outputPathDir, 0755); err != nil {
+		return fmt.Errorf("не удалось создать выходную директорию %s: %w", outputPathDir, err)
 	}
 
-	segmentDuration := 10   // Длительность каждого сегмента в секундах
 	playlistType := "event" // Тип плейлиста: "vod" (Video On Demand)
 
 	var generatedPlaylists []HLSQuality
-	err := func() error {
-		err := createMasterPlaylist(outputPathDir, qualities)
-		
-		if(err != nil){
+	var generatedAudio []AudioRendition
+	var lastErr error
+	steps := len(audio) + len(qualities)
+	err = func() error {
+		err := createMasterPlaylist(outputPathDir, qualities, audio)
+		if err != nil {
 			return err
 		}
-		// 3. Генерируем HLS-поток для каждого качества
-		for _, q := range qualities {
+		// 3. Сначала звук: он кодируется быстро, и видео можно смотреть со звуком уже во время кодирования
+		for i, a := range audio {
+			reportAudio := func(outTime, speed, percent float64) {
+				onProgress(Progress{
+					Quality: a.BaseName,
+					OutTime: outTime,
+					Speed:   speed,
+					Percent: (float64(i)*100 + percent) / float64(steps),
+				})
+			}
+			if err := generateAudioRendition(inputPath, outputPathDir, profile, playlistType, a, info, reportAudio); err != nil {
+				lastErr = err
+				slog.Error("Не удалось сгенерировать аудиодорожку HLS", "audio", a.BaseName, "error", err)
+				continue
+			}
+			generatedAudio = append(generatedAudio, a)
+		}
+		if len(audio) > 0 && len(generatedAudio) == 0 {
+			return fmt.Errorf("не удалось сгенерировать ни одной аудиодорожки: %w", lastErr)
+		}
+
+		// 4. Генерируем HLS-поток для каждого качества
+		for i, q := range qualities {
+			reportQuality := func(outTime, speed, percent float64) {
+				onProgress(Progress{
+					Quality: q.BaseName,
+					OutTime: outTime,
+					Speed:   speed,
+					Percent: (float64(len(audio)+i)*100 + percent) / float64(steps),
+				})
+			}
 			err := generateSingleQualityHLS(
 				inputPath,
 				outputPathDir,
-				segmentDuration,
+				profile,
 				playlistType,
-				q.Resolution,
-				q.VideoBitrate,
-				q.AudioBitrate,
-				q.BaseName,
+				q,
+				info,
+				reportQuality,
 			)
-			if err == nil {
-				generatedPlaylists = append(generatedPlaylists, q)
-
+			if err != nil {
+				lastErr = err
+				slog.Error("Не удалось сгенерировать качество HLS", "quality", q.BaseName, "error", err)
+				continue
 			}
+			generatedPlaylists = append(generatedPlaylists, q)
 		}
 
+		if len(generatedPlaylists) == 0 {
+			return fmt.Errorf("не удалось сгенерировать ни одного качества: %w", lastErr)
+		}
+		// Мастер-плейлист не должен ссылаться на качества и дорожки, которые не получились
+		if len(generatedPlaylists) < len(qualities) || len(generatedAudio) < len(audio) {
+			return createMasterPlaylist(outputPathDir, generatedPlaylists, generatedAudio)
+		}
 		return nil
 	}()
 	if err != nil {
@@ -138,10 +246,17 @@ func GenerateAdaptiveHLS(inputFolder, outputFoler, originalFileName string) erro
 		}
 		return fmt.Errorf("ошибка обработки видео: %w", err)
 	}
+
+	// 5. Обложка и превью для перемотки. Видео смотреть можно и без них
+	if err := GenerateThumbnails(inputPath, outputPathDir, info, profile.ThumbnailStep); err != nil {
+		slog.Warn("Не удалось сгенерировать превью", "path", inputPath, "error", err)
+	}
 	return nil
 }
 
-func createMasterPlaylist(outputPathDir string, generatedPlaylists []HLSQuality) error {
+// createMasterPlaylist пишет main.m3u8 с вариантами качества. Если есть аудиодорожки,
+// каждый вариант ссылается на их группу, а звук выбирается отдельно от качества.
+func createMasterPlaylist(outputPathDir string, generatedPlaylists []HLSQuality, audio []AudioRendition) error {
 	masterPlaylistPath := filepath.Join(outputPathDir, "main.m3u8")
 	masterPlaylistFile, err := os.Create(masterPlaylistPath)
 	if err != nil {
@@ -157,19 +272,36 @@ func createMasterPlaylist(outputPathDir string, generatedPlaylists []HLSQuality)
 	if err != nil {
 		return fmt.Errorf("ошибка записи в мастер-плейлист: %w", err)
 	}
+	for _, a := range audio {
+		if _, err := fmt.Fprintln(masterPlaylistFile, a.mediaTag()); err != nil {
+			return fmt.Errorf("ошибка записи в мастер-плейлист для аудиодорожки %s: %w", a.BaseName, err)
+		}
+	}
+	audioGroup := ""
+	if len(audio) > 0 {
+		audioGroup = fmt.Sprintf(",AUDIO=\"%s\"", AudioGroup)
+	}
 
 	for _, q := range generatedPlaylists {
 		// Извлекаем чистый числовой битрейт для Bandwidth из VideoBitrate и AudioBitrate
 		videoBitrateVal := parseBitrateToBPS(q.VideoBitrate)
-		audioBitrateVal := parseBitrateToBPS(q.AudioBitrate)
+		// Битрейт у всех аудиодорожек общий, вариант играет с любой из них
+		audioBitrateVal := 0
+		if len(audio) > 0 {
+			audioBitrateVal = parseBitrateToBPS(audio[0].Bitrate)
+		} else if strings.Contains(q.Codecs, "mp4a") {
+			audioBitrateVal = parseBitrateToBPS(q.AudioBitrate)
+		}
 
 		// Суммарный битрейт видео и аудио для параметра BANDWIDTH
 		bandwidth := videoBitrateVal + audioBitrateVal
 
 		_, err := fmt.Fprintf(masterPlaylistFile,
-			"#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s,CODECS=\"avc1.42e01e,mp4a.40.2\"\n",
+			"#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s,CODECS=\"%s\"%s\n",
 			bandwidth,
-			q.Resolution)
+			q.Resolution,
+			q.Codecs,
+			audioGroup)
 		if err != nil {
 			return fmt.Errorf("ошибка записи в мастер-плейлист для качества %s: %w", q.BaseName, err)
 		}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MediaInfo - параметры исходного файла, полученные от ffprobe.
+type MediaInfo struct {
+	Width         int     // Ширина кадра с учетом поворота (как его увидит зритель)
+	Height        int     // Высота кадра с учетом поворота
+	FrameRate     float64 // Кадров в секунду
+	AudioChannels int     // Количество каналов первой аудиодорожки, 0 - звука нет
+	Duration      float64 // Длительность в секундах
+	Audio         []AudioStream
+	Subtitles     []SubtitleStream
+}
+
+// AudioStream - аудиодорожка исходного файла, например дубляж или оригинал.
+type AudioStream struct {
+	Index    int    // Номер среди аудиодорожек, для -map 0:a:<Index>
+	Channels int    // Количество каналов
+	Language string // Код языка из тегов, пустой - не указан
+	Title    string // Название дорожки из тегов
+	Default  bool   // Дорожка помечена как основная
+}
+
+// SubtitleStream - дорожка субтитров исходного файла.
+type SubtitleStream struct {
+	Index    int    // Номер среди дорожек субтитров, для -map 0:s:<Index>
+	Codec    string // Например, "subrip" или "ass"
+	Language string // Код языка из тегов, пустой - не указан
+	Title    string // Название дорожки из тегов
+	Default  bool   // Дорожка помечена как основная
+}
+
+// IsText сообщает, можно ли перевести дорожку в WebVTT.
+// Субтитры-картинки (PGS, DVD) так не переводятся.
+func (s SubtitleStream) IsText() bool {
+	switch s.Codec {
+	case "subrip", "srt", "ass", "ssa", "webvtt", "mov_text", "text":
+		return true
+	}
+	return false
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType    string            `json:"codec_type"`
+		CodecName    string            `json:"codec_name"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		AvgFrameRate string            `json:"avg_frame_rate"`
+		RFrameRate   string            `json:"r_frame_rate"`
+		Channels     int               `json:"channels"`
+		Tags         map[string]string `json:"tags"`
+		SideDataList []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+		Disposition struct {
+			Default int `json:"default"`
+		} `json:"disposition"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// Probe читает с помощью ffprobe параметры первой видеодорожки файла,
+// а также все его аудиодорожки и дорожки субтитров.
+func Probe(inputPath string) (*MediaInfo, error) {
+	args := []string{
+		"-v", "error",
+		"-show_streams",
+		"-show_format",
+		"-of", "json",
+		inputPath,
+	}
+	out, err := exec.Command(FFprobePath, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при выполнении ffprobe для %s: %w", inputPath, err)
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать вывод ffprobe: %w", err)
+	}
+
+	info := &MediaInfo{}
+	hasVideo := false
+	for _, s := range probe.Streams {
+		switch {
+		case s.CodecType == "video" && !hasVideo && s.Width > 0 && s.Height > 0:
+			hasVideo = true
+			info.Width, info.Height = s.Width, s.Height
+			// Телефоны пишут вертикальное видео как горизонтальное с меткой поворота,
+			// а ffmpeg при кодировании поворачивает кадр, поэтому меняем стороны местами
+			rotation := 0.0
+			if rotate, ok := s.Tags["rotate"]; ok {
+				rotation, _ = strconv.ParseFloat(rotate, 64)
+			}
+			for _, sd := range s.SideDataList {
+				if sd.Rotation != 0 {
+					rotation = sd.Rotation
+				}
+			}
+			if int(math.Abs(rotation))%180 == 90 {
+				info.Width, info.Height = info.Height, info.Width
+			}
+			info.FrameRate = parseFrameRate(s.AvgFrameRate)
+			if info.FrameRate == 0 {
+				info.FrameRate = parseFrameRate(s.RFrameRate)
+			}
+		case s.CodecType == "audio":
+			if info.AudioChannels == 0 {
+				info.AudioChannels = s.Channels
+			}
+			info.Audio = append(info.Audio, AudioStream{
+				Index:    len(info.Audio),
+				Channels: s.Channels,
+				Language: s.Tags["language"],
+				Title:    s.Tags["title"],
+				Default:  s.Disposition.Default == 1,
+			})
+		case s.CodecType == "subtitle":
+			info.Subtitles = append(info.Subtitles, SubtitleStream{
+				Index:    len(info.Subtitles),
+				Codec:    s.CodecName,
+				Language: s.Tags["language"],
+				Title:    s.Tags["title"],
+				Default:  s.Disposition.Default == 1,
+			})
+		}
+	}
+	if !hasVideo {
+		return nil, fmt.Errorf("в файле %s нет видеодорожки", inputPath)
+	}
+
+	if probe.Format.Duration != "" {
+		info.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	}
+	return info, nil
+}
+
+// parseFrameRate разбирает частоту кадров ffprobe вида "30000/1001".
+func parseFrameRate(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		f, _ := strconv.ParseFloat(rate, 64)
+		return f
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// h264Levels - уровни H.264 и их ограничения: макроблоков в секунду и в кадре.
+var h264Levels = []struct {
+	level    string
+	maxMBPS  int
+	maxFrame int
+}{
+	{"3.0", 40500, 1620},
+	{"3.1", 108000, 3600},
+	{"3.2", 216000, 5120},
+	{"4.0", 245760, 8192},
+	{"4.2", 522240, 8704},
+	{"5.0", 589824, 22080},
+	{"5.1", 983040, 36864},
+	{"5.2", 2073600, 36864},
+}
+
+// h264Level подбирает минимальный уровень H.264 для кадра width x height с частотой fps.
+func h264Level(width, height int, fps float64) string {
+	if fps <= 0 {
+		fps = 30
+	}
+	frame := ((width + 15) / 16) * ((height + 15) / 16)
+	mbps := int(float64(frame) * fps)
+	for _, l := range h264Levels {
+		if frame <= l.maxFrame && mbps <= l.maxMBPS {
+			return l.level
+		}
+	}
+	return h264Levels[len(h264Levels)-1].level
+}
+
+// h264Codecs возвращает строку CODECS для профиля High и уровня level, например "avc1.64001f".
+func h264Codecs(level string) string {
+	major, minor, _ := strings.Cut(level, ".")
+	m, _ := strconv.Atoi(major)
+	n, _ := strconv.Atoi(minor)
+	return fmt.Sprintf("avc1.6400%02x", m*10+n)
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// DefaultLadder - ступени качества по умолчанию, от худшего к лучшему.
+// Resolution у ступеней не задан: его вычисляет BuildLadder под пропорции исходника.
+var DefaultLadder = []HLSQuality{
+	{Height: 360, VideoBitrate: "600k", AudioBitrate: "64k", BaseName: "360p"},
+	{Height: 480, VideoBitrate: "1M", AudioBitrate: "96k", BaseName: "480p"},
+	{Height: 720, VideoBitrate: "2M", AudioBitrate: "128k", BaseName: "720p"},
+	{Height: 1080, VideoBitrate: "4M", AudioBitrate: "192k", BaseName: "1080p"},
+	{Height: 1440, VideoBitrate: "8M", AudioBitrate: "192k", BaseName: "1440p"},
+	{Height: 2160, VideoBitrate: "14M", AudioBitrate: "192k", BaseName: "2160p"},
+}
+
+// BuildLadder выбирает из ladder ступени, не превышающие качество исходника,
+// и вычисляет для них разрешение с сохранением пропорций кадра.
+// Ступень Height задает рамку 16:9 (например, 1920x1080 для 1080p), в которую
+// вписывается кадр; для вертикального видео рамка поворачивается (1080x1920).
+// Так широкоформатный фильм 3840x1606 в 1080p станет 1920x803, а не 2582x1080.
+// Если исходник меньше самой низкой ступени, она кодируется в исходном размере.
+// Ступени возвращаются от худшей к лучшей, даже если в ladder они заданы в другом порядке.
+func BuildLadder(info *MediaInfo, ladder []HLSQuality) []HLSQuality {
+	var qualities []HLSQuality
+	var smallest *HLSQuality
+	for i := range ladder {
+		rung := ladder[i]
+		if smallest == nil || rung.Height < smallest.Height {
+			smallest = &ladder[i]
+		}
+		if scale := fitScale(info, rung.Height); scale <= 1 {
+			qualities = append(qualities, fitQuality(info, rung, scale))
+		}
+	}
+	if len(qualities) == 0 && smallest != nil {
+		qualities = append(qualities, fitQuality(info, *smallest, 1))
+	}
+	slices.SortStableFunc(qualities, func(a, b HLSQuality) int {
+		return cmp.Compare(a.Height, b.Height)
+	})
+	return qualities
+}
+
+// fitScale возвращает коэффициент, с которым кадр исходника вписывается в рамку ступени height.
+func fitScale(info *MediaInfo, height int) float64 {
+	boxWidth, boxHeight := float64(height)*16/9, float64(height)
+	if info.Height > info.Width {
+		boxWidth, boxHeight = boxHeight, boxWidth
+	}
+	return min(boxWidth/float64(info.Width), boxHeight/float64(info.Height))
+}
+
+// fitQuality масштабирует кадр исходника с коэффициентом scale
+// и заполняет у ступени Resolution, Level и Codecs.
+func fitQuality(info *MediaInfo, q HLSQuality, scale float64) HLSQuality {
+	width := evenRound(float64(info.Width) * scale)
+	height := evenRound(float64(info.Height) * scale)
+
+	q.Resolution = fmt.Sprintf("%dx%d", width, height)
+	q.Level = h264Level(width, height, info.FrameRate)
+	q.Codecs = h264Codecs(q.Level)
+	if info.AudioChannels > 0 {
+		q.Codecs += ",mp4a.40.2"
+	}
+	return q
+}
+
+// evenRound округляет до ближайшего четного числа: libx264 с yuv420p не принимает нечетные размеры.
+func evenRound(v float64) int {
+	return max(2, int(math.Round(v/2))*2)
+}
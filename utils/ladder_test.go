@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"testing"
+)
+
+// fakeFFprobeEnv заставляет тестовый бинарник вести себя как ffprobe,
+// который печатает содержимое переменной и завершается успешно.
+const fakeFFprobeEnv = "VIDEO_TEST_FFPROBE_OUTPUT"
+
+func TestMain(m *testing.M) {
+	if out, ok := os.LookupEnv(fakeFFprobeEnv); ok {
+		fmt.Print(out)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeProbe подменяет ffprobe тестовым бинарником, который выводит out.
+func fakeProbe(t *testing.T, out string) {
+	t.Helper()
+	old := FFprobePath
+	FFprobePath = os.Args[0]
+	t.Setenv(fakeFFprobeEnv, out)
+	t.Cleanup(func() { FFprobePath = old })
+}
+
+// resolutions возвращает имена и разрешения ступеней в порядке следования.
+func resolutions(qualities []HLSQuality) []string {
+	var got []string
+	for _, q := range qualities {
+		got = append(got, q.BaseName+" "+q.Resolution)
+	}
+	return got
+}
+
+func TestBuildLadder(t *testing.T) {
+	tests := []struct {
+		name   string
+		info   MediaInfo
+		ladder []HLSQuality
+		want   []string
+	}{
+		{
+			name: "горизонтальное 1080p",
+			info: MediaInfo{Width: 1920, Height: 1080, FrameRate: 30},
+			want: []string{"360p 640x360", "480p 854x480", "720p 1280x720", "1080p 1920x1080"},
+		},
+		{
+			name: "вертикальное 1080x1920",
+			info: MediaInfo{Width: 1080, Height: 1920, FrameRate: 30},
+			want: []string{"360p 360x640", "480p 480x854", "720p 720x1280", "1080p 1080x1920"},
+		},
+		{
+			name: "широкоформатное 3840x1606",
+			info: MediaInfo{Width: 3840, Height: 1606, FrameRate: 24},
+			want: []string{
+				"360p 640x268", "480p 854x356", "720p 1280x536",
+				"1080p 1920x804", "1440p 2560x1070", "2160p 3840x1606",
+			},
+		},
+		{
+			name: "меньше 360p",
+			info: MediaInfo{Width: 320, Height: 240, FrameRate: 25},
+			want: []string{"360p 320x240"},
+		},
+		{
+			name: "нечетные размеры меньше 360p",
+			info: MediaInfo{Width: 321, Height: 241, FrameRate: 25},
+			want: []string{"360p 322x242"},
+		},
+		{
+			name: "нечетные размеры",
+			info: MediaInfo{Width: 1001, Height: 563, FrameRate: 25},
+			want: []string{"360p 640x360", "480p 854x480"},
+		},
+		{
+			name: "ступени не по порядку",
+			info: MediaInfo{Width: 1920, Height: 1080, FrameRate: 30},
+			ladder: []HLSQuality{
+				{Height: 1080, VideoBitrate: "4M", AudioBitrate: "192k", BaseName: "1080p"},
+				{Height: 360, VideoBitrate: "600k", AudioBitrate: "64k", BaseName: "360p"},
+				{Height: 720, VideoBitrate: "2M", AudioBitrate: "128k", BaseName: "720p"},
+			},
+			want: []string{"360p 640x360", "720p 1280x720", "1080p 1920x1080"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ladder := tt.ladder
+			if ladder == nil {
+				ladder = DefaultLadder
+			}
+			qualities := BuildLadder(&tt.info, ladder)
+			if got := resolutions(qualities); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("BuildLadder = %v, ожидалось %v", got, tt.want)
+			}
+			for _, q := range qualities {
+				if q.Level == "" || q.Codecs == "" {
+					t.Errorf("у ступени %s не заполнены Level и Codecs: %+v", q.BaseName, q)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildLadderRotated(t *testing.T) {
+	// Телефон записал вертикальное видео как 1920x1080 с поворотом на -90 градусов
+	fakeProbe(t, `{
+		"streams": [{
+			"codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080,
+			"avg_frame_rate": "30/1", "side_data_list": [{"rotation": -90}]
+		}],
+		"format": {"duration": "12.5"}
+	}`)
+	info, err := Probe("portrait.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Width != 1080 || info.Height != 1920 {
+		t.Fatalf("Probe = %dx%d, ожидалось 1080x1920 после поворота", info.Width, info.Height)
+	}
+	want := []string{"360p 360x640", "480p 480x854", "720p 720x1280", "1080p 1080x1920"}
+	if got := resolutions(BuildLadder(info, DefaultLadder)); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("BuildLadder = %v, ожидалось %v", got, want)
+	}
+}
+
+func TestFitScale(t *testing.T) {
+	tests := []struct {
+		width, height int
+		rung          int
+		want          float64
+	}{
+		{1920, 1080, 1080, 1},
+		{1920, 1080, 720, 2.0 / 3},
+		{1080, 1920, 720, 2.0 / 3},
+		{3840, 1606, 1080, 0.5},
+		{1440, 1080, 720, 2.0 / 3}, // 4:3 упирается в высоту рамки
+		{320, 240, 360, 1.5},
+	}
+	for _, tt := range tests {
+		got := fitScale(&MediaInfo{Width: tt.width, Height: tt.height}, tt.rung)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("fitScale(%dx%d, %d) = %v, ожидалось %v", tt.width, tt.height, tt.rung, got, tt.want)
+		}
+	}
+}
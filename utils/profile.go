@@ -0,0 +1,57 @@
+package utils
+
+import "fmt"
+
+// Пути к исполняемым файлам ffmpeg и ffprobe. Переопределяются из конфигурации.
+var (
+	FFmpegPath  = "ffmpeg"
+	FFprobePath = "ffprobe"
+)
+
+// HLSProfile - именованный набор настроек кодирования HLS.
+type HLSProfile struct {
+	Qualities       []HLSQuality `yaml:"qualities"`        // Ступени качества в любом порядке, BuildLadder сортирует их по высоте
+	SegmentDuration int          `yaml:"segment_duration"` // Длительность сегмента в секундах
+	Preset          string       `yaml:"preset"`           // Пресет libx264, например "ultrafast"
+	CRF             int          `yaml:"crf"`              // Если больше 0, кодируем с CRF, а VideoBitrate становится потолком
+	ThumbnailStep   int          `yaml:"thumbnail_step"`   // Через сколько секунд брать кадры превью для перемотки
+}
+
+// DefaultProfile - профиль кодирования по умолчанию.
+var DefaultProfile = HLSProfile{
+	Qualities:       DefaultLadder,
+	SegmentDuration: 10,
+	Preset:          "ultrafast",
+	ThumbnailStep:   10,
+}
+
+// Validate проверяет, что профилем можно кодировать.
+func (p *HLSProfile) Validate() error {
+	if len(p.Qualities) == 0 {
+		return fmt.Errorf("не задано ни одного качества")
+	}
+	if p.SegmentDuration <= 0 {
+		return fmt.Errorf("длительность сегмента должна быть положительной: %d", p.SegmentDuration)
+	}
+	if p.Preset == "" {
+		return fmt.Errorf("не задан пресет")
+	}
+	if p.ThumbnailStep <= 0 {
+		return fmt.Errorf("шаг превью должен быть положительным: %d", p.ThumbnailStep)
+	}
+	if p.CRF < 0 || p.CRF > 51 {
+		return fmt.Errorf("CRF должен быть от 0 до 51: %d", p.CRF)
+	}
+
+	names := make(map[string]bool)
+	for _, q := range p.Qualities {
+		if q.BaseName == "" || q.Height <= 0 || q.VideoBitrate == "" || q.AudioBitrate == "" {
+			return fmt.Errorf("у качества должны быть заданы name, height, video_bitrate и audio_bitrate: %+v", q)
+		}
+		if names[q.BaseName] {
+			return fmt.Errorf("качество %q задано дважды", q.BaseName)
+		}
+		names[q.BaseName] = true
+	}
+	return nil
+}
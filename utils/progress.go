@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Progress - прогресс транскодирования, разобранный из вывода ffmpeg -progress.
+type Progress struct {
+	Quality string  `json:"quality"`  // Что сейчас кодируется: качество "480p" или аудиодорожка "audio_0"
+	OutTime float64 `json:"out_time"` // Сколько секунд видео уже закодировано
+	Speed   float64 `json:"speed"`    // Скорость кодирования относительно реального времени
+	Percent float64 `json:"percent"`  // Общий процент готовности по всем качествам, 0-100
+}
+
+// ProgressFunc получает обновления прогресса транскодирования.
+type ProgressFunc func(Progress)
+
+// parseProgress читает блоки key=value из вывода ffmpeg -progress
+// и вызывает report в конце каждого блока (строка progress=continue|end).
+// duration - длительность исходника в секундах; если она неизвестна, Percent не считается.
+func parseProgress(r io.Reader, duration float64, report func(outTime, speed, percent float64)) {
+	var outTime, speed float64
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "out_time_us":
+			// Бывает "N/A" в начале кодирования — тогда оставляем прошлое значение
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+				outTime = float64(us) / 1e6
+			}
+		case "speed":
+			if s, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+				speed = s
+			}
+		case "progress":
+			var percent float64
+			if value == "end" {
+				percent = 100
+			} else if duration > 0 {
+				percent = min(outTime/duration*100, 100)
+			}
+			report(outTime, speed, percent)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseProgress(t *testing.T) {
+	// Блоки в том виде, в котором их пишет ffmpeg -progress pipe:1
+	output := strings.Join([]string{
+		"frame=0", "out_time_us=N/A", "speed=N/A", "progress=continue",
+		"frame=120", "out_time_us=5000000", "speed=2.5x", "progress=continue",
+		"out_time_us=N/A", "speed=3x", "progress=continue",
+		"out_time_us=12000000", "progress=continue",
+		"out_time_us=10000000", "speed=2x", "progress=end",
+	}, "\n")
+
+	tests := []struct {
+		name     string
+		duration float64
+		want     []string
+	}{
+		{
+			name:     "длительность известна",
+			duration: 10,
+			want: []string{
+				"0 0 0",
+				"5 2.5 50",
+				"5 3 50",   // out_time_us=N/A не сбрасывает закодированное время
+				"12 3 100", // ffmpeg может закодировать чуть больше длительности из ffprobe
+				"10 2 100",
+			},
+		},
+		{
+			name:     "длительность неизвестна",
+			duration: 0,
+			want:     []string{"0 0 0", "5 2.5 0", "5 3 0", "12 3 0", "10 2 100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			parseProgress(strings.NewReader(output), tt.duration, func(outTime, speed, percent float64) {
+				got = append(got, fmt.Sprint(outTime, speed, percent))
+			})
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("прогресс = %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProgressIgnoresNoise(t *testing.T) {
+	// Строки без "=" и неизвестные ключи пропускаются, отчет только по строке progress
+	output := "ffmpeg version n7.0\r\n  bitrate=N/A\r\nout_time_us=2500000\r\nprogress=continue\r\n"
+	calls := 0
+	parseProgress(strings.NewReader(output), 5, func(outTime, speed, percent float64) {
+		calls++
+		if outTime != 2.5 || speed != 0 || percent != 50 {
+			t.Errorf("прогресс = %v %v %v, ожидалось 2.5 0 50", outTime, speed, percent)
+		}
+	})
+	if calls != 1 {
+		t.Errorf("отчетов %d, ожидался один", calls)
+	}
+}
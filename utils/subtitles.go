@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SubtitleBaseName возвращает имя плейлиста субтитров с ID id без расширения.
+// Сегменты лежат рядом: <имя>_000.vtt, <имя>_001.vtt, ...
+func SubtitleBaseName(id int) string {
+	return fmt.Sprintf("sub_%d", id)
+}
+
+// ConvertToWebVTT переводит дорожку субтитров stream файла inputPath в WebVTT.
+// Подходит и для видео с дорожками субтитров, и для отдельных файлов SRT и ASS:
+// у них единственная дорожка имеет номер 0.
+func ConvertToWebVTT(inputPath string, stream int) ([]byte, error) {
+	args := []string{
+		"-v", "error",
+		"-i", inputPath,
+		"-map", fmt.Sprintf("0:s:%d", stream),
+		"-c:s", "webvtt",
+		"-f", "webvtt",
+		"pipe:1",
+	}
+	out, err := exec.Command(FFmpegPath, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			lines := strings.Split(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+			return nil, fmt.Errorf("ошибка перевода субтитров в WebVTT: %w: %s", err, lines[len(lines)-1])
+		}
+		return nil, fmt.Errorf("ошибка перевода субтитров в WebVTT: %w", err)
+	}
+	if !bytes.HasPrefix(bytes.TrimPrefix(out, []byte("\ufeff")), []byte("WEBVTT")) {
+		return nil, fmt.Errorf("ffmpeg вернул не WebVTT")
+	}
+	return out, nil
+}
+
+// vttCue - реплика WebVTT: время и блок целиком, вместе со строкой времени.
+type vttCue struct {
+	start, end float64
+	block      string
+}
+
+// WriteSubtitlePlaylist нарезает WebVTT на сегменты по segmentDuration секунд
+// и пишет в outputDir плейлист <baseName>.m3u8 с ними.
+// Реплика, которая идет на стыке сегментов, попадает в оба: плееры убирают повторы.
+// duration - длительность видео, 0 - по последней реплике.
+func WriteSubtitlePlaylist(outputDir, baseName string, vtt []byte, duration float64, segmentDuration int) error {
+	if segmentDuration <= 0 {
+		return fmt.Errorf("длительность сегмента должна быть положительной: %d", segmentDuration)
+	}
+	header, cues, err := parseVTT(vtt)
+	if err != nil {
+		return err
+	}
+	for _, cue := range cues {
+		duration = math.Max(duration, cue.end)
+	}
+	count := max(int(math.Ceil(duration/float64(segmentDuration))), 1)
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("не удалось создать выходную директорию: %w", err)
+	}
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n")
+	playlist.WriteString("#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&playlist, "#EXT-X-TARGETDURATION:%d\n", segmentDuration)
+	playlist.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	playlist.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	for i := 0; i < count; i++ {
+		start := float64(i * segmentDuration)
+		end := math.Min(float64((i+1)*segmentDuration), duration)
+
+		var segment strings.Builder
+		segment.WriteString(header)
+		for _, cue := range cues {
+			if cue.start < end && cue.end > start {
+				segment.WriteString("\n")
+				segment.WriteString(cue.block)
+				segment.WriteString("\n")
+			}
+		}
+
+		segmentName := fmt.Sprintf("%s_%03d.vtt", baseName, i)
+		if err := os.WriteFile(filepath.Join(outputDir, segmentName), []byte(segment.String()), 0644); err != nil {
+			return fmt.Errorf("не удалось сохранить сегмент субтитров %s: %w", segmentName, err)
+		}
+		fmt.Fprintf(&playlist, "#EXTINF:%.3f,\n%s\n", end-start, segmentName)
+	}
+	playlist.WriteString("#EXT-X-ENDLIST\n")
+
+	playlistPath := filepath.Join(outputDir, baseName+".m3u8")
+	if err := os.WriteFile(playlistPath, []byte(playlist.String()), 0644); err != nil {
+		return fmt.Errorf("не удалось сохранить плейлист субтитров %s: %w", playlistPath, err)
+	}
+	return nil
+}
+
+// parseVTT делит WebVTT на заголовок и реплики. В заголовок попадают строка WEBVTT
+// и блоки STYLE и REGION, к нему добавляется X-TIMESTAMP-MAP: время реплик
+// отсчитывается от начала видео. Комментарии NOTE отбрасываются.
+func parseVTT(vtt []byte) (string, []vttCue, error) {
+	text := strings.ReplaceAll(strings.TrimPrefix(string(vtt), "\ufeff"), "\r\n", "\n")
+	blocks := strings.Split(text, "\n\n")
+	if !strings.HasPrefix(blocks[0], "WEBVTT") {
+		return "", nil, fmt.Errorf("файл не начинается с WEBVTT")
+	}
+
+	header := []string{"WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:0,LOCAL:00:00:00.000\n"}
+	var cues []vttCue
+	for _, block := range blocks[1:] {
+		block = strings.Trim(block, "\n")
+		switch {
+		case block == "", strings.HasPrefix(block, "NOTE"):
+		case strings.HasPrefix(block, "STYLE"), strings.HasPrefix(block, "REGION"):
+			header = append(header, block+"\n")
+		default:
+			cue, err := parseCue(block)
+			if err != nil {
+				return "", nil, err
+			}
+			cues = append(cues, cue)
+		}
+	}
+	return strings.Join(header, "\n"), cues, nil
+}
+
+// parseCue разбирает реплику: необязательный идентификатор, строку
+// "00:00:01.000 --> 00:00:02.000 <настройки>" и текст.
+func parseCue(block string) (vttCue, error) {
+	lines := strings.SplitN(block, "\n", 3)
+	for _, line := range lines[:min(len(lines), 2)] {
+		from, rest, ok := strings.Cut(line, "-->")
+		if !ok {
+			continue
+		}
+		start, err := parseVTTTimestamp(strings.TrimSpace(from))
+		if err != nil {
+			return vttCue{}, err
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return vttCue{}, fmt.Errorf("некорректная строка времени реплики: %q", line)
+		}
+		end, err := parseVTTTimestamp(fields[0])
+		if err != nil {
+			return vttCue{}, err
+		}
+		return vttCue{start: start, end: end, block: block}, nil
+	}
+	return vttCue{}, fmt.Errorf("у реплики нет строки времени: %q", block)
+}
+
+// parseVTTTimestamp разбирает время вида ЧЧ:ММ:СС.ммм или ММ:СС.ммм в секунды.
+func parseVTTTimestamp(ts string) (float64, error) {
+	parts := strings.Split(ts, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("некорректное время реплики: %q", ts)
+	}
+	var seconds float64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("некорректное время реплики: %q", ts)
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, nil
+}
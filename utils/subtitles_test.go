@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseVTT(t *testing.T) {
+	// BOM и переводы строк Windows, как в файлах из Aegisub и Subtitle Edit
+	vtt := "\ufeffWEBVTT - Перевод\r\n\r\n" +
+		"STYLE\r\n::cue { color: yellow }\r\n\r\n" +
+		"NOTE проверить перевод\r\nвторой строкой\r\n\r\n" +
+		"1\r\n00:00:01.000 --> 00:00:02.500\r\nПривет\r\n\r\n" +
+		"intro-2\r\n00:03.000 --> 00:04.250 align:start position:10%\r\nДве\r\nстроки\r\n\r\n" +
+		"01:00:00.000 --> 01:00:01.000\r\nБез идентификатора\r\n"
+
+	header, cues, err := parseVTT([]byte(vtt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(header, "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:0,LOCAL:00:00:00.000\n") {
+		t.Errorf("заголовок без X-TIMESTAMP-MAP: %q", header)
+	}
+	if !strings.Contains(header, "STYLE\n::cue { color: yellow }\n") {
+		t.Errorf("блок STYLE не попал в заголовок: %q", header)
+	}
+	if strings.Contains(header, "NOTE") || strings.Contains(header, "\r") || strings.Contains(header, "\ufeff") {
+		t.Errorf("в заголовке NOTE, BOM или \\r: %q", header)
+	}
+
+	want := []vttCue{
+		{1, 2.5, "1\n00:00:01.000 --> 00:00:02.500\nПривет"},
+		{3, 4.25, "intro-2\n00:03.000 --> 00:04.250 align:start position:10%\nДве\nстроки"},
+		{3600, 3601, "01:00:00.000 --> 01:00:01.000\nБез идентификатора"},
+	}
+	if len(cues) != len(want) {
+		t.Fatalf("реплик %d, ожидалось %d: %+v", len(cues), len(want), cues)
+	}
+	for i, cue := range cues {
+		if cue != want[i] {
+			t.Errorf("реплика %d = %+v, ожидалось %+v", i, cue, want[i])
+		}
+	}
+}
+
+func TestParseVTTErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		vtt  string
+	}{
+		{"нет WEBVTT", "1\n00:00:01.000 --> 00:00:02.000\nПривет\n"},
+		{"нет строки времени", "WEBVTT\n\n1\nПривет\n"},
+		{"нет конца реплики", "WEBVTT\n\n00:00:01.000 -->\nПривет\n"},
+		{"некорректное время", "WEBVTT\n\n00:01.x --> 00:02.000\nПривет\n"},
+		{"время без минут", "WEBVTT\n\n1.000 --> 2.000\nПривет\n"},
+	}
+	for _, tt := range tests {
+		if _, _, err := parseVTT([]byte(tt.vtt)); err == nil {
+			t.Errorf("%s: ошибка не возвращена", tt.name)
+		}
+	}
+}
+
+func TestWriteSubtitlePlaylist(t *testing.T) {
+	dir := t.TempDir()
+	vtt := "WEBVTT\n\n" +
+		"00:00:01.000 --> 00:00:02.000\nПервый сегмент\n\n" +
+		"00:00:09.000 --> 00:00:11.000\nНа стыке\n\n" +
+		"00:00:12.000 --> 00:00:13.000\nВторой сегмент\n"
+	if err := WriteSubtitlePlaylist(dir, "sub_1", []byte(vtt), 25, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	playlist, err := os.ReadFile(filepath.Join(dir, "sub_1.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXT-X-MEDIA-SEQUENCE:0\n" +
+		"#EXT-X-PLAYLIST-TYPE:VOD\n" +
+		"#EXTINF:10.000,\nsub_1_000.vtt\n" +
+		"#EXTINF:10.000,\nsub_1_001.vtt\n" +
+		"#EXTINF:5.000,\nsub_1_002.vtt\n" +
+		"#EXT-X-ENDLIST\n"
+	if string(playlist) != want {
+		t.Errorf("плейлист:\n%s\nожидался:\n%s", playlist, want)
+	}
+
+	segments := []struct {
+		name     string
+		contains []string
+		excludes []string
+	}{
+		{"sub_1_000.vtt", []string{"Первый сегмент", "На стыке"}, []string{"Второй сегмент"}},
+		{"sub_1_001.vtt", []string{"На стыке", "Второй сегмент"}, []string{"Первый сегмент"}},
+		{"sub_1_002.vtt", nil, []string{"Первый сегмент", "На стыке", "Второй сегмент"}},
+	}
+	for _, seg := range segments {
+		data, err := os.ReadFile(filepath.Join(dir, seg.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := string(data)
+		if !strings.HasPrefix(text, "WEBVTT\nX-TIMESTAMP-MAP=") {
+			t.Errorf("%s начинается не с заголовка: %q", seg.name, text)
+		}
+		for _, s := range seg.contains {
+			if !strings.Contains(text, s) {
+				t.Errorf("в %s нет реплики %q", seg.name, s)
+			}
+		}
+		for _, s := range seg.excludes {
+			if strings.Contains(text, s) {
+				t.Errorf("в %s лишняя реплика %q", seg.name, s)
+			}
+		}
+	}
+}
+
+func TestWriteSubtitlePlaylistDuration(t *testing.T) {
+	// Без длительности видео сегменты считаются по последней реплике
+	dir := t.TempDir()
+	vtt := "WEBVTT\n\n00:14.000 --> 00:21.500\nПоследняя\n"
+	if err := WriteSubtitlePlaylist(dir, "sub_2", []byte(vtt), 0, 10); err != nil {
+		t.Fatal(err)
+	}
+	playlist, err := os.ReadFile(filepath.Join(dir, "sub_2.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(playlist), "#EXTINF:1.500,\nsub_2_002.vtt\n") {
+		t.Errorf("последний сегмент не по концу реплики:\n%s", playlist)
+	}
+
+	if err := WriteSubtitlePlaylist(dir, "sub_3", []byte(vtt), 0, 0); err == nil {
+		t.Error("нулевая длительность сегмента не вернула ошибку")
+	}
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Имена файлов превью в папке HLS видео.
+const (
+	PosterFile     = "poster.jpg"     // Кадр-обложка
+	ThumbnailsFile = "thumbnails.vtt" // Дорожка WebVTT со ссылками на кадры в спрайтах
+)
+
+const (
+	posterMaxWidth  = 1280
+	thumbnailWidth  = 160
+	spriteColumns   = 10
+	spriteRows      = 10
+	spriteFilesMask = "sprite_%03d.jpg" // ffmpeg нумерует спрайты с 1
+)
+
+// GenerateThumbnails сохраняет в outputDir обложку и превью для перемотки:
+// кадры каждые interval секунд склеиваются в спрайты по 10x10,
+// а thumbnails.vtt сопоставляет каждому отрезку времени его кадр (#xywh=...).
+// Такой формат понимают hls.js, Video.js, Shaka и другие плееры.
+func GenerateThumbnails(inputPath, outputDir string, info *MediaInfo, interval int) error {
+	if interval <= 0 {
+		return fmt.Errorf("интервал превью должен быть положительным: %d", interval)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("не удалось создать выходную директорию: %w", err)
+	}
+
+	// Первые кадры часто черные, поэтому обложку берем чуть дальше от начала
+	posterAt := math.Min(info.Duration/10, 30)
+	err := runFFmpeg(
+		"-ss", strconv.FormatFloat(posterAt, 'f', 3, 64),
+		"-i", inputPath,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale='min(%d,iw)':-2", posterMaxWidth),
+		"-q:v", "3",
+		"-y", filepath.Join(outputDir, PosterFile),
+	)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить обложку: %w", err)
+	}
+
+	if info.Duration <= 0 {
+		return nil // Без длительности не построить дорожку превью
+	}
+	width, height := thumbnailSize(info)
+	err = runFFmpeg(
+		"-i", inputPath,
+		"-vf", fmt.Sprintf("fps=1/%d,scale=%d:%d,tile=%dx%d", interval, width, height, spriteColumns, spriteRows),
+		"-q:v", "5",
+		"-y", filepath.Join(outputDir, spriteFilesMask),
+	)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить спрайты превью: %w", err)
+	}
+
+	vtt := buildThumbnailsVTT(info.Duration, interval, width, height)
+	if err := os.WriteFile(filepath.Join(outputDir, ThumbnailsFile), []byte(vtt), 0644); err != nil {
+		return fmt.Errorf("не удалось сохранить %s: %w", ThumbnailsFile, err)
+	}
+	return nil
+}
+
+// thumbnailSize возвращает размер кадра превью с пропорциями исходника.
+// Высота четная: этого требует yuv420p.
+func thumbnailSize(info *MediaInfo) (int, int) {
+	if info.Width <= 0 || info.Height <= 0 {
+		return thumbnailWidth, thumbnailWidth * 9 / 16
+	}
+	height := int(math.Round(float64(thumbnailWidth*info.Height)/float64(info.Width)/2)) * 2
+	return thumbnailWidth, max(height, 2)
+}
+
+// buildThumbnailsVTT строит дорожку WebVTT: по отрезку на каждый кадр спрайтов.
+func buildThumbnailsVTT(duration float64, interval, width, height int) string {
+	perSprite := spriteColumns * spriteRows
+	count := int(math.Ceil(duration / float64(interval)))
+
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for i := 0; i < count; i++ {
+		start := float64(i * interval)
+		end := math.Min(float64((i+1)*interval), duration)
+		cell := i % perSprite
+		fmt.Fprintf(&b, "\n%s --> %s\n", vttTimestamp(start), vttTimestamp(end))
+		fmt.Fprintf(&b, spriteFilesMask+"#xywh=%d,%d,%d,%d\n",
+			i/perSprite+1,
+			cell%spriteColumns*width,
+			cell/spriteColumns*height,
+			width,
+			height,
+		)
+	}
+	return b.String()
+}
+
+// vttTimestamp форматирует секунды как ЧЧ:ММ:СС.ммм.
+func vttTimestamp(seconds float64) string {
+	ms := int(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// runFFmpeg запускает ffmpeg и добавляет к ошибке его последнее сообщение.
+func runFFmpeg(args ...string) error {
+	out, err := exec.Command(FFmpegPath, append([]string{"-v", "error"}, args...)...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			lines := strings.Split(msg, "\n")
+			return fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+		}
+		return err
+	}
+	return nil
+}
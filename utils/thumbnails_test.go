@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThumbnailSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		info          MediaInfo
+		width, height int
+	}{
+		{"16:9", MediaInfo{Width: 1920, Height: 1080}, 160, 90},
+		{"высота округляется до четной", MediaInfo{Width: 720, Height: 300}, 160, 66},
+		{"вертикальное", MediaInfo{Width: 1080, Height: 1920}, 160, 284},
+		{"очень широкое", MediaInfo{Width: 1920, Height: 2}, 160, 2},
+		{"нулевой размер", MediaInfo{}, 160, 90},
+		{"нулевая высота", MediaInfo{Width: 1920}, 160, 90},
+		{"отрицательная ширина", MediaInfo{Width: -1, Height: 1080}, 160, 90},
+	}
+	for _, tt := range tests {
+		width, height := thumbnailSize(&tt.info)
+		if width != tt.width || height != tt.height {
+			t.Errorf("%s: thumbnailSize = %dx%d, ожидалось %dx%d", tt.name, width, height, tt.width, tt.height)
+		}
+	}
+}
+
+func TestVTTTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00:00.000"},
+		{1.5, "00:00:01.500"},
+		{59.9994, "00:00:59.999"},
+		{59.9996, "00:01:00.000"},
+		{61.25, "00:01:01.250"},
+		{3600, "01:00:00.000"},
+		{3723.004, "01:02:03.004"},
+		{36000, "10:00:00.000"},
+	}
+	for _, tt := range tests {
+		if got := vttTimestamp(tt.seconds); got != tt.want {
+			t.Errorf("vttTimestamp(%v) = %q, ожидалось %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestBuildThumbnailsVTT(t *testing.T) {
+	// 1005 секунд по 10: 101 кадр, последний уже во втором спрайте и короче интервала
+	vtt := buildThumbnailsVTT(1005, 10, 160, 90)
+	if !strings.HasPrefix(vtt, "WEBVTT\n\n") {
+		t.Fatalf("нет заголовка WEBVTT:\n%s", vtt)
+	}
+	cues := strings.Split(strings.TrimSpace(strings.TrimPrefix(vtt, "WEBVTT\n")), "\n\n")
+	if len(cues) != 101 {
+		t.Fatalf("кадров %d, ожидался 101", len(cues))
+	}
+
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "00:00:00.000 --> 00:00:10.000\nsprite_001.jpg#xywh=0,0,160,90"},
+		{1, "00:00:10.000 --> 00:00:20.000\nsprite_001.jpg#xywh=160,0,160,90"},
+		{9, "00:01:30.000 --> 00:01:40.000\nsprite_001.jpg#xywh=1440,0,160,90"},
+		{10, "00:01:40.000 --> 00:01:50.000\nsprite_001.jpg#xywh=0,90,160,90"},
+		{99, "00:16:30.000 --> 00:16:40.000\nsprite_001.jpg#xywh=1440,810,160,90"},
+		// Сотый кадр начинает новый спрайт с левого верхнего угла
+		{100, "00:16:40.000 --> 00:16:45.000\nsprite_002.jpg#xywh=0,0,160,90"},
+	}
+	for _, tt := range tests {
+		if cues[tt.index] != tt.want {
+			t.Errorf("кадр %d:\n%s\nожидалось:\n%s", tt.index, cues[tt.index], tt.want)
+		}
+	}
+}
+
+func TestBuildThumbnailsVTTShort(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration float64
+		interval int
+		width    int
+		height   int
+		want     string
+	}{
+		{
+			name:     "короче интервала",
+			duration: 3.5, interval: 10, width: 160, height: 90,
+			want: "WEBVTT\n\n00:00:00.000 --> 00:00:03.500\nsprite_001.jpg#xywh=0,0,160,90\n",
+		},
+		{
+			name:     "ровно два интервала",
+			duration: 20, interval: 10, width: 160, height: 284,
+			want: "WEBVTT\n\n00:00:00.000 --> 00:00:10.000\nsprite_001.jpg#xywh=0,0,160,284\n" +
+				"\n00:00:10.000 --> 00:00:20.000\nsprite_001.jpg#xywh=160,0,160,284\n",
+		},
+		{
+			name:     "нулевая длительность",
+			duration: 0, interval: 10, width: 160, height: 90,
+			want: "WEBVTT\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := buildThumbnailsVTT(tt.duration, tt.interval, tt.width, tt.height); got != tt.want {
+			t.Errorf("%s:\n%q\nожидалось:\n%q", tt.name, got, tt.want)
+		}
+	}
+}
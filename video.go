@@ -1,59 +1,233 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	"video/auth"
+	"video/config"
 	"video/database"
+	"video/handlers/room"
+	"video/handlers/user"
 	"video/handlers/video"
+	"video/jobs"
 	"video/logger"
+	"video/playback"
+	"video/rooms"
+	"video/storage"
 	"video/streamer"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 )
 
+// shutdownTimeout - сколько при остановке ждать завершения запросов и текущих задач
+// транскодирования. Недоделанные задачи продолжатся после следующего запуска.
+const shutdownTimeout = 30 * time.Second
+
 // 1. Создание комнаты
 // 2. Закачка на сервер
 // 3. Синхронизация видео
 
 func main() {
-	sqllite, err := database.New("./sqlite.db")
+	configPath := flag.String("config", envOr("VIDEO_CONFIG", "config.yaml"), "путь к файлу конфигурации YAML")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Println(fmt.Errorf("конфигурация не загрузилась: %w", err))
+		return
+	}
+	policy, err := rooms.NewPolicy(cfg.RoomPolicy)
 	if err != nil {
-		fmt.Printf(fmt.Errorf("база данных не открылась: %w", err).Error())
+		fmt.Println(fmt.Errorf("конфигурация не загрузилась: room_policy: %w", err))
 		return
 	}
-	err = sqllite.CreateTable()
+	for _, dir := range []string{cfg.UploadDir, cfg.TemporaryDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Println(fmt.Errorf("не удалось создать папку %s: %w", dir, err))
+			return
+		}
+	}
+
+	db, err := database.Open(cfg.DatabaseDSN())
 	if err != nil {
-		fmt.Printf(fmt.Errorf("база данных не создалась: %w", err).Error())
+		fmt.Println(fmt.Errorf("база данных не открылась: %w", err))
+		return
+	}
+	if command := flag.Arg(0); command != "" {
+		err := runCommand(context.Background(), db, command, flag.Args()[1:])
+		db.Close()
+		if err != nil {
+			fmt.Println(fmt.Errorf("команда %s не выполнилась: %w", command, err))
+			os.Exit(1)
+		}
+		return
+	}
+	if err := checkSchema(context.Background(), db, cfg.AutoMigrate); err != nil {
+		fmt.Println(fmt.Errorf("база данных не готова: %w", err))
+		return
+	}
+	backend, err := storage.Open(context.Background(), cfg)
+	if err != nil {
+		fmt.Println(fmt.Errorf("хранилище видео не открылось: %w", err))
+		return
+	}
+	streamer := streamer.FileStreamer{Storage: backend}
+	signer := playback.NewSigner(secretKey("playback_secret", cfg.PlaybackSecret), cfg.PlaybackTTL)
+	tokens := auth.NewManager(secretKey("jwt_secret", cfg.JWTSecret), cfg.AccessTTL, cfg.RefreshTTL)
+	hub := rooms.NewHub(db, db, db, db, db, signer, policy)
+	// SIGINT и SIGTERM останавливают прием новых задач и запросов
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	pool := jobs.NewPool(db, db, db, backend, cfg)
+	if err := pool.Start(ctx); err != nil {
+		fmt.Println(fmt.Errorf("очередь задач не запустилась: %w", err))
 		return
 	}
-	streamer := streamer.FileStreamer{}
+	tus := video.NewTus(db, db, pool, cfg)
+	go tus.RunCleanup(ctx)
 	// Регистрируем обработчик
 
 	router := chi.NewRouter()
-	router.Use(logger.Middlerware)                   // Логирование запросов
-	router.Use(middleware.Recoverer)                 // Восстановление после паники
-	router.Use(middleware.Timeout(30 * time.Second)) // Таймаут на обработку
+	router.Use(logger.Middlerware)   // Логирование запросов
+	router.Use(middleware.Recoverer) // Восстановление после паники
 
+	// Authenticate стоит после CORSMiddleware: ответ 401 на просроченный токен
+	// должен дойти до браузерного клиента, чтобы тот обновил токен
 	router.Route("/video", func(r chi.Router) {
 		r.Use(CORSMiddleware)
-		r.Get("/", video.Sender(&streamer, sqllite))
-		r.Get("/all", video.GetAllVideo(sqllite))
-		r.Post("/upload", video.Upload(sqllite))
-		r.Get("/delete", video.Delete(sqllite))
-		r.Get("/hls/*", video.HLSHandler())
+		r.Use(tokens.Authenticate) // Пользователь из заголовка Authorization
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(30 * time.Second)) // Таймаут на обработку
+			r.Get("/all", video.GetAllVideo(db))
+			r.With(auth.RequireUser).Post("/upload", video.Upload(db, pool))
+			r.With(auth.RequireUser).Get("/delete", video.Delete(db, backend))
+			r.Get("/hls/*", video.HLSHandler(backend, &streamer, db, db, db, signer))
+			r.Get("/{id}/status", video.Status(db, db, pool))
+			r.Get("/{id}/playback", video.Playback(db, signer))
+			poster := video.Poster(db, &streamer)
+			r.Get("/{id}/poster", poster)
+			r.Head("/{id}/poster", poster) // Плееры и браузеры проверяют обложку запросом HEAD
+			r.With(auth.RequireUser).Patch("/{id}", video.Update(db))
+			r.Get("/{id}/subtitles", video.Subtitles(db, db))
+			r.With(auth.RequireUser).Post("/{id}/subtitles", video.UploadSubtitle(db, db, backend, cfg))
+			r.With(auth.RequireUser).Delete("/{id}/subtitles/{subtitleID}", video.DeleteSubtitle(db, db, backend))
+			r.Get("/{id}/reactions", video.Reactions(db, db))
+		})
+		// SSE-поток открыт до конца обработки, поэтому он вне группы с таймаутом
+		r.Get("/{id}/events", video.Events(db, db, pool))
+		// Части больших файлов грузятся дольше таймаута, поэтому tus тоже вне группы
+		r.Route("/tus", func(r chi.Router) {
+			r.Use(tus.Middleware)
+			r.Options("/", tus.Options)
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireUser)
+				r.Post("/", tus.Create)
+				r.Head("/{uploadID}", tus.Head)
+				r.Patch("/{uploadID}", tus.Patch)
+				r.Delete("/{uploadID}", tus.Terminate)
+			})
+		})
 	})
 
-	fmt.Println("Сервер запущен на http://localhost:3030")
-	http.ListenAndServe(":3030", router)
+	router.Route("/user", func(r chi.Router) {
+		r.Use(CORSMiddleware)
+		r.Use(middleware.Timeout(30 * time.Second)) // Таймаут на обработку
+		// Вход и обновление токена не смотрят на Authorization: клиент
+		// с просроченным access-токеном в заголовке должен суметь его обновить
+		r.Post("/register", user.Register(db, tokens))
+		r.Post("/login", user.Login(db, tokens))
+		r.Post("/refresh", user.Refresh(db, tokens))
+		r.With(tokens.Authenticate, auth.RequireUser).Get("/me", user.Me(db))
+	})
+
+	router.Route("/room", func(r chi.Router) {
+		r.Use(CORSMiddleware)
+		r.Use(tokens.Authenticate)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(30 * time.Second)) // Таймаут на обработку
+			r.Post("/create", room.Create(db, db))
+			r.Post("/join", room.Join(db))
+			r.Get("/{id}/members", room.Members(db))
+			r.Post("/{id}/leave", room.Leave(db, hub))
+			r.Get("/{id}/playback", room.Playback(db, db, signer))
+			r.Get("/{id}/queue", room.Queue(db, db))
+			r.Post("/{id}/queue", room.AddToQueue(db, db, db, hub))
+			r.Put("/{id}/queue", room.ReorderQueue(db, db, hub))
+			r.Delete("/{id}/queue/{itemID}", room.RemoveFromQueue(db, db, hub))
+			r.Post("/{id}/queue/skip", room.Skip(db, hub))
+			r.Get("/{id}/messages", room.Messages(db, db))
+		})
+		// WebSocket живёт дольше любого таймаута, поэтому он вне группы
+		r.Get("/{id}/ws", room.Sync(db, hub))
+	})
+
+	server := &http.Server{Addr: cfg.ListenAddr, Handler: router}
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.ListenAndServe() }()
+	fmt.Printf("Сервер запущен на %s\n", cfg.ListenAddr)
+
+	select {
+	case err := <-serverErr:
+		fmt.Println(fmt.Errorf("сервер остановлен: %w", err))
+		return
+	case <-ctx.Done():
+	}
+	fmt.Println("Получен сигнал остановки, дожидаемся текущих запросов и задач...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		fmt.Println(fmt.Errorf("сервер остановлен с ошибкой: %w", err))
+	}
+	if err := pool.Wait(shutdownCtx); err != nil {
+		fmt.Println("Не все задачи транскодирования завершились, они продолжатся после перезапуска.")
+	}
+	db.Close()
+	fmt.Println("Сервер остановлен.")
+}
+
+// secretKey возвращает ключ подписи из настройки name. Если он не задан,
+// создается случайный: подписанное им будет действовать только до перезапуска сервера.
+func secretKey(name, value string) []byte {
+	if value != "" {
+		return []byte(value)
+	}
+	fmt.Printf("%s не задан: подписанные ссылки и токены перестанут действовать после перезапуска.\n", name)
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
 }
+
+// envOr возвращает значение переменной окружения key или fallback, если она не задана.
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "*")
+		// Authorization не входит в "*" и разрешается только явно
+		w.Header().Set("Access-Control-Allow-Headers", "*, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, HEAD, PUT, PATCH, DELETE, OPTIONS")
+		// Заголовки, которые браузер должен показать клиенту tus
+		w.Header().Set("Access-Control-Expose-Headers",
+			"Location, Upload-Offset, Upload-Length, Upload-Metadata, Upload-Expires, "+
+				"Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size")
 
-		if r.Method == "OPTIONS" {
+		// Preflight-запрос браузера отвечаем сразу. Обычный OPTIONS идет дальше:
+		// через него tus-клиенты узнают возможности сервера
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}